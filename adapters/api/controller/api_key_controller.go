@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"encoding/json"
+	"golang-api-hexagonal/adapters/api/dto"
+	"golang-api-hexagonal/adapters/api/middleware"
+	"golang-api-hexagonal/adapters/api/router"
+	"golang-api-hexagonal/adapters/opa"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	serverMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
+	"go.uber.org/zap"
+)
+
+// ApiKeyController admin controller for the api keys
+type ApiKeyController struct {
+	log           *zap.SugaredLogger
+	validate      *validator.Validate
+	service       ports.IApiKeyService
+	jwtVerify     *middleware.JWTVerify
+	policyService *opa.PolicyService
+}
+
+// NewApiKeyController create a new http api key controller API
+func NewApiKeyController(httpRouter *router.HTTPRouter, log *zap.SugaredLogger, validator *validator.Validate,
+	service ports.IApiKeyService, jwtVerify *middleware.JWTVerify, policyService *opa.PolicyService) {
+	controller := &ApiKeyController{
+		log:           log,
+		validate:      validator,
+		service:       service,
+		jwtVerify:     jwtVerify,
+		policyService: policyService,
+	}
+
+	httpRouter.Router.Group(func(r chi.Router) {
+		r.Use(controller.jwtVerify.JWTVerifyHandler())
+		r.Use(httpRouter.RateLimiter.Handler())
+		r.Post("/v1/api-keys", controller.createApiKey)
+		r.Get("/v1/api-keys", controller.listApiKeys)
+		r.Delete("/v1/api-keys/{id}", controller.revokeApiKey)
+	})
+}
+
+// createApiKey create the api key, the key is only returned in this response
+func (ac *ApiKeyController) createApiKey(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	ac.log.With("traceId", traceID).Infof("User %v is creating an api key.", claims.Username)
+
+	if !ac.allowed(writer, request, claims, traceID) {
+		return
+	}
+
+	apiKeyRequest := &domain.ApiKey{}
+	err := json.NewDecoder(request.Body).Decode(apiKeyRequest)
+	if err != nil {
+		ac.log.With("traceId", traceID).Errorf("Error to parsing the api key payload body. Maformed: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	_ = ac.validate.RegisterValidation("not_blank", validators.NotBlank)
+	err = ac.validate.Struct(apiKeyRequest)
+	if err != nil {
+		ac.log.With("traceId", traceID).Errorf("Api key validation error: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	response, err := ac.service.CreateApiKey(request.Context(), apiKeyRequest, claims.Username, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+	dto.RenderResponse(request.Context(), writer, http.StatusCreated, response)
+}
+
+// listApiKeys list the api keys without their secrets
+func (ac *ApiKeyController) listApiKeys(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	ac.log.With("traceId", traceID).Infof("User %v is listing the api keys.", claims.Username)
+
+	if !ac.allowed(writer, request, claims, traceID) {
+		return
+	}
+
+	response, err := ac.service.ListApiKeys(request.Context(), traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, response)
+}
+
+// revokeApiKey revoke the api key by id
+func (ac *ApiKeyController) revokeApiKey(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	id := chi.URLParam(request, "id")
+	ac.log.With("traceId", traceID).Infof("User %v is revoking the api key %s.", claims.Username, id)
+
+	if !ac.allowed(writer, request, claims, traceID) {
+		return
+	}
+
+	err := ac.service.RevokeApiKey(request.Context(), id, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, dto.DefaultResponse(http.StatusText(http.StatusOK), "revoked"))
+}
+
+// allowed evaluate the api keys management policy, only the admins are allowed and an api key can not manage the api keys
+func (ac *ApiKeyController) allowed(writer http.ResponseWriter, request *http.Request, claims domain.AuthClaims, traceID string) bool {
+	if strings.HasPrefix(claims.Subject, domain.ApiKeySubjectPrefix) {
+		ac.log.With("traceId", traceID).Errorf("Forbidden access: api key")
+		dto.RenderForbiddenResponse(request.Context(), writer, []string{"api_key_not_allowed"})
+		return false
+	}
+
+	decision := ac.policyService.EvaluateApiPolicy(request.Context(), claims, domain.ManageApiKeysOperation, domain.PolicyResource{})
+	if !decision.Allow {
+		ac.log.With("traceId", traceID).Errorf("Forbidden access: %v", decision.Reasons)
+		dto.RenderForbiddenResponse(request.Context(), writer, decision.Reasons)
+	}
+	return decision.Allow
+}
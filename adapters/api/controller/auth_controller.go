@@ -8,6 +8,7 @@ import (
 	"golang-api-hexagonal/core/ports"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
 	serverMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
 	"github.com/go-playground/validator/v10/non-standard/validators"
@@ -29,10 +30,16 @@ func NewAuthController(httpRouter *router.HTTPRouter, log *zap.SugaredLogger, va
 		service:  service,
 	}
 
-	httpRouter.Router.Post("/v1/sts/token", controller.createToken)
+	httpRouter.Router.Group(func(r chi.Router) {
+		r.Use(httpRouter.RateLimiter.Handler())
+		r.Post("/v1/sts/token", controller.createToken)
+		r.Post("/v1/sts/refresh", controller.refreshToken)
+		r.Post("/v1/sts/revoke", controller.revokeToken)
+	})
+	httpRouter.Router.Get("/.well-known/jwks.json", controller.getJWKS)
 }
 
-// createToken create a simple access token for tests
+// createToken verify the user credentials and create the access and refresh tokens
 func (ac *AuthController) createToken(writer http.ResponseWriter, request *http.Request) {
 	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
 	auth := &domain.Auth{}
@@ -52,11 +59,75 @@ func (ac *AuthController) createToken(writer http.ResponseWriter, request *http.
 		return
 	}
 
-	tokenString, err := ac.service.CreateOauthToken(auth, traceID)
+	tokenResponse, err := ac.service.CreateOauthToken(request.Context(), auth, traceID)
 	if err != nil {
 		dto.RenderErrorResponse(request.Context(), writer, http.StatusInternalServerError, err)
 		return
 	}
 
-	dto.RenderResponse(request.Context(), writer, http.StatusOK, tokenString)
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, tokenResponse)
+}
+
+// refreshToken rotate the refresh token and create a new access token
+func (ac *AuthController) refreshToken(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	refresh := &domain.RefreshTokenRequest{}
+
+	err := json.NewDecoder(request.Body).Decode(refresh)
+	if err != nil {
+		ac.log.With("traceId", traceID).Errorf("Error to parsing the refresh token payload body. Maformed: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	_ = ac.validate.RegisterValidation("not_blank", validators.NotBlank)
+	err = ac.validate.Struct(refresh)
+	if err != nil {
+		ac.log.With("traceId", traceID).Errorf("Refresh token validation error: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	tokenResponse, err := ac.service.RefreshOauthToken(request.Context(), refresh, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusInternalServerError, err)
+		return
+	}
+
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, tokenResponse)
+}
+
+// revokeToken revoke an access token or a refresh token with its family, used to logout
+func (ac *AuthController) revokeToken(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	revoke := &domain.RevokeTokenRequest{}
+
+	err := json.NewDecoder(request.Body).Decode(revoke)
+	if err != nil {
+		ac.log.With("traceId", traceID).Errorf("Error to parsing the revoke token payload body. Maformed: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	_ = ac.validate.RegisterValidation("not_blank", validators.NotBlank)
+	err = ac.validate.Struct(revoke)
+	if err != nil {
+		ac.log.With("traceId", traceID).Errorf("Revoke token validation error: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	err = ac.service.RevokeOauthToken(request.Context(), revoke, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusInternalServerError, err)
+		return
+	}
+
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, dto.DefaultResponse(http.StatusText(http.StatusOK), "revoked"))
+}
+
+// getJWKS publish the public keys to verify the access tokens
+func (ac *AuthController) getJWKS(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Cache-Control", "public, max-age=300")
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, ac.service.GetJWKS())
 }
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"golang-api-hexagonal/adapters/api/dto"
+	"golang-api-hexagonal/adapters/api/middleware"
+	"golang-api-hexagonal/adapters/api/router"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/adapters/opa"
+	"golang-api-hexagonal/core/domain"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	serverMiddleware "github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// PolicyController admin controller for the OPA policies
+type PolicyController struct {
+	log           *zap.SugaredLogger
+	jwtVerify     *middleware.JWTVerify
+	policyService *opa.PolicyService
+}
+
+// NewPolicyController create a new http policy controller API
+func NewPolicyController(httpRouter *router.HTTPRouter, log *zap.SugaredLogger, jwtVerify *middleware.JWTVerify, policyService *opa.PolicyService) {
+	controller := &PolicyController{
+		log:           log,
+		jwtVerify:     jwtVerify,
+		policyService: policyService,
+	}
+
+	httpRouter.Router.Group(func(r chi.Router) {
+		r.Use(controller.jwtVerify.JWTVerifyHandler())
+		r.Use(httpRouter.RateLimiter.Handler())
+		r.Post("/v1/admin/policies/reload", controller.reloadPolicies)
+	})
+}
+
+// reloadPolicies recompile the policies, the current policies are kept when the compilation fails
+func (pc *PolicyController) reloadPolicies(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	pc.log.With("traceId", traceID).Infof("User %v is reloading the policies.", claims.Username)
+
+	decision := pc.policyService.EvaluateApiPolicy(request.Context(), claims, domain.ReloadPoliciesOperation, domain.PolicyResource{})
+	if !decision.Allow {
+		pc.log.With("traceId", traceID).Errorf("Forbidden access: %v", decision.Reasons)
+		dto.RenderForbiddenResponse(request.Context(), writer, decision.Reasons)
+		return
+	}
+
+	err := pc.policyService.Reload(request.Context())
+	if err != nil {
+		// The compilation error exposes the policy source, it is only logged
+		pc.log.With("traceId", traceID).Errorf("Failed to reload the policies: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, 0, custom_error.New(http.StatusUnprocessableEntity, "invalid policy"))
+		return
+	}
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, dto.DefaultResponse(http.StatusText(http.StatusOK), "reloaded"))
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/opa"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReloadPoliciesHidesTheCompilationError for test reloadPolicies
+func TestReloadPoliciesHidesTheCompilationError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_policies.rego")
+	assert.Nil(t, os.WriteFile(path, []byte("package golangapitemplate.authz\n\nallow = true\n"), 0o600))
+	log := zap.NewNop().Sugar()
+	controller := &PolicyController{
+		log:           log,
+		policyService: opa.NewPolicyService(config.PoliciesConfiguration{Path: path}, log, prometheus.NewRegistry(), nil),
+	}
+	assert.Nil(t, os.WriteFile(path, []byte("package golangapitemplate.authz\n\nallow { secret_rule_name\n"), 0o600))
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/admin/policies/reload", nil)
+	ctx := context.WithValue(request.Context(), middleware.RequestIDKey, "trace")
+	ctx = context.WithValue(ctx, domain.ClaimsKey, domain.AuthClaims{Username: "john", Roles: []string{"admin"}})
+	recorder := httptest.NewRecorder()
+
+	controller.reloadPolicies(recorder, request.WithContext(ctx))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid policy")
+	assert.NotContains(t, recorder.Body.String(), "secret_rule_name")
+	assert.NotContains(t, recorder.Body.String(), path)
+}
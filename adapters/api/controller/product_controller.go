@@ -1,15 +1,20 @@
 package controller
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"golang-api-hexagonal/adapters/api/dto"
 	"golang-api-hexagonal/adapters/api/middleware"
 	"golang-api-hexagonal/adapters/api/router"
+	"golang-api-hexagonal/adapters/custom_error"
 	"golang-api-hexagonal/adapters/opa"
 	"golang-api-hexagonal/core/domain"
 	"golang-api-hexagonal/core/ports"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	serverMiddleware "github.com/go-chi/chi/v5/middleware"
@@ -27,17 +32,19 @@ type ProductController struct {
 	validate      *validator.Validate
 	service       ports.IProductService
 	jwtVerify     *middleware.JWTVerify
+	idempotency   *middleware.Idempotency
 	policyService *opa.PolicyService
 }
 
 // NewProductController Create a new http product controller API
 func NewProductController(httpRouter *router.HTTPRouter, log *zap.SugaredLogger, validator *validator.Validate, prometheusRegistry *middleware.CustomMetricRegistry,
-	service ports.IProductService, jwtVerify *middleware.JWTVerify, policyService *opa.PolicyService) {
+	service ports.IProductService, jwtVerify *middleware.JWTVerify, idempotency *middleware.Idempotency, policyService *opa.PolicyService) {
 	controller := &ProductController{
 		log:           log,
 		validate:      validator,
 		service:       service,
 		jwtVerify:     jwtVerify,
+		idempotency:   idempotency,
 		policyService: policyService,
 		counterMetric: promauto.With(prometheusRegistry).NewCounter(prometheus.CounterOpts{
 			Name: "products_reqs_total",
@@ -47,8 +54,14 @@ func NewProductController(httpRouter *router.HTTPRouter, log *zap.SugaredLogger,
 
 	httpRouter.Router.Group(func(r chi.Router) {
 		r.Use(controller.jwtVerify.JWTVerifyHandler())
-		r.Post("/v1/product", controller.createProduct)
+		r.Use(httpRouter.RateLimiter.Handler())
+		r.With(controller.idempotency.Handler()).Post("/v1/product", controller.createProduct)
+		r.Post("/v1/product/batch-get", controller.batchGetProducts)
 		r.Get("/v1/product/{id}", controller.getProduct)
+		r.Put("/v1/product/{id}", controller.updateProduct)
+		r.Patch("/v1/product/{id}", controller.patchProduct)
+		r.Post("/v1/product/{id}/status", controller.changeProductStatus)
+		r.Get("/v1/product/{id}/history", controller.getProductHistory)
 	})
 }
 
@@ -59,16 +72,13 @@ func (pc *ProductController) createProduct(writer http.ResponseWriter, request *
 	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
 	pc.log.With("traceId", traceID).Infof("User %v is creating a product.", claims.Username)
 
-	allowed := pc.policyService.EvaluateApiPolicy(request.Context(), claims, "createProduct", "")
-	if !allowed {
-		pc.log.With("traceId", traceID).Errorf("Forbidden access role")
-		dto.RenderErrorResponse(request.Context(), writer, http.StatusForbidden, errors.New("forbidden access"))
-		return
-	}
-
 	productRequest := &domain.Product{}
-	err := json.NewDecoder(request.Body).Decode(productRequest)
-	if err != nil {
+	err := json.NewDecoder(http.MaxBytesReader(writer, request.Body, dto.MaxBodyBytes)).Decode(productRequest)
+	if tooLarge := dto.BodyTooLargeError(err); tooLarge != nil {
+		pc.log.With("traceId", traceID).Errorf("The product payload body is larger than %d bytes", dto.MaxBodyBytes)
+		dto.RenderErrorResponse(request.Context(), writer, 0, tooLarge)
+		return
+	} else if err != nil {
 		pc.log.With("traceId", traceID).Errorf("Error to parsing the product payload body. Maformed: %v", err)
 		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
 		return
@@ -82,6 +92,12 @@ func (pc *ProductController) createProduct(writer http.ResponseWriter, request *
 		return
 	}
 
+	// The new product has no owner yet, so only the roles can allow its creation
+	resource := domain.PolicyResource{TenantID: domain.TenantFromContext(request.Context()), Status: productRequest.Status}
+	if !pc.authorize(writer, request, claims, domain.CreateProductOperation, resource, traceID) {
+		return
+	}
+
 	response, err := pc.service.CreateProduct(request.Context(), productRequest, claims.Username, traceID)
 	if err != nil {
 		dto.RenderErrorResponse(request.Context(), writer, 0, err)
@@ -98,17 +114,283 @@ func (pc *ProductController) getProduct(writer http.ResponseWriter, request *htt
 	id := chi.URLParam(request, "id")
 	pc.log.With("traceId", traceID).Infof("User %v is searching a product.", claims.Username)
 
-	allowed := pc.policyService.EvaluateApiPolicy(request.Context(), claims, "viewProduct", "")
-	if !allowed {
-		pc.log.With("traceId", traceID).Errorf("Forbidden access role")
-		dto.RenderErrorResponse(request.Context(), writer, http.StatusForbidden, errors.New("forbidden access"))
+	response, ok := pc.loadAuthorizedProduct(writer, request, claims, domain.ViewProductOperation, pc.service.GetProduct, id, traceID)
+	if !ok {
+		return
+	}
+
+	etag := dto.VersionETag(response.Version)
+	writer.Header().Set("ETag", etag)
+	if ifNoneMatch := request.Header.Get("If-None-Match"); ifNoneMatch != "" && dto.ETagMatches(ifNoneMatch, etag, true) {
+		writer.Header().Set(serverMiddleware.RequestIDHeader, traceID)
+		writer.WriteHeader(http.StatusNotModified)
+		return
+	}
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, response)
+}
+
+// batchGetProducts get the products by ids in the order of the ids, the products not found or not allowed have their error
+func (pc *ProductController) batchGetProducts(writer http.ResponseWriter, request *http.Request) {
+	pc.counterMetric.Inc()
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	pc.log.With("traceId", traceID).Infof("User %v is searching products by ids.", claims.Username)
+
+	batchRequest := &domain.ProductBatchGetRequest{}
+	err := json.NewDecoder(request.Body).Decode(batchRequest)
+	if err != nil {
+		pc.log.With("traceId", traceID).Errorf("Error to parsing the batch get payload body. Maformed: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+	err = pc.validate.Struct(batchRequest)
+	if err != nil {
+		pc.log.With("traceId", traceID).Errorf("Batch get validation error: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	response, err := pc.service.GetProducts(request.Context(), batchRequest.IDs, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+	for _, item := range response.Items {
+		if !item.Found {
+			continue
+		}
+		decision := pc.policyService.EvaluateApiPolicy(request.Context(), claims, domain.ViewProductOperation, domain.ProductPolicyResource(item.Product))
+		if !decision.Allow {
+			pc.log.With("traceId", traceID).Errorf("Forbidden access to the productID %s: %v", item.ID, decision.Reasons)
+			item.Found, item.Product, item.Error = false, nil, "forbidden"
+		}
+	}
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, response)
+}
+
+// updateProduct replace the product, the If-Match header must match the ETag of the product when it is sent
+func (pc *ProductController) updateProduct(writer http.ResponseWriter, request *http.Request) {
+	pc.counterMetric.Inc()
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	id := chi.URLParam(request, "id")
+	pc.log.With("traceId", traceID).Infof("User %v is updating a product.", claims.Username)
+
+	product, ok := pc.loadAuthorizedProduct(writer, request, claims, domain.UpdateProductOperation, pc.service.ReloadProduct, id, traceID)
+	if !ok || !pc.preconditionMatches(writer, request, product, traceID) {
+		return
+	}
+
+	productRequest := &domain.Product{}
+	err := json.NewDecoder(request.Body).Decode(productRequest)
+	if err != nil {
+		pc.log.With("traceId", traceID).Errorf("Error to parsing the product payload body. Maformed: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	_ = pc.validate.RegisterValidation("not_blank", validators.NotBlank)
+	err = pc.validate.Struct(productRequest)
+	if err != nil {
+		pc.log.With("traceId", traceID).Errorf("Product validation error: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
 		return
 	}
 
-	response, err := pc.service.GetProduct(request.Context(), id, traceID)
+	response, err := pc.service.UpdateProduct(request.Context(), product, productRequest, claims.Username, traceID)
 	if err != nil {
 		dto.RenderErrorResponse(request.Context(), writer, 0, err)
 		return
 	}
+	writer.Header().Set("ETag", dto.VersionETag(response.Version))
 	dto.RenderResponse(request.Context(), writer, http.StatusOK, response)
 }
+
+// patchProduct partially update the product with a JSON Merge Patch or a JSON Patch of the product fields,
+// the patched product has the validation and the rules of the update
+func (pc *ProductController) patchProduct(writer http.ResponseWriter, request *http.Request) {
+	pc.counterMetric.Inc()
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	id := chi.URLParam(request, "id")
+	pc.log.With("traceId", traceID).Infof("User %v is patching a product.", claims.Username)
+
+	product, ok := pc.loadAuthorizedProduct(writer, request, claims, domain.UpdateProductOperation, pc.service.ReloadProduct, id, traceID)
+	if !ok || !pc.preconditionMatches(writer, request, product, traceID) {
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, dto.MaxBodyBytes))
+	if tooLarge := dto.BodyTooLargeError(err); tooLarge != nil {
+		pc.log.With("traceId", traceID).Errorf("The product patch is larger than %d bytes", dto.MaxBodyBytes)
+		dto.RenderErrorResponse(request.Context(), writer, 0, tooLarge)
+		return
+	} else if err != nil {
+		pc.log.With("traceId", traceID).Errorf("Error to read the product patch: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+	document, _ := json.Marshal(domain.FromProductResponseToProduct(product))
+	patched, err := dto.ApplyPatch(request.Header.Get("Content-Type"), document, patch)
+	if err != nil {
+		pc.log.With("traceId", traceID).Errorf("Error to apply the product patch: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	productRequest := &domain.Product{}
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(productRequest); err != nil {
+		pc.log.With("traceId", traceID).Errorf("Error to parsing the patched product. Maformed: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+	// the status changes need the changeProductStatus policy, the transition rules and a reason, they are not patches
+	if productRequest.Status != product.Status {
+		pc.log.With("traceId", traceID).Errorf("The patch changes the productID %s status from %s to %s", product.ID, product.Status, productRequest.Status)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusConflict, custom_error.New(http.StatusConflict, domain.StatusNotUpdatedMessage))
+		return
+	}
+
+	_ = pc.validate.RegisterValidation("not_blank", validators.NotBlank)
+	err = pc.validate.Struct(productRequest)
+	if err != nil {
+		pc.log.With("traceId", traceID).Errorf("Product validation error: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	response, err := pc.service.UpdateProduct(request.Context(), product, productRequest, claims.Username, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+	writer.Header().Set("ETag", dto.VersionETag(response.Version))
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, response)
+}
+
+// changeProductStatus change the product status with the reason of the change, the policy receives the current and requested status
+func (pc *ProductController) changeProductStatus(writer http.ResponseWriter, request *http.Request) {
+	pc.counterMetric.Inc()
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	id := chi.URLParam(request, "id")
+	pc.log.With("traceId", traceID).Infof("User %v is changing a product status.", claims.Username)
+
+	statusRequest := &domain.ProductStatusChange{}
+	err := json.NewDecoder(request.Body).Decode(statusRequest)
+	if err != nil {
+		pc.log.With("traceId", traceID).Errorf("Error to parsing the product status payload body. Maformed: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	_ = pc.validate.RegisterValidation("not_blank", validators.NotBlank)
+	err = pc.validate.Struct(statusRequest)
+	if err != nil {
+		pc.log.With("traceId", traceID).Errorf("Product status validation error: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	// the policy and the transition use the product of the database, a stale cached status could allow a forbidden transition
+	product, err := pc.service.ReloadProduct(request.Context(), id, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+	resource := domain.ProductPolicyResource(product)
+	resource.TargetStatus = statusRequest.Status
+	if !pc.authorize(writer, request, claims, domain.ChangeProductStatusOperation, resource, traceID) ||
+		!pc.preconditionMatches(writer, request, product, traceID) {
+		return
+	}
+
+	response, err := pc.service.ChangeProductStatus(request.Context(), product, statusRequest, claims.Username, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+	writer.Header().Set("ETag", dto.VersionETag(response.Version))
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, response)
+}
+
+// getProductHistory get a page of the product audit trail, newest first, with the limit and cursor query parameters
+func (pc *ProductController) getProductHistory(writer http.ResponseWriter, request *http.Request) {
+	pc.counterMetric.Inc()
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	id := chi.URLParam(request, "id")
+	pc.log.With("traceId", traceID).Infof("User %v is searching the product history.", claims.Username)
+
+	limit := domain.DefaultProductHistoryLimit
+	var cursor int64
+	var err error
+	if value := request.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > domain.MaxProductHistoryLimit {
+			pc.log.With("traceId", traceID).Errorf("Invalid history limit: %s", value)
+			dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest,
+				custom_error.New(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", domain.MaxProductHistoryLimit)))
+			return
+		}
+	}
+	if value := request.URL.Query().Get("cursor"); value != "" {
+		if cursor, err = strconv.ParseInt(value, 10, 64); err != nil || cursor < 1 {
+			pc.log.With("traceId", traceID).Errorf("Invalid history cursor: %s", value)
+			dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, custom_error.New(http.StatusBadRequest, "invalid cursor"))
+			return
+		}
+	}
+
+	if _, ok := pc.loadAuthorizedProduct(writer, request, claims, domain.ViewProductHistoryOperation, pc.service.GetProduct, id, traceID); !ok {
+		return
+	}
+
+	response, err := pc.service.GetProductHistory(request.Context(), id, cursor, limit, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, response)
+}
+
+// preconditionMatches verify the If-Match header of the mutations with the ETag of the loaded product, rendering 412 when it does not match
+func (pc *ProductController) preconditionMatches(writer http.ResponseWriter, request *http.Request, product *domain.ProductResponse, traceID string) bool {
+	ifMatch := request.Header.Get("If-Match")
+	if ifMatch == "" || dto.ETagMatches(ifMatch, dto.VersionETag(product.Version), false) {
+		return true
+	}
+	pc.log.With("traceId", traceID).Errorf("If-Match %s does not match the productID %s version %d", ifMatch, product.ID, product.Version)
+	writer.Header().Set("ETag", dto.VersionETag(product.Version))
+	dto.RenderErrorResponse(request.Context(), writer, http.StatusPreconditionFailed, custom_error.New(http.StatusPreconditionFailed, "the product was modified"))
+	return false
+}
+
+// loadAuthorizedProduct load the product once and evaluate the policy with its owner, tenant and status,
+// the loaded product is returned to be used by the handler. The error responses are already rendered when it is not ok.
+// The reads load the cached product, the mutations load the product of the database so the policy and the update use the same version
+func (pc *ProductController) loadAuthorizedProduct(writer http.ResponseWriter, request *http.Request, claims domain.AuthClaims, operation domain.Operation,
+	load func(ctx context.Context, productID, traceID string) (*domain.ProductResponse, error), productID, traceID string) (*domain.ProductResponse, bool) {
+	product, err := load(request.Context(), productID, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return nil, false
+	}
+
+	if !pc.authorize(writer, request, claims, operation, domain.ProductPolicyResource(product), traceID) {
+		return nil, false
+	}
+	return product, true
+}
+
+// authorize evaluate the policy of the operation on the resource, rendering the forbidden response when it is denied
+func (pc *ProductController) authorize(writer http.ResponseWriter, request *http.Request, claims domain.AuthClaims,
+	operation domain.Operation, resource domain.PolicyResource, traceID string) bool {
+	decision := pc.policyService.EvaluateApiPolicy(request.Context(), claims, operation, resource)
+	if !decision.Allow {
+		pc.log.With("traceId", traceID).Errorf("Forbidden access to %s: %v", operation, decision.Reasons)
+		dto.RenderForbiddenResponse(request.Context(), writer, decision.Reasons)
+	}
+	return decision.Allow
+}
@@ -0,0 +1,213 @@
+//go:build integration
+
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/api/controller"
+	"golang-api-hexagonal/adapters/api/middleware"
+	"golang-api-hexagonal/adapters/api/router"
+	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/adapters/opa"
+	"golang-api-hexagonal/adapters/repository/products"
+	"golang-api-hexagonal/adapters/repository/users"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/services"
+	"golang-api-hexagonal/test/integration"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+const productTopic = "product-events"
+
+// apiServer the product API with its real adapters, only kafka is in memory
+type apiServer struct {
+	*httptest.Server
+	repository *products.ProductRepository
+	redis      *cache.RedisCache
+	messages   *integration.MessageRecorder
+}
+
+func TestMain(m *testing.M) {
+	os.Exit(integration.Run(m))
+}
+
+// newAPIServer start the auth and product API on a new database and redis database, with the business_main_id user
+func newAPIServer(t *testing.T) *apiServer {
+	log := zap.NewNop().Sugar()
+	db := integration.Database(t)
+	redisCache := integration.Redis(t)
+	messages := integration.NewMessageRecorder()
+	kafkaConfig := config.KafkaConfiguration{Producer: config.KafkaProducerConfiguration{ProductTopic: productTopic}}
+
+	productRepository := products.NewProductRepository(db)
+	userRepository := users.NewUserRepository(db)
+	_, err := services.NewUserService(log, userRepository).CreateUser(context.Background(),
+		&domain.User{Username: "business_main_id", Password: "password123", Roles: []string{"business"}}, "trace")
+	require.Nil(t, err)
+
+	oauthConfig := config.Oauth{Secret: "integration-secret"}
+	oauthKeys, err := config.LoadOauthKeys(oauthConfig)
+	require.Nil(t, err)
+	authService := services.NewAuthService(log, oauthConfig, oauthKeys, userRepository, redisCache)
+	productService := services.NewProductService(log, productRepository, redisCache, messages, kafkaConfig)
+	policies := opa.NewPolicyService(config.PoliciesConfiguration{Path: "../../../resources/api_policies.rego"}, log, prometheus.NewRegistry(), nil)
+
+	metrics := middleware.NewPrometheusMiddleware("integration")
+	route := router.NewHTTPRouter(metrics, middleware.NewRateLimiter(log, redisCache, false, middleware.RateLimitRule{}, nil))
+	controller.NewAuthController(route, log, validator.New(), authService)
+	controller.NewProductController(route, log, validator.New(), metrics, productService, middleware.NewJWTHandler(log, authService, nil),
+		middleware.NewIdempotencyHandler(log, redisCache), policies)
+
+	server := &apiServer{Server: httptest.NewServer(route.Router), repository: productRepository, redis: redisCache, messages: messages}
+	t.Cleanup(server.Close)
+	return server
+}
+
+// token access token of the user
+func (as *apiServer) token(t *testing.T, username, password string) string {
+	response := as.send(t, http.MethodPost, "/v1/sts/token", "", &domain.Auth{Username: username, Password: password}, nil)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	token := &domain.TokenResponse{}
+	require.Nil(t, json.NewDecoder(response.Body).Decode(token))
+	return token.AccessToken
+}
+
+// send send the json body with the headers, the token is the bearer token when it is not empty
+func (as *apiServer) send(t *testing.T, method, path, token string, body interface{}, headers map[string]string) *http.Response {
+	var payload []byte
+	if raw, ok := body.(string); ok {
+		payload = []byte(raw)
+	} else if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		require.Nil(t, err)
+	}
+	request, err := http.NewRequest(method, as.URL+path, bytes.NewReader(payload))
+	require.Nil(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	response, err := as.Client().Do(request)
+	require.Nil(t, err)
+	t.Cleanup(func() {
+		_ = response.Body.Close()
+	})
+	return response
+}
+
+// TestProductLifecycle for test the creation with an idempotency key, the get with ETag and the merge patch of a product
+func TestProductLifecycle(t *testing.T) {
+	t.Parallel()
+	server := newAPIServer(t)
+	token := server.token(t, "business_main_id", "password123")
+	product := &domain.Product{Name: "shoes", UnitType: "unit", Unit: "1", Brand: "acme", Color: "red", Style: "classic", Status: domain.ProductPending}
+
+	created := &domain.ProductResponse{}
+	for attempt := 0; attempt < 2; attempt++ {
+		response := server.send(t, http.MethodPost, "/v1/product", token, product, map[string]string{middleware.IdempotencyKeyHeader: "create-shoes"})
+		require.Equal(t, http.StatusCreated, response.StatusCode)
+		require.Nil(t, json.NewDecoder(response.Body).Decode(created))
+	}
+	ctx := domain.WithTenant(context.Background(), domain.DefaultTenantID)
+	count, err := server.repository.CountProducts(ctx, domain.ProductFilter{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	events := server.messages.Messages(productTopic)
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.ProductEventName, events[0].EventName)
+
+	response := server.send(t, http.MethodGet, "/v1/product/"+created.ID, token, nil, nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	etag := response.Header.Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+	response = server.send(t, http.MethodGet, "/v1/product/"+created.ID, token, nil, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, response.StatusCode)
+
+	patch := map[string]string{"Content-Type": "application/merge-patch+json", "If-Match": etag}
+	response = server.send(t, http.MethodPatch, "/v1/product/"+created.ID, token, `{"description":"running shoes"}`, patch)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = server.send(t, http.MethodPatch, "/v1/product/"+created.ID, token, `{"description":"old shoes"}`, patch)
+	assert.Equal(t, http.StatusPreconditionFailed, response.StatusCode)
+	response = server.send(t, http.MethodPatch, "/v1/product/"+created.ID, token, `{"status":"available"}`,
+		map[string]string{"Content-Type": "application/merge-patch+json"})
+	assert.Equal(t, http.StatusConflict, response.StatusCode)
+
+	model, err := server.repository.GetProductById(ctx, created.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "running shoes", model.Description)
+	assert.Equal(t, int64(2), model.Version)
+	assert.Len(t, server.messages.Messages(productTopic), 2)
+}
+
+// TestChangeProductStatusWithStaleCache for test the policy of a status change on the product of the database, the stale
+// cached status must not allow the reactivation of an inactive product by the business role
+func TestChangeProductStatusWithStaleCache(t *testing.T) {
+	t.Parallel()
+	server := newAPIServer(t)
+	token := server.token(t, "business_main_id", "password123")
+	product := &domain.Product{Name: "boots", UnitType: "unit", Unit: "1", Brand: "acme", Color: "black", Style: "classic", Status: domain.ProductPending}
+
+	created := &domain.ProductResponse{}
+	response := server.send(t, http.MethodPost, "/v1/product", token, product, nil)
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+	require.Nil(t, json.NewDecoder(response.Body).Decode(created))
+	response = server.send(t, http.MethodPost, "/v1/product/"+created.ID+"/status", token,
+		&domain.ProductStatusChange{Status: domain.ProductInactive, Reason: "discontinued"}, nil)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	// the cache still has the product before its deactivation
+	ctx := domain.WithTenant(context.Background(), domain.DefaultTenantID)
+	model, err := server.repository.GetProductById(ctx, created.ID)
+	require.Nil(t, err)
+	stale := *model
+	stale.Status, stale.Version = domain.ProductAvailable, model.Version-1
+	payload, err := json.Marshal(&stale)
+	require.Nil(t, err)
+	require.Nil(t, server.redis.Set(ctx, cache.ProductKey(domain.DefaultTenantID, created.ID), payload, time.Minute).Err())
+
+	response = server.send(t, http.MethodPost, "/v1/product/"+created.ID+"/status", token,
+		&domain.ProductStatusChange{Status: domain.ProductPending, Reason: "reactivated"},
+		map[string]string{"If-Match": fmt.Sprintf(`"%d"`, model.Version)})
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+
+	model, err = server.repository.GetProductById(ctx, created.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.ProductInactive, model.Status)
+}
+
+// TestProductWithoutToken for test the authentication and the authorization of the product API
+func TestProductWithoutToken(t *testing.T) {
+	t.Parallel()
+	server := newAPIServer(t)
+
+	unknown := "/v1/product/" + uuid.NewString()
+	response := server.send(t, http.MethodGet, unknown, "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	response = server.send(t, http.MethodPost, "/v1/sts/token", "", &domain.Auth{Username: "business_main_id", Password: "wrong-password"}, nil)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	token := server.token(t, "business_main_id", "password123")
+	response = server.send(t, http.MethodGet, unknown, token, nil, nil)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	response = server.send(t, http.MethodGet, "/v1/product/unknown", token, nil, nil)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
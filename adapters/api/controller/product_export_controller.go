@@ -0,0 +1,307 @@
+package controller
+
+import (
+	"fmt"
+	"golang-api-hexagonal/adapters/api/dto"
+	"golang-api-hexagonal/adapters/api/middleware"
+	"golang-api-hexagonal/adapters/api/router"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/adapters/export"
+	"golang-api-hexagonal/adapters/opa"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	serverMiddleware "github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// ExportStatusTrailer trailer of the streamed exports, complete when all the products were sent and failed when the stream was cut
+const ExportStatusTrailer = "Export-Status"
+
+// Values of the ExportStatusTrailer
+const (
+	exportStatusComplete = "complete"
+	exportStatusFailed   = "failed"
+)
+
+// exportFormats export format of each accepted media type
+var exportFormats = map[string]string{
+	"*/*":                  domain.ExportCSV,
+	"text/*":               domain.ExportCSV,
+	"text/csv":             domain.ExportCSV,
+	"application/*":        domain.ExportJSON,
+	"application/json":     domain.ExportJSON,
+	"application/x-ndjson": domain.ExportNDJSON,
+	"application/ndjson":   domain.ExportNDJSON,
+}
+
+// ProductExportController controller for the product export API
+type ProductExportController struct {
+	log           *zap.SugaredLogger
+	service       ports.IProductExportService
+	jwtVerify     *middleware.JWTVerify
+	policyService *opa.PolicyService
+}
+
+// NewProductExportController create a new http product export controller API
+func NewProductExportController(httpRouter *router.HTTPRouter, log *zap.SugaredLogger, service ports.IProductExportService,
+	jwtVerify *middleware.JWTVerify, policyService *opa.PolicyService) {
+	controller := &ProductExportController{
+		log:           log,
+		service:       service,
+		jwtVerify:     jwtVerify,
+		policyService: policyService,
+	}
+
+	httpRouter.Router.Group(func(r chi.Router) {
+		r.Use(controller.jwtVerify.JWTVerifyHandler())
+		r.Use(httpRouter.RateLimiter.Handler())
+		r.Get("/v1/product/export", controller.exportProducts)
+		r.Get("/v1/product/export/{id}", controller.getExportJob)
+		r.Get("/v1/product/export/{id}/file", controller.getExportFile)
+	})
+}
+
+// exportProducts stream the products with the status, brand, color, style, unitType and createdSince filters in the Accept format.
+// The exports with more than domain.MaxSyncExportRows products, or with async=true, are asynchronous jobs
+func (ec *ProductExportController) exportProducts(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	ec.log.With("traceId", traceID).Infof("User %v is exporting products.", claims.Username)
+
+	query, async, err := ec.exportQuery(request)
+	if err != nil {
+		ec.log.With("traceId", traceID).Errorf("Invalid export request: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	resource := domain.PolicyResource{TenantID: domain.TenantFromContext(request.Context()), Status: query.Filter.Status}
+	if !ec.authorize(writer, request, claims, resource, traceID) {
+		return
+	}
+
+	total, err := ec.service.CountProducts(request.Context(), query.Filter, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+	if async || total > domain.MaxSyncExportRows {
+		job, err := ec.service.StartExport(request.Context(), query, total, claims.Username, traceID)
+		if err != nil {
+			dto.RenderErrorResponse(request.Context(), writer, 0, err)
+			return
+		}
+		writer.Header().Set("Location", "/v1/product/export/"+job.ID)
+		dto.RenderResponse(request.Context(), writer, http.StatusAccepted, job)
+		return
+	}
+
+	ec.streamExport(writer, request, query.Format, "products", func(stream io.Writer) error {
+		_, err := ec.service.WriteProducts(request.Context(), query, stream, traceID)
+		return err
+	}, traceID)
+}
+
+// streamExport write the export in the response. The status is sent with the first bytes of the export, so an error before them
+// is an error response. An error after them can not change the status, it is the failed ExportStatusTrailer of the truncated export
+func (ec *ProductExportController) streamExport(writer http.ResponseWriter, request *http.Request, format, fileName string,
+	write func(stream io.Writer) error, traceID string) {
+	ec.writeExportHeaders(writer, format, fileName, traceID)
+	writer.Header().Set("Trailer", ExportStatusTrailer)
+	stream := &exportStream{writer: writer}
+
+	err := write(stream)
+	if err != nil && !stream.committed {
+		ec.log.With("traceId", traceID).Errorf("The export failed before its first products: %v", err)
+		writer.Header().Del("Trailer")
+		writer.Header().Del("Content-Disposition")
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusInternalServerError,
+			custom_error.New(http.StatusInternalServerError, "internal server error"))
+		return
+	}
+	stream.commit()
+	if err != nil {
+		ec.log.With("traceId", traceID).Errorf("The export was cut after its first products: %v", err)
+		writer.Header().Set(ExportStatusTrailer, exportStatusFailed)
+		return
+	}
+	writer.Header().Set(ExportStatusTrailer, exportStatusComplete)
+}
+
+// getExportJob get the export job, only the owner of the export can view it
+func (ec *ProductExportController) getExportJob(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	id := chi.URLParam(request, "id")
+	ec.log.With("traceId", traceID).Infof("User %v is searching the export job %s.", claims.Username, id)
+
+	job, ok := ec.loadAuthorizedJob(writer, request, claims, id, traceID)
+	if !ok {
+		return
+	}
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, job)
+}
+
+// getExportFile download the file of the completed export job
+func (ec *ProductExportController) getExportFile(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	id := chi.URLParam(request, "id")
+	ec.log.With("traceId", traceID).Infof("User %v is downloading the export job %s.", claims.Username, id)
+
+	job, ok := ec.loadAuthorizedJob(writer, request, claims, id, traceID)
+	if !ok {
+		return
+	}
+	file, err := ec.service.OpenExportFile(request.Context(), job, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+
+	ec.streamExport(writer, request, job.Format, "products-"+job.ID, func(stream io.Writer) error {
+		_, err := io.Copy(stream, file)
+		return err
+	}, traceID)
+}
+
+// exportQuery the export query of the request parameters and Accept header
+func (ec *ProductExportController) exportQuery(request *http.Request) (domain.ProductExportQuery, bool, error) {
+	params := request.URL.Query()
+	query := domain.ProductExportQuery{
+		Filter: domain.ProductFilter{
+			Status:   params.Get("status"),
+			Brand:    params.Get("brand"),
+			Color:    params.Get("color"),
+			Style:    params.Get("style"),
+			UnitType: params.Get("unitType"),
+		},
+		Columns: domain.ProductExportColumns,
+	}
+
+	format, ok := negotiateExportFormat(request.Header.Get("Accept"))
+	if !ok {
+		return query, false, custom_error.New(http.StatusNotAcceptable, "the export formats are text/csv, application/x-ndjson and application/json")
+	}
+	query.Format = format
+
+	if value := params.Get("createdSince"); value != "" {
+		createdSince, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, false, custom_error.New(http.StatusBadRequest, "invalid createdSince")
+		}
+		query.Filter.CreatedSince = createdSince
+	}
+	if value := params.Get("columns"); value != "" {
+		query.Columns = nil
+		for _, column := range strings.Split(value, ",") {
+			column = strings.TrimSpace(column)
+			if !domain.IsProductExportColumn(column) {
+				return query, false, custom_error.New(http.StatusBadRequest, fmt.Sprintf("unknown column %s", column))
+			}
+			query.Columns = append(query.Columns, column)
+		}
+	}
+
+	var err error
+	if value := params.Get("excel"); value != "" {
+		if query.Excel, err = strconv.ParseBool(value); err != nil {
+			return query, false, custom_error.New(http.StatusBadRequest, "invalid excel")
+		}
+	}
+	async := false
+	if value := params.Get("async"); value != "" {
+		if async, err = strconv.ParseBool(value); err != nil {
+			return query, false, custom_error.New(http.StatusBadRequest, "invalid async")
+		}
+	}
+	return query, async, nil
+}
+
+// loadAuthorizedJob load the export job and evaluate the policy with the owner of the job
+func (ec *ProductExportController) loadAuthorizedJob(writer http.ResponseWriter, request *http.Request, claims domain.AuthClaims,
+	id, traceID string) (*domain.ProductExportJob, bool) {
+	job, err := ec.service.GetExportJob(request.Context(), id, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return nil, false
+	}
+	if !ec.authorize(writer, request, claims, domain.PolicyResource{Owner: job.User, TenantID: job.TenantID}, traceID) {
+		return nil, false
+	}
+	return job, true
+}
+
+// authorize evaluate the export policy on the resource, rendering the forbidden response when it is denied
+func (ec *ProductExportController) authorize(writer http.ResponseWriter, request *http.Request, claims domain.AuthClaims,
+	resource domain.PolicyResource, traceID string) bool {
+	decision := ec.policyService.EvaluateApiPolicy(request.Context(), claims, domain.ExportProductsOperation, resource)
+	if !decision.Allow {
+		ec.log.With("traceId", traceID).Errorf("Forbidden access: %v", decision.Reasons)
+		dto.RenderForbiddenResponse(request.Context(), writer, decision.Reasons)
+	}
+	return decision.Allow
+}
+
+// writeExportHeaders headers of the export file in the format
+func (ec *ProductExportController) writeExportHeaders(writer http.ResponseWriter, format, fileName, traceID string) {
+	writer.Header().Set(serverMiddleware.RequestIDHeader, traceID)
+	writer.Header().Set("Content-Type", export.ContentTypes[format])
+	writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s"`, fileName, export.FileExtensions[format]))
+}
+
+// negotiateExportFormat the export format of the media type with the highest quality in the Accept header, csv without Accept header
+func negotiateExportFormat(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return domain.ExportCSV, true
+	}
+	format, quality := "", 0.0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		rangeFormat, ok := exportFormats[mediaType]
+		if !ok {
+			continue
+		}
+		rangeQuality := 1.0
+		if value, ok := params["q"]; ok {
+			if rangeQuality, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if rangeQuality > quality {
+			format, quality = rangeFormat, rangeQuality
+		}
+	}
+	return format, format != ""
+}
+
+// exportStream response of a streamed export, the 200 status is only sent with the first bytes of the export
+type exportStream struct {
+	writer    http.ResponseWriter
+	committed bool
+}
+
+// Write send the status before the first bytes
+func (es *exportStream) Write(data []byte) (int, error) {
+	es.commit()
+	return es.writer.Write(data)
+}
+
+// commit send the 200 status once
+func (es *exportStream) commit() {
+	if !es.committed {
+		es.committed = true
+		es.writer.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/core/domain"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestStreamExport for test the status of the streamed exports, before and after their first bytes
+func TestStreamExport(t *testing.T) {
+	controller := &ProductExportController{log: zap.NewNop().Sugar()}
+	failure := errors.New("connection reset")
+	tests := []struct {
+		name    string
+		write   func(stream io.Writer) error
+		status  int
+		body    string
+		trailer string
+	}{
+		{"complete", func(stream io.Writer) error {
+			_, err := io.WriteString(stream, "id\n1\n")
+			return err
+		}, http.StatusOK, "id\n1\n", exportStatusComplete},
+		{"empty", func(stream io.Writer) error {
+			return nil
+		}, http.StatusOK, "", exportStatusComplete},
+		{"failed_before_the_first_bytes", func(stream io.Writer) error {
+			return failure
+		}, http.StatusInternalServerError, "", ""},
+		{"failed_after_the_first_bytes", func(stream io.Writer) error {
+			_, _ = io.WriteString(stream, "id\n1\n")
+			return failure
+		}, http.StatusOK, "id\n1\n", exportStatusFailed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/v1/product/export", nil)
+			request = request.WithContext(context.WithValue(request.Context(), middleware.RequestIDKey, "trace"))
+			recorder := httptest.NewRecorder()
+
+			controller.streamExport(recorder, request, domain.ExportCSV, "products", test.write, "trace")
+
+			response := recorder.Result()
+			assert.Equal(t, test.status, response.StatusCode)
+			assert.Equal(t, test.trailer, response.Trailer.Get(ExportStatusTrailer))
+			if test.status != http.StatusOK {
+				assert.Empty(t, response.Header.Get("Content-Disposition"))
+				assert.Contains(t, recorder.Body.String(), "internal server error")
+				return
+			}
+			assert.Equal(t, test.body, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"golang-api-hexagonal/adapters/api/dto"
+	"golang-api-hexagonal/adapters/api/middleware"
+	"golang-api-hexagonal/adapters/api/router"
+	"golang-api-hexagonal/adapters/opa"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	serverMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
+	"go.uber.org/zap"
+)
+
+// ProductImportController controller for the product bulk import API
+type ProductImportController struct {
+	log           *zap.SugaredLogger
+	validate      *validator.Validate
+	service       ports.IProductImportService
+	jwtVerify     *middleware.JWTVerify
+	policyService *opa.PolicyService
+}
+
+// NewProductImportController create a new http product import controller API
+func NewProductImportController(httpRouter *router.HTTPRouter, log *zap.SugaredLogger, validator *validator.Validate,
+	service ports.IProductImportService, jwtVerify *middleware.JWTVerify, policyService *opa.PolicyService) {
+	controller := &ProductImportController{
+		log:           log,
+		validate:      validator,
+		service:       service,
+		jwtVerify:     jwtVerify,
+		policyService: policyService,
+	}
+	_ = controller.validate.RegisterValidation("not_blank", validators.NotBlank)
+
+	httpRouter.Router.Group(func(r chi.Router) {
+		r.Use(controller.jwtVerify.JWTVerifyHandler())
+		r.Use(httpRouter.RateLimiter.Handler())
+		r.Post("/v1/product/import", controller.importProducts)
+		r.Get("/v1/product/import/{id}", controller.getImportJob)
+	})
+}
+
+// importProducts start the import of the CSV or NDJSON products, the response is the job to follow the import
+func (ic *ProductImportController) importProducts(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	ic.log.With("traceId", traceID).Infof("User %v is importing products.", claims.Username)
+
+	resource := domain.PolicyResource{TenantID: domain.TenantFromContext(request.Context())}
+	decision := ic.policyService.EvaluateApiPolicy(request.Context(), claims, domain.ImportProductsOperation, resource)
+	if !decision.Allow {
+		ic.log.With("traceId", traceID).Errorf("Forbidden access: %v", decision.Reasons)
+		dto.RenderForbiddenResponse(request.Context(), writer, decision.Reasons)
+		return
+	}
+
+	body := http.MaxBytesReader(writer, request.Body, domain.MaxImportBytes)
+	rows, err := dto.ParseProductImport(request.Header.Get("Content-Type"), body, func(product *domain.Product) error {
+		return ic.validate.Struct(product)
+	})
+	if err != nil {
+		ic.log.With("traceId", traceID).Errorf("Error to parsing the product import body: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	job, err := ic.service.StartImport(request.Context(), rows, claims.Username, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+	writer.Header().Set("Location", "/v1/product/import/"+job.ID)
+	dto.RenderResponse(request.Context(), writer, http.StatusAccepted, job)
+}
+
+// getImportJob get the import job with its progress and error report, only the owner of the import can view it
+func (ic *ProductImportController) getImportJob(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	id := chi.URLParam(request, "id")
+	ic.log.With("traceId", traceID).Infof("User %v is searching the import job %s.", claims.Username, id)
+
+	job, err := ic.service.GetImportJob(request.Context(), id, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+
+	resource := domain.PolicyResource{Owner: job.User, TenantID: job.TenantID}
+	decision := ic.policyService.EvaluateApiPolicy(request.Context(), claims, domain.ImportProductsOperation, resource)
+	if !decision.Allow {
+		ic.log.With("traceId", traceID).Errorf("Forbidden access: %v", decision.Reasons)
+		dto.RenderForbiddenResponse(request.Context(), writer, decision.Reasons)
+		return
+	}
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, job)
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"golang-api-hexagonal/adapters/api/dto"
+	"golang-api-hexagonal/adapters/api/middleware"
+	"golang-api-hexagonal/adapters/api/router"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/adapters/opa"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	serverMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// ProductSearchController controller for the product search API
+type ProductSearchController struct {
+	log           *zap.SugaredLogger
+	validate      *validator.Validate
+	service       ports.IProductSearchService
+	jwtVerify     *middleware.JWTVerify
+	policyService *opa.PolicyService
+}
+
+// NewProductSearchController create a new http product search controller API
+func NewProductSearchController(httpRouter *router.HTTPRouter, log *zap.SugaredLogger, validator *validator.Validate,
+	service ports.IProductSearchService, jwtVerify *middleware.JWTVerify, policyService *opa.PolicyService) {
+	controller := &ProductSearchController{
+		log:           log,
+		validate:      validator,
+		service:       service,
+		jwtVerify:     jwtVerify,
+		policyService: policyService,
+	}
+
+	httpRouter.Router.Group(func(r chi.Router) {
+		r.Use(controller.jwtVerify.JWTVerifyHandler())
+		r.Use(httpRouter.RateLimiter.Handler())
+		r.Get("/v1/product/search", controller.searchProducts)
+	})
+}
+
+// searchProducts search the products with the q, brand, color, style, unitType, status, sort, cursor and limit query parameters
+func (sc *ProductSearchController) searchProducts(writer http.ResponseWriter, request *http.Request) {
+	traceID := request.Context().Value(serverMiddleware.RequestIDKey).(string)
+	claims := request.Context().Value(domain.ClaimsKey).(domain.AuthClaims)
+	sc.log.With("traceId", traceID).Infof("User %v is searching products.", claims.Username)
+
+	params := request.URL.Query()
+	query := domain.ProductSearchQuery{
+		Text:     params.Get("q"),
+		Brand:    params.Get("brand"),
+		Color:    params.Get("color"),
+		Style:    params.Get("style"),
+		UnitType: params.Get("unitType"),
+		Status:   params.Get("status"),
+		Sort:     params.Get("sort"),
+		Cursor:   params.Get("cursor"),
+		Limit:    domain.DefaultProductSearchLimit,
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			sc.log.With("traceId", traceID).Errorf("Invalid search limit: %s", value)
+			dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, custom_error.New(http.StatusBadRequest, "invalid limit"))
+			return
+		}
+		query.Limit = limit
+	}
+
+	err := sc.validate.Struct(query)
+	if err != nil {
+		sc.log.With("traceId", traceID).Errorf("Product search validation error: %v", err)
+		dto.RenderErrorResponse(request.Context(), writer, http.StatusBadRequest, err)
+		return
+	}
+
+	decision := sc.policyService.EvaluateApiPolicy(request.Context(), claims, domain.SearchProductsOperation, domain.PolicyResource{})
+	if !decision.Allow {
+		sc.log.With("traceId", traceID).Errorf("Forbidden access: %v", decision.Reasons)
+		dto.RenderForbiddenResponse(request.Context(), writer, decision.Reasons)
+		return
+	}
+
+	response, err := sc.service.SearchProducts(request.Context(), query, traceID)
+	if err != nil {
+		dto.RenderErrorResponse(request.Context(), writer, 0, err)
+		return
+	}
+	dto.RenderResponse(request.Context(), writer, http.StatusOK, response)
+}
@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"strconv"
+	"strings"
+)
+
+// VersionETag strong entity tag of the version of a resource
+func VersionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ETagMatches the If-Match or If-None-Match header matches the strong entity tag, the header is a list of entity tags or *.
+// If-None-Match uses the weak comparison, so the weak entity tags match by their value, and If-Match the strong comparison
+func ETagMatches(header, etag string, weakComparison bool) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if weakComparison {
+			value = strings.TrimPrefix(value, "W/")
+		}
+		if value == "*" || value == etag {
+			return true
+		}
+	}
+	return false
+}
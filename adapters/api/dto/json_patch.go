@@ -0,0 +1,320 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-api-hexagonal/adapters/custom_error"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Content types of the partial updates
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// patchOperation operation of a JSON Patch document
+type patchOperation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// ApplyPatch apply the merge patch (RFC 7396) or the JSON Patch (RFC 6902) of the content type to the json document.
+// A malformed patch returns 400, a patch that can not be applied 422 and a failed test operation 409
+func ApplyPatch(contentType string, document, patch []byte) ([]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, custom_error.New(http.StatusUnsupportedMediaType, "unsupported content type")
+	}
+	switch mediaType {
+	case MergePatchContentType:
+		return ApplyMergePatch(document, patch)
+	case JSONPatchContentType:
+		return ApplyJSONPatch(document, patch)
+	default:
+		return nil, custom_error.New(http.StatusUnsupportedMediaType,
+			fmt.Sprintf("the partial updates are %s or %s", MergePatchContentType, JSONPatchContentType))
+	}
+}
+
+// ApplyMergePatch apply the JSON Merge Patch to the json document, the null values remove the fields
+func ApplyMergePatch(document, patch []byte) ([]byte, error) {
+	var target, patchValue interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, custom_error.New(http.StatusBadRequest, "malformed merge patch: "+err.Error())
+	}
+	return json.Marshal(mergePatch(target, patchValue))
+}
+
+// ApplyJSONPatch apply the operations of the JSON Patch to the json document, the operations are applied in order
+// and the document is not changed when one of them fails
+func ApplyJSONPatch(document, patch []byte) ([]byte, error) {
+	var operations []patchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, custom_error.New(http.StatusBadRequest, "malformed json patch: "+err.Error())
+	}
+	var target interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+
+	for index, operation := range operations {
+		var err error
+		if target, err = applyOperation(target, operation); err != nil {
+			var customError *custom_error.StatusError
+			if errors.As(err, &customError) {
+				return nil, custom_error.New(customError.ErrorCode(), fmt.Sprintf("operation %d: %s", index, err.Error()))
+			}
+			return nil, err
+		}
+	}
+	return json.Marshal(target)
+}
+
+// mergePatch merge the patch in the target
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// applyOperation apply the operation to the document, returns the updated document
+func applyOperation(document interface{}, operation patchOperation) (interface{}, error) {
+	if operation.Path == nil {
+		return nil, custom_error.New(http.StatusBadRequest, "missing path")
+	}
+	path, err := parsePointer(*operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, custom_error.New(http.StatusBadRequest, "missing value")
+		}
+		if err = json.Unmarshal(*operation.Value, &value); err != nil {
+			return nil, custom_error.New(http.StatusBadRequest, "malformed value")
+		}
+	case "move", "copy":
+		if operation.From == nil {
+			return nil, custom_error.New(http.StatusBadRequest, "missing from")
+		}
+	}
+
+	switch operation.Op {
+	case "add":
+		return addValue(document, path, value)
+	case "remove":
+		document, _, err = removeValue(document, path)
+		return document, err
+	case "replace":
+		if len(path) == 0 {
+			return value, nil
+		}
+		if document, _, err = removeValue(document, path); err != nil {
+			return nil, err
+		}
+		return addValue(document, path, value)
+	case "move":
+		from, err := parsePointer(*operation.From)
+		if err != nil {
+			return nil, err
+		}
+		if len(from) < len(path) && reflect.DeepEqual(from, path[:len(from)]) {
+			return nil, custom_error.New(http.StatusUnprocessableEntity, "a value can not be moved into itself")
+		}
+		document, value, err = removeValue(document, from)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(document, path, value)
+	case "copy":
+		from, err := parsePointer(*operation.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = getValue(document, from); err != nil {
+			return nil, err
+		}
+		data, _ := json.Marshal(value)
+		_ = json.Unmarshal(data, &value)
+		return addValue(document, path, value)
+	case "test":
+		current, err := getValue(document, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, custom_error.New(http.StatusConflict, fmt.Sprintf("test failed at %s", *operation.Path))
+		}
+		return document, nil
+	default:
+		return nil, custom_error.New(http.StatusBadRequest, fmt.Sprintf("unknown op %s", operation.Op))
+	}
+}
+
+// parsePointer reference tokens of the JSON Pointer (RFC 6901), the empty pointer is the whole document
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, custom_error.New(http.StatusBadRequest, fmt.Sprintf("invalid path %s", pointer))
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for index, token := range tokens {
+		tokens[index] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// getValue value at the path of the node
+func getValue(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch typed := node.(type) {
+		case map[string]interface{}:
+			child, ok := typed[token]
+			if !ok {
+				return nil, pathNotFound(token)
+			}
+			node = child
+		case []interface{}:
+			index, err := arrayIndex(token, len(typed)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = typed[index]
+		default:
+			return nil, pathNotFound(token)
+		}
+	}
+	return node, nil
+}
+
+// addValue add the value at the path of the node, the array values are inserted. Returns the updated node
+func addValue(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token := path[0]
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			typed[token] = value
+			return typed, nil
+		}
+		child, ok := typed[token]
+		if !ok {
+			return nil, pathNotFound(token)
+		}
+		updated, err := addValue(child, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		typed[token] = updated
+		return typed, nil
+	case []interface{}:
+		if len(path) == 1 {
+			index := len(typed)
+			if token != "-" {
+				var err error
+				if index, err = arrayIndex(token, len(typed)); err != nil {
+					return nil, err
+				}
+			}
+			typed = append(typed, nil)
+			copy(typed[index+1:], typed[index:])
+			typed[index] = value
+			return typed, nil
+		}
+		index, err := arrayIndex(token, len(typed)-1)
+		if err != nil {
+			return nil, err
+		}
+		if typed[index], err = addValue(typed[index], path[1:], value); err != nil {
+			return nil, err
+		}
+		return typed, nil
+	default:
+		return nil, pathNotFound(token)
+	}
+}
+
+// removeValue remove the value at the path of the node, returns the updated node and the removed value
+func removeValue(node interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, custom_error.New(http.StatusUnprocessableEntity, "the document can not be removed")
+	}
+	token := path[0]
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		child, ok := typed[token]
+		if !ok {
+			return nil, nil, pathNotFound(token)
+		}
+		if len(path) == 1 {
+			delete(typed, token)
+			return typed, child, nil
+		}
+		updated, removed, err := removeValue(child, path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		typed[token] = updated
+		return typed, removed, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(typed)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(path) == 1 {
+			removed := typed[index]
+			return append(typed[:index], typed[index+1:]...), removed, nil
+		}
+		updated, removed, err := removeValue(typed[index], path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		typed[index] = updated
+		return typed, removed, nil
+	default:
+		return nil, nil, pathNotFound(token)
+	}
+}
+
+// arrayIndex index of the array token, up to the max index
+func arrayIndex(token string, maxIndex int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > maxIndex || (len(token) > 1 && token[0] == '0') {
+		return 0, pathNotFound(token)
+	}
+	return index, nil
+}
+
+func pathNotFound(token string) error {
+	return custom_error.New(http.StatusUnprocessableEntity, fmt.Sprintf("path not found at %s", token))
+}
@@ -0,0 +1,59 @@
+package dto
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/adapters/custom_error"
+	"testing"
+)
+
+const patchDocument = `{"name":"shoe","description":"red shoe","tags":["a","b"],"size":{"eu":42}}`
+
+// TestApplyMergePatch for test the merge and the removal of the fields
+func TestApplyMergePatch(t *testing.T) {
+	patched, err := ApplyPatch(MergePatchContentType, []byte(patchDocument), []byte(`{"description":null,"size":{"us":9},"tags":["c"]}`))
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"shoe","tags":["c"],"size":{"eu":42,"us":9}}`, string(patched))
+}
+
+// TestApplyJSONPatch for test the operations of the JSON Patch
+func TestApplyJSONPatch(t *testing.T) {
+	patch := `[
+		{"op":"test","path":"/name","value":"shoe"},
+		{"op":"replace","path":"/description","value":"blue shoe"},
+		{"op":"add","path":"/tags/1","value":"x"},
+		{"op":"add","path":"/tags/-","value":"z"},
+		{"op":"remove","path":"/tags/0"},
+		{"op":"copy","from":"/size/eu","path":"/size/fr"},
+		{"op":"move","from":"/size/eu","path":"/eu~1size"}
+	]`
+
+	patched, err := ApplyPatch(JSONPatchContentType+"; charset=utf-8", []byte(patchDocument), []byte(patch))
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"shoe","description":"blue shoe","tags":["x","b","z"],"size":{"fr":42},"eu/size":42}`, string(patched))
+}
+
+// TestApplyJSONPatchErrors for test the status of the patch errors
+func TestApplyJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		contentType string
+		patch       string
+		status      int
+	}{
+		{JSONPatchContentType, `{"op":"add"}`, 400},
+		{JSONPatchContentType, `[{"op":"copy","path":"/name"}]`, 400},
+		{JSONPatchContentType, `[{"op":"remove","path":"/unknown"}]`, 422},
+		{JSONPatchContentType, `[{"op":"replace","path":"/tags/2","value":"c"}]`, 422},
+		{JSONPatchContentType, `[{"op":"move","from":"/size","path":"/size/eu"}]`, 422},
+		{JSONPatchContentType, `[{"op":"test","path":"/name","value":"boot"}]`, 409},
+		{"application/json", `{}`, 415},
+	}
+
+	for _, test := range tests {
+		_, err := ApplyPatch(test.contentType, []byte(patchDocument), []byte(test.patch))
+		statusError, ok := err.(*custom_error.StatusError)
+		assert.True(t, ok, test.patch)
+		assert.Equal(t, test.status, statusError.ErrorCode(), test.patch)
+	}
+}
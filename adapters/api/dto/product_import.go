@@ -0,0 +1,172 @@
+package dto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/core/domain"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Content types of the product import
+const (
+	CSVContentType    = "text/csv"
+	NDJSONContentType = "application/x-ndjson"
+)
+
+// maxNDJSONLineBytes max size of a product line
+const maxNDJSONLineBytes = 64 << 10
+
+// importColumns setters of the product fields by CSV column, the columns are the json names of the fields
+var importColumns = map[string]func(product *domain.Product, value string){
+	"name":        func(product *domain.Product, value string) { product.Name = value },
+	"description": func(product *domain.Product, value string) { product.Description = value },
+	"unitType":    func(product *domain.Product, value string) { product.UnitType = value },
+	"unit":        func(product *domain.Product, value string) { product.Unit = value },
+	"brand":       func(product *domain.Product, value string) { product.Brand = value },
+	"color":       func(product *domain.Product, value string) { product.Color = value },
+	"style":       func(product *domain.Product, value string) { product.Style = value },
+	"status":      func(product *domain.Product, value string) { product.Status = value },
+}
+
+// ParseProductImport parse and validate the products of a CSV or NDJSON body. The row is the line of the product, the invalid rows
+// have their error so the valid rows can still be imported. The errors are returned when the whole body is invalid
+func ParseProductImport(contentType string, body io.Reader, validate func(product *domain.Product) error) ([]domain.ProductImportRow, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, custom_error.New(http.StatusUnsupportedMediaType, "unsupported content type")
+	}
+
+	var rows []domain.ProductImportRow
+	switch mediaType {
+	case CSVContentType:
+		rows, err = parseCSVImport(body, validate)
+	case NDJSONContentType:
+		rows, err = parseNDJSONImport(body, validate)
+	default:
+		return nil, custom_error.New(http.StatusUnsupportedMediaType, "unsupported content type")
+	}
+
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return nil, custom_error.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("the import is limited to %d bytes", maxBytesError.Limit))
+	} else if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, custom_error.New(http.StatusBadRequest, "the import has no products")
+	}
+	return rows, nil
+}
+
+// parseCSVImport parse the CSV rows, the first line is the header with the columns of the rows
+func parseCSVImport(body io.Reader, validate func(product *domain.Product) error) ([]domain.ProductImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, csvError(err)
+	}
+	for _, column := range header {
+		if _, ok := importColumns[column]; !ok {
+			return nil, custom_error.New(http.StatusBadRequest, fmt.Sprintf("unknown column %s", column))
+		}
+	}
+
+	var rows []domain.ProductImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		// the position of the fields is only known after a successful read, the line of a malformed row is in its parse error
+		var parseError *csv.ParseError
+		if err != nil && (!errors.As(err, &parseError) || !errors.Is(err, csv.ErrFieldCount)) {
+			return nil, csvError(err)
+		}
+		if len(rows) == domain.MaxImportRows {
+			return nil, tooManyRowsError()
+		}
+		if err != nil {
+			rows = append(rows, domain.ProductImportRow{Row: parseError.Line, Error: fmt.Sprintf("expected %d columns", len(header))})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		product := &domain.Product{}
+		for index, column := range header {
+			importColumns[column](product, record[index])
+		}
+		rows = append(rows, validatedRow(line, product, validate))
+	}
+}
+
+// parseNDJSONImport parse the NDJSON rows, one product by line, the empty lines are ignored
+func parseNDJSONImport(body io.Reader, validate func(product *domain.Product) error) ([]domain.ProductImportRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxNDJSONLineBytes)
+
+	var rows []domain.ProductImportRow
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(rows) == domain.MaxImportRows {
+			return nil, tooManyRowsError()
+		}
+
+		product := &domain.Product{}
+		if err := json.Unmarshal(data, product); err != nil {
+			rows = append(rows, domain.ProductImportRow{Row: line, Error: "malformed product: " + err.Error()})
+			continue
+		}
+		rows = append(rows, validatedRow(line, product, validate))
+	}
+
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return nil, custom_error.New(http.StatusBadRequest, fmt.Sprintf("the product lines are limited to %d bytes", maxNDJSONLineBytes))
+	}
+	return rows, scanner.Err()
+}
+
+// validatedRow the row of the product with its validation error
+func validatedRow(line int, product *domain.Product, validate func(product *domain.Product) error) domain.ProductImportRow {
+	err := validate(product)
+	if err == nil {
+		return domain.ProductImportRow{Row: line, Product: product}
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return domain.ProductImportRow{Row: line, Error: err.Error()}
+	}
+	issues := make([]string, 0, len(validationErrors))
+	for _, validationErr := range validationErrors {
+		issues = append(issues, fmt.Sprintf("%s is %s", validationErr.Field(), validationErr.Tag()))
+	}
+	return domain.ProductImportRow{Row: line, Error: "validation errors: " + strings.Join(issues, ", ")}
+}
+
+// csvError error of a malformed CSV body, the body size error is kept
+func csvError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return err
+	}
+	return custom_error.New(http.StatusBadRequest, "malformed CSV: "+err.Error())
+}
+
+func tooManyRowsError() error {
+	return custom_error.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("the import is limited to %d products", domain.MaxImportRows))
+}
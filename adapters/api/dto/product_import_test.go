@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/core/domain"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func validateName(product *domain.Product) error {
+	if product.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// TestParseCSVImport for test the CSV columns and the row errors
+func TestParseCSVImport(t *testing.T) {
+	body := "name,unit,status\nfirst,1,pending\n,2,pending\nthird\n"
+
+	rows, err := ParseProductImport("text/csv; charset=utf-8", strings.NewReader(body), validateName)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.ProductImportRow{
+		{Row: 2, Product: &domain.Product{Name: "first", Unit: "1", Status: "pending"}},
+		{Row: 3, Error: "name is required"},
+		{Row: 4, Error: "expected 3 columns"},
+	}, rows)
+
+	_, err = ParseProductImport("text/csv", strings.NewReader("name,price\nfirst,1\n"), validateName)
+	assert.Equal(t, "unknown column price", err.Error())
+}
+
+// TestParseMalformedCSVImport for test the malformed CSV and the oversized bodies
+func TestParseMalformedCSVImport(t *testing.T) {
+	_, err := ParseProductImport(CSVContentType, strings.NewReader("name,unit\nfirst,1\nsec\"ond,2\n"), validateName)
+	var customError *custom_error.StatusError
+	assert.ErrorAs(t, err, &customError)
+	assert.Equal(t, http.StatusBadRequest, customError.ErrorCode())
+	assert.Contains(t, err.Error(), "malformed CSV")
+
+	body := http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader("name,unit\nfirst,1\nsecond,2\n")), 16)
+	_, err = ParseProductImport(CSVContentType, body, validateName)
+	assert.ErrorAs(t, err, &customError)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, customError.ErrorCode())
+}
+
+// TestParseNDJSONImport for test the NDJSON lines and the row errors
+func TestParseNDJSONImport(t *testing.T) {
+	body := "{\"name\":\"first\",\"brand\":\"acme\"}\n\n{\"name\":\n"
+
+	rows, err := ParseProductImport(NDJSONContentType, strings.NewReader(body), validateName)
+
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, domain.ProductImportRow{Row: 1, Product: &domain.Product{Name: "first", Brand: "acme"}}, rows[0])
+	assert.Equal(t, 3, rows[1].Row)
+	assert.Contains(t, rows[1].Error, "malformed product")
+
+	_, err = ParseProductImport("application/xml", strings.NewReader(body), validateName)
+	assert.Equal(t, "unsupported content type", err.Error())
+}
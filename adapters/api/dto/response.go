@@ -4,12 +4,25 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
 	"golang-api-hexagonal/adapters/custom_error"
 	"net/http"
 )
 
+// MaxBodyBytes max size of the json bodies of the product API
+const MaxBodyBytes = 1 << 20
+
+// BodyTooLargeError the 413 error of a body read over the limit of http.MaxBytesReader, nil for the other errors
+func BodyTooLargeError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return custom_error.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("the body is limited to %d bytes", maxBytesError.Limit))
+	}
+	return nil
+}
+
 // DefaultResponse create a default response object
 func DefaultResponse(codeDescription, message string) map[string]interface{} {
 	return map[string]interface{}{
@@ -33,6 +46,13 @@ func RenderResponse(ctx context.Context, writer http.ResponseWriter, httpStatusC
 	_, _ = writer.Write(marshal)
 }
 
+// RenderForbiddenResponse render http forbidden response with the reason codes of the policy decision
+func RenderForbiddenResponse(ctx context.Context, writer http.ResponseWriter, reasons []string) {
+	response := DefaultResponse(http.StatusText(http.StatusForbidden), "forbidden access")
+	response["reasons"] = reasons
+	RenderResponse(ctx, writer, http.StatusForbidden, response)
+}
+
 // RenderErrorResponse render http error response
 func RenderErrorResponse(ctx context.Context, writer http.ResponseWriter, httpStatusCode int, err error) {
 	var response map[string]interface{}
@@ -40,6 +60,14 @@ func RenderErrorResponse(ctx context.Context, writer http.ResponseWriter, httpSt
 	var customError *custom_error.StatusError
 	if errors.As(err, &customError) {
 		response = DefaultResponse(http.StatusText(customError.ErrorCode()), err.Error())
+		var transitionError *custom_error.TransitionError
+		if errors.As(err, &transitionError) {
+			response["details"] = map[string]interface{}{
+				"from":    transitionError.From,
+				"to":      transitionError.To,
+				"allowed": transitionError.Allowed,
+			}
+		}
 		RenderResponse(ctx, writer, customError.ErrorCode(), response)
 		return
 	}
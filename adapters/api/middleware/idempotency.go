@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/api/dto"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	serverMiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// IdempotencyKeyHeader header with the client key of the request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader header of the stored responses
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// IdempotencyKeyDuration expiration time of the stored responses
+	IdempotencyKeyDuration = time.Hour * 24
+	// idempotencyLockDuration expiration time of a request in flight, the router timeout
+	idempotencyLockDuration = time.Second * 60
+	// idempotencyStoreTimeout max duration to store the response or release the key once the request is served
+	idempotencyStoreTimeout = time.Second * 5
+	idempotencyKeyMaxLength = 255
+	idempotencyKeyPrefix    = "idempotency:"
+)
+
+// idempotencyRecord request in flight or its stored response
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Idempotency idempotency key handler, the first response of a key is replayed to the retries of the same request
+type Idempotency struct {
+	log   *zap.SugaredLogger
+	cache ports.IRedis
+}
+
+// NewIdempotencyHandler create new idempotency key handler
+func NewIdempotencyHandler(log *zap.SugaredLogger, cache ports.IRedis) *Idempotency {
+	return &Idempotency{
+		log:   log,
+		cache: cache,
+	}
+}
+
+// Handler store the response of the requests with an idempotency key by tenant and user, so it must be used after the JWT verify handler.
+// The retries with the same method, path and body get the stored response, other requests with the same key get 422
+// and the retries while the first request is in flight get 409. The server errors are not stored so the request can be retried.
+func (id *Idempotency) Handler() func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+			if idempotencyKey == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			traceID, _ := r.Context().Value(serverMiddleware.RequestIDKey).(string)
+			if len(idempotencyKey) > idempotencyKeyMaxLength {
+				id.log.With("traceId", traceID).Errorf("Invalid idempotency key with %d characters", len(idempotencyKey))
+				dto.RenderErrorResponse(r.Context(), w, http.StatusBadRequest, custom_error.New(http.StatusBadRequest, "invalid idempotency key"))
+				return
+			}
+
+			// the body is read before the handler, so it has the body limit of the handlers
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, dto.MaxBodyBytes))
+			if tooLarge := dto.BodyTooLargeError(err); tooLarge != nil {
+				id.log.With("traceId", traceID).Errorf("The request body is larger than %d bytes", dto.MaxBodyBytes)
+				dto.RenderErrorResponse(r.Context(), w, 0, tooLarge)
+				return
+			} else if err != nil {
+				id.log.With("traceId", traceID).Errorf("Error to read the request body: %v", err)
+				dto.RenderErrorResponse(r.Context(), w, http.StatusBadRequest, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key := id.key(r, idempotencyKey)
+			fingerprint := requestFingerprint(r, body)
+			record, _ := json.Marshal(&idempotencyRecord{Fingerprint: fingerprint})
+			acquired, err := id.cache.SetNX(r.Context(), key, record, idempotencyLockDuration).Result()
+			if err != nil {
+				id.log.With("traceId", traceID).Errorf("Idempotency key not verified, Redis error: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !acquired {
+				id.replay(w, r, key, fingerprint, traceID)
+				return
+			}
+
+			id.serveAndStore(next, w, r, key, fingerprint, traceID)
+		})
+	}
+}
+
+// serveAndStore serve the request and store its response, the key is released when the response is a server error.
+// The client can be gone when the request is served, so the response is stored with a context that is not cancelled with the request
+func (id *Idempotency) serveAndStore(next http.Handler, w http.ResponseWriter, r *http.Request, key, fingerprint, traceID string) {
+	response := &bytes.Buffer{}
+	writer := serverMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	writer.Tee(response)
+	next.ServeHTTP(writer, r)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), idempotencyStoreTimeout)
+	defer cancel()
+
+	status := writer.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status >= http.StatusInternalServerError {
+		if err := id.cache.Del(ctx, key).Err(); err != nil {
+			id.log.With("traceId", traceID).Errorf("Error to release the idempotency key: %v", err)
+		}
+		return
+	}
+
+	record, _ := json.Marshal(&idempotencyRecord{
+		Fingerprint: fingerprint,
+		Completed:   true,
+		Status:      status,
+		ContentType: writer.Header().Get("Content-Type"),
+		Body:        response.Bytes(),
+	})
+	if err := id.cache.Set(ctx, key, record, IdempotencyKeyDuration).Err(); err != nil {
+		id.log.With("traceId", traceID).Errorf("Error to store the idempotent response: %v", err)
+	}
+}
+
+// replay write the stored response of the key
+func (id *Idempotency) replay(w http.ResponseWriter, r *http.Request, key, fingerprint, traceID string) {
+	value, err := id.cache.Get(r.Context(), key).Bytes()
+	if err == redis.Nil {
+		// the first request failed and released the key meanwhile
+		dto.RenderErrorResponse(r.Context(), w, http.StatusConflict, custom_error.New(http.StatusConflict, "request with the same idempotency key in progress"))
+		return
+	}
+	record := &idempotencyRecord{}
+	if err == nil {
+		err = json.Unmarshal(value, record)
+	}
+	if err != nil {
+		id.log.With("traceId", traceID).Errorf("Internal error to get the idempotent response: %v", err)
+		dto.RenderErrorResponse(r.Context(), w, http.StatusInternalServerError, custom_error.New(http.StatusInternalServerError, "internal server error"))
+		return
+	}
+
+	switch {
+	case record.Fingerprint != fingerprint:
+		id.log.With("traceId", traceID).Errorf("Idempotency key reused with another request")
+		dto.RenderErrorResponse(r.Context(), w, http.StatusUnprocessableEntity,
+			custom_error.New(http.StatusUnprocessableEntity, "idempotency key already used with another request"))
+	case !record.Completed:
+		id.log.With("traceId", traceID).Errorf("Request with the same idempotency key in progress")
+		dto.RenderErrorResponse(r.Context(), w, http.StatusConflict, custom_error.New(http.StatusConflict, "request with the same idempotency key in progress"))
+	default:
+		id.log.With("traceId", traceID).Infof("Replaying the response of the idempotency key")
+		w.Header().Set(serverMiddleware.RequestIDHeader, traceID)
+		w.Header().Set(IdempotentReplayedHeader, strconv.FormatBool(true))
+		if record.ContentType != "" {
+			w.Header().Set("Content-Type", record.ContentType)
+		}
+		w.WriteHeader(record.Status)
+		_, _ = w.Write(record.Body)
+	}
+}
+
+// key cache key of the idempotency key of the tenant and user
+func (id *Idempotency) key(r *http.Request, idempotencyKey string) string {
+	user := ""
+	if claims, ok := r.Context().Value(domain.ClaimsKey).(domain.AuthClaims); ok {
+		user = claims.Username
+		if strings.HasPrefix(claims.Subject, domain.ApiKeySubjectPrefix) {
+			user = claims.Subject
+		}
+	}
+	hash := sha256.Sum256([]byte(idempotencyKey))
+	return idempotencyKeyPrefix + domain.TenantFromContext(r.Context()) + ":" + user + ":" + hex.EncodeToString(hash[:])
+}
+
+// requestFingerprint hash of the method, path and body of the request
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
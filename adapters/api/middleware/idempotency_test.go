@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/api/dto"
+	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/core/domain"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestIdempotencyReplaysTheStoredResponse for test the replay, the mismatched request and the request in flight
+func TestIdempotencyReplaysTheStoredResponse(t *testing.T) {
+	redisCache := &cache.RedisCacheMock{}
+	mockIdempotencyStore(redisCache)
+	handler := NewIdempotencyHandler(zap.NewNop().Sugar(), redisCache)
+	calls := 0
+	server := handler.Handler()(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		calls++
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusCreated)
+		_, _ = writer.Write([]byte(`{"id":"1"}`))
+	}))
+
+	first := serveIdempotent(server, "key-1", `{"name":"product"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	replay := serveIdempotent(server, "key-1", `{"name":"product"}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, `{"id":"1"}`, replay.Body.String())
+	assert.Equal(t, "application/json", replay.Header().Get("Content-Type"))
+	assert.Equal(t, "true", replay.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, serveIdempotent(server, "key-1", `{"name":"other"}`).Code)
+
+	inFlight := serveIdempotent(handler.Handler()(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, http.StatusConflict, serveIdempotent(server, "key-2", `{"name":"product"}`).Code)
+		writer.WriteHeader(http.StatusCreated)
+	})), "key-2", `{"name":"product"}`)
+	assert.Equal(t, http.StatusCreated, inFlight.Code)
+	assert.Equal(t, 1, calls)
+}
+
+// TestIdempotencyReleasesTheKeyOnServerError for test the retry after a server error
+func TestIdempotencyReleasesTheKeyOnServerError(t *testing.T) {
+	redisCache := &cache.RedisCacheMock{}
+	mockIdempotencyStore(redisCache)
+	handler := NewIdempotencyHandler(zap.NewNop().Sugar(), redisCache)
+	status := http.StatusInternalServerError
+	server := handler.Handler()(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(status)
+	}))
+
+	assert.Equal(t, http.StatusInternalServerError, serveIdempotent(server, "key-1", `{}`).Code)
+	status = http.StatusCreated
+	assert.Equal(t, http.StatusCreated, serveIdempotent(server, "key-1", `{}`).Code)
+}
+
+// TestIdempotencyStoresTheResponseOfACancelledRequest for test the store of the response when the client is gone
+func TestIdempotencyStoresTheResponseOfACancelledRequest(t *testing.T) {
+	redisCache := &cache.RedisCacheMock{}
+	mockIdempotencyStore(redisCache)
+	set := redisCache.SetFunc
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+		assert.NoError(t, ctx.Err())
+		return set(ctx, key, value, expiration)
+	}
+	handler := NewIdempotencyHandler(zap.NewNop().Sugar(), redisCache)
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), domain.ClaimsKey, domain.AuthClaims{Username: "john"}))
+	server := handler.Handler()(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// the client disconnects while the product is created
+		cancel()
+		writer.WriteHeader(http.StatusCreated)
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/product", strings.NewReader(`{}`)).WithContext(ctx)
+	request.Header.Set(IdempotencyKeyHeader, "key-1")
+	server.ServeHTTP(httptest.NewRecorder(), request)
+
+	replay := serveIdempotent(server, "key-1", `{}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get(IdempotentReplayedHeader))
+}
+
+// TestIdempotencyWithTooLargeBody for test the body limit of the requests with an idempotency key
+func TestIdempotencyWithTooLargeBody(t *testing.T) {
+	redisCache := &cache.RedisCacheMock{}
+	mockIdempotencyStore(redisCache)
+	server := NewIdempotencyHandler(zap.NewNop().Sugar(), redisCache).Handler()(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		t.Error("the handler must not be called")
+	}))
+
+	response := serveIdempotent(server, "key-1", strings.Repeat("a", dto.MaxBodyBytes+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+}
+
+func mockIdempotencyStore(redisCache *cache.RedisCacheMock) {
+	store := map[string]string{}
+	redisCache.SetNXFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+		cmd := redis.NewBoolCmd(ctx)
+		if _, ok := store[key]; ok {
+			cmd.SetVal(false)
+			return cmd
+		}
+		store[key] = string(value.([]byte))
+		cmd.SetVal(true)
+		return cmd
+	}
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+		store[key] = string(value.([]byte))
+		return redis.NewStatusCmd(ctx)
+	}
+	redisCache.GetFunc = func(ctx context.Context, key string) *redis.StringCmd {
+		cmd := redis.NewStringCmd(ctx)
+		value, ok := store[key]
+		if !ok {
+			cmd.SetErr(redis.Nil)
+		}
+		cmd.SetVal(value)
+		return cmd
+	}
+	redisCache.DelFunc = func(ctx context.Context, keys ...string) *redis.IntCmd {
+		for _, key := range keys {
+			delete(store, key)
+		}
+		return redis.NewIntCmd(ctx)
+	}
+}
+
+func serveIdempotent(handler http.Handler, idempotencyKey, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/v1/product", strings.NewReader(body))
+	request.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+	request = request.WithContext(context.WithValue(request.Context(), domain.ClaimsKey, domain.AuthClaims{Username: "john"}))
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response
+}
@@ -14,14 +14,16 @@ import (
 // JWTVerify jwt verify token
 type JWTVerify struct {
 	log     *zap.SugaredLogger
-	service ports.IAuthService
+	service ports.ITokenVerifier
+	apiKeys ports.IApiKeyVerifier
 }
 
-// NewJWTHandler create new JWT verify handler
-func NewJWTHandler(log *zap.SugaredLogger, service ports.IAuthService) *JWTVerify {
+// NewJWTHandler create new JWT verify handler, the requests can be authenticated with a bearer token or an api key
+func NewJWTHandler(log *zap.SugaredLogger, service ports.ITokenVerifier, apiKeys ports.IApiKeyVerifier) *JWTVerify {
 	return &JWTVerify{
 		log:     log,
 		service: service,
+		apiKeys: apiKeys,
 	}
 }
 
@@ -36,6 +38,10 @@ func (jw *JWTVerify) JWTVerifyHandler() func(handler http.Handler) http.Handler
 				return
 			}
 
+			if claims.TenantID == "" {
+				claims.TenantID = domain.DefaultTenantID
+			}
+			r = r.WithContext(domain.WithTenant(r.Context(), claims.TenantID))
 			r = r.WithContext(context.WithValue(r.Context(), domain.ClaimsKey, *claims))
 			r = r.WithContext(context.WithValue(r.Context(), domain.JwtTokenKey, token))
 			next.ServeHTTP(w, r)
@@ -44,6 +50,14 @@ func (jw *JWTVerify) JWTVerifyHandler() func(handler http.Handler) http.Handler
 }
 
 func (jw *JWTVerify) parseTokenFromRequest(r *http.Request) (*domain.AuthClaims, string, error) {
+	if apiKey := r.Header.Get(domain.ApiKeyHeader); len(apiKey) > 0 && jw.apiKeys != nil {
+		authClaims, err := jw.apiKeys.ParseApiKey(r.Context(), apiKey)
+		if err != nil {
+			return nil, "", err
+		}
+		return authClaims, "", nil
+	}
+
 	header := r.Header.Get("Authorization")
 	if len(header) == 0 {
 		jw.log.Error("no security header")
@@ -56,7 +70,7 @@ func (jw *JWTVerify) parseTokenFromRequest(r *http.Request) (*domain.AuthClaims,
 		return nil, "", errors.New("no security header token")
 	}
 
-	authClaims, err := jw.service.ParseOauthToken(tokenString[1])
+	authClaims, err := jw.service.ParseOauthToken(r.Context(), tokenString[1])
 	if err != nil {
 		return nil, "", err
 	}
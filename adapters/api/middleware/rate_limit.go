@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	serverMiddleware "github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/api/dto"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	rateLimitKeyPrefix = "ratelimit:"
+	// memoryPruneInterval removes the in memory clients without pending requests
+	memoryPruneInterval = time.Minute
+)
+
+// gcraScript GCRA rate limit, the key stores the theoretical arrival time of the next request.
+// KEYS[1] the client key, ARGV[1] the burst, ARGV[2] the emission interval in seconds.
+// Returns the allowed flag, the remaining requests, the seconds to retry and the seconds to reset.
+const gcraScript = `
+redis.replicate_commands()
+local burst = tonumber(ARGV[1])
+local emission = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+local newTat = tat + emission
+local diff = now - (newTat - emission * burst)
+if diff < 0 then
+	return {0, 0, tostring(-diff), tostring(tat - now)}
+end
+redis.call("SET", KEYS[1], tostring(newTat), "EX", math.ceil(newTat - now))
+return {1, math.floor(diff / emission), "0", tostring(newTat - now)}
+`
+
+// RateLimitRule limit of requests by period of a route pattern, an empty method applies to every method
+type RateLimitRule struct {
+	Method   string
+	Pattern  string
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// rateLimitResult result of a rate limit check
+type rateLimitResult struct {
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration
+	resetAfter time.Duration
+}
+
+// RateLimiter rate limit of the clients by route, the limits are shared by the replicas in Redis
+type RateLimiter struct {
+	log         *zap.SugaredLogger
+	cache       ports.IRedis
+	enabled     bool
+	defaultRule RateLimitRule
+	rules       map[string]RateLimitRule
+	memory      *memoryLimiter
+	redisDown   atomic.Bool
+}
+
+// NewRateLimiter create the rate limiter, the rules without requests do not limit the route
+func NewRateLimiter(log *zap.SugaredLogger, cache ports.IRedis, enabled bool, defaultRule RateLimitRule, rules []RateLimitRule) *RateLimiter {
+	limiter := &RateLimiter{
+		log:         log,
+		cache:       cache,
+		enabled:     enabled,
+		defaultRule: defaultRule,
+		rules:       map[string]RateLimitRule{},
+		memory:      newMemoryLimiter(),
+	}
+	for _, rule := range rules {
+		limiter.rules[strings.ToUpper(rule.Method)+" "+rule.Pattern] = rule
+	}
+	return limiter
+}
+
+// Handler limit the requests of the client, the authenticated clients are limited by username or api key and the others by ip,
+// so it must be used after the JWT verify handler of the authenticated routes
+func (rl *RateLimiter) Handler() func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !rl.enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := chi.RouteContext(r.Context()).RoutePattern()
+			rule := rl.rule(r.Method, pattern)
+			if rule.Requests <= 0 || rule.Period <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := rateLimitKeyPrefix + r.Method + ":" + pattern + ":" + clientKey(r)
+			result := rl.allow(r.Context(), key, rule)
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(rule.Requests))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.resetAfter)))
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", rule.Requests, ceilSeconds(rule.Period), burst(rule)))
+			if !result.allowed {
+				traceID, _ := r.Context().Value(serverMiddleware.RequestIDKey).(string)
+				rl.log.With("traceId", traceID).Warnf("Rate limit exceeded for %s", key)
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.retryAfter)))
+				dto.RenderErrorResponse(r.Context(), w, http.StatusTooManyRequests, custom_error.New(http.StatusTooManyRequests, "too many requests"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (rl *RateLimiter) rule(method, pattern string) RateLimitRule {
+	if rule, ok := rl.rules[method+" "+pattern]; ok {
+		return rule
+	}
+	if rule, ok := rl.rules[" "+pattern]; ok {
+		return rule
+	}
+	return rl.defaultRule
+}
+
+// allow check the limit in Redis, or in memory while Redis is down
+func (rl *RateLimiter) allow(ctx context.Context, key string, rule RateLimitRule) rateLimitResult {
+	emission := rule.Period / time.Duration(rule.Requests)
+	values, err := rl.cache.Eval(ctx, gcraScript, []string{key}, burst(rule), emission.Seconds()).Slice()
+	if err == nil {
+		var result rateLimitResult
+		if result, err = parseGCRAResult(values); err == nil {
+			if rl.redisDown.CompareAndSwap(true, false) {
+				rl.log.Infof("Redis is available again, the rate limits are shared by the replicas")
+			}
+			return result
+		}
+	}
+
+	if rl.redisDown.CompareAndSwap(false, true) {
+		rl.log.Errorf("Redis rate limit failed, limiting in memory: %v", err)
+	}
+	return rl.memory.allow(key, burst(rule), emission, time.Now())
+}
+
+func parseGCRAResult(values []interface{}) (rateLimitResult, error) {
+	if len(values) != 4 {
+		return rateLimitResult{}, fmt.Errorf("unexpected rate limit result %v", values)
+	}
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(int64)
+	retryAfter, err := durationFromSeconds(values[2])
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+	resetAfter, err := durationFromSeconds(values[3])
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+	return rateLimitResult{allowed: allowed == 1, remaining: int(remaining), retryAfter: retryAfter, resetAfter: resetAfter}, nil
+}
+
+func durationFromSeconds(value interface{}) (time.Duration, error) {
+	text, _ := value.(string)
+	seconds, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// clientKey identify the client by api key, tenant and username, or ip for the anonymous requests
+func clientKey(r *http.Request) string {
+	if claims, ok := r.Context().Value(domain.ClaimsKey).(domain.AuthClaims); ok {
+		if strings.HasPrefix(claims.Subject, domain.ApiKeySubjectPrefix) {
+			return claims.Subject
+		}
+		return "user:" + claims.TenantID + ":" + claims.Username
+	}
+
+	// RemoteAddr is the result of the RealIP middleware, or the connection address with its port
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func burst(rule RateLimitRule) int {
+	if rule.Burst <= 0 {
+		return rule.Requests
+	}
+	return rule.Burst
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
+
+// memoryLimiter GCRA rate limit of a single replica, used while Redis is down
+type memoryLimiter struct {
+	lock      sync.Mutex
+	tats      map[string]time.Time
+	lastPrune time.Time
+}
+
+func newMemoryLimiter() *memoryLimiter {
+	return &memoryLimiter{
+		tats:      map[string]time.Time{},
+		lastPrune: time.Now(),
+	}
+}
+
+func (ml *memoryLimiter) allow(key string, burst int, emission time.Duration, now time.Time) rateLimitResult {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+
+	if now.Sub(ml.lastPrune) > memoryPruneInterval {
+		for client, tat := range ml.tats {
+			if tat.Before(now) {
+				delete(ml.tats, client)
+			}
+		}
+		ml.lastPrune = now
+	}
+
+	tat, ok := ml.tats[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(emission)
+	diff := now.Sub(newTat.Add(-emission * time.Duration(burst)))
+	if diff < 0 {
+		return rateLimitResult{retryAfter: -diff, resetAfter: tat.Sub(now)}
+	}
+
+	ml.tats[key] = newTat
+	return rateLimitResult{allowed: true, remaining: int(diff / emission), resetAfter: newTat.Sub(now)}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/cache"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRateLimitWithRedis for test the rate limit headers and the 429 response with the Redis result
+func TestRateLimitWithRedis(t *testing.T) {
+	redisCache := &cache.RedisCacheMock{}
+	var evalKeys []string
+	redisCache.EvalFunc = func(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+		evalKeys = keys
+		cmd := redis.NewCmd(ctx)
+		cmd.SetVal([]interface{}{int64(0), int64(0), "1.5", "12"})
+		return cmd
+	}
+	limiter := NewRateLimiter(zap.NewNop().Sugar(), redisCache, true, RateLimitRule{},
+		[]RateLimitRule{{Method: http.MethodPost, Pattern: "/v1/sts/token", Requests: 10, Period: time.Minute, Burst: 5}})
+
+	response := serve(limiter, http.MethodPost, "/v1/sts/token", "10.0.0.1:5000")
+
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, []string{"ratelimit:POST:/v1/sts/token:ip:10.0.0.1"}, evalKeys)
+	assert.Equal(t, "10", response.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", response.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "12", response.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2", response.Header().Get("Retry-After"))
+
+	body := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Equal(t, "Too Many Requests", body["code"])
+}
+
+// TestRateLimitInMemoryWhenRedisIsDown for test the in memory fallback
+func TestRateLimitInMemoryWhenRedisIsDown(t *testing.T) {
+	redisCache := &cache.RedisCacheMock{}
+	redisCache.EvalFunc = func(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+		cmd := redis.NewCmd(ctx)
+		cmd.SetErr(errors.New("connection refused"))
+		return cmd
+	}
+	limiter := NewRateLimiter(zap.NewNop().Sugar(), redisCache, true,
+		RateLimitRule{Requests: 60, Period: time.Minute, Burst: 2}, nil)
+
+	assert.Equal(t, http.StatusOK, serve(limiter, http.MethodGet, "/v1/product", "10.0.0.1:5000").Code)
+	assert.Equal(t, http.StatusOK, serve(limiter, http.MethodGet, "/v1/product", "10.0.0.1:5001").Code)
+	response := serve(limiter, http.MethodGet, "/v1/product", "10.0.0.1:5002")
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "1", response.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serve(limiter, http.MethodGet, "/v1/product", "10.0.0.2:5000").Code)
+}
+
+// TestMemoryLimiter for test the GCRA replenishment
+func TestMemoryLimiter(t *testing.T) {
+	limiter := newMemoryLimiter()
+	now := time.Now()
+
+	assert.Equal(t, rateLimitResult{allowed: true, remaining: 1, resetAfter: time.Second}, limiter.allow("client", 2, time.Second, now))
+	assert.Equal(t, rateLimitResult{allowed: true, remaining: 0, resetAfter: 2 * time.Second}, limiter.allow("client", 2, time.Second, now))
+	assert.Equal(t, rateLimitResult{retryAfter: time.Second, resetAfter: 2 * time.Second}, limiter.allow("client", 2, time.Second, now))
+	assert.True(t, limiter.allow("client", 2, time.Second, now.Add(time.Second)).allowed)
+}
+
+func serve(limiter *RateLimiter, method, path, remoteAddr string) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.With(limiter.Handler()).MethodFunc(method, path, func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	})
+
+	request := httptest.NewRequest(method, path, nil)
+	request.RemoteAddr = remoteAddr
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return response
+}
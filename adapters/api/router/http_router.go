@@ -10,15 +10,18 @@ import (
 
 // HTTPRouter http routers
 type HTTPRouter struct {
-	Router *chi.Mux
+	Router      *chi.Mux
+	RateLimiter *middleware3.RateLimiter
 }
 
 // NewHTTPRouter create new http router
-func NewHTTPRouter(prometheusMetricRegistry *middleware3.CustomMetricRegistry) *HTTPRouter {
+func NewHTTPRouter(prometheusMetricRegistry *middleware3.CustomMetricRegistry, rateLimiter *middleware3.RateLimiter) *HTTPRouter {
 	router := chi.NewRouter()
 
 	// Adding some middlewares ready
-	router.Use(middleware.AllowContentType("application/json"))
+	// The CSV and NDJSON content types are only used by the product import, the patch content types by the product patch
+	router.Use(middleware.AllowContentType("application/json", "text/csv", "application/x-ndjson",
+		"application/merge-patch+json", "application/json-patch+json"))
 	// Enable Elastic APM chiv5 Middleware
 	router.Use(apmchiv5.Middleware())
 	// Timeout is a middleware that cancels ctx after a given timeout and return http status error 504.
@@ -33,6 +36,7 @@ func NewHTTPRouter(prometheusMetricRegistry *middleware3.CustomMetricRegistry) *
 	router.Use(middleware3.NewHttpHandlerMiddleware(prometheusMetricRegistry))
 
 	return &HTTPRouter{
-		Router: router,
+		Router:      router,
+		RateLimiter: rateLimiter,
 	}
 }
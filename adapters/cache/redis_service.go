@@ -3,12 +3,33 @@ package cache
 import (
 	"context"
 	"github.com/go-redis/redis/v8"
+	"strconv"
 	"time"
 )
 
 // KeyCacheDuration expiration time for a key in cache
 const KeyCacheDuration = time.Hour * 1
 
+// ProductKey cache key of the product, prefixed by the tenant
+func ProductKey(tenantID, productID string) string {
+	return tenantID + ":product:" + productID
+}
+
+// ImportJobKey cache key of the product import job, prefixed by the tenant
+func ImportJobKey(tenantID, jobID string) string {
+	return tenantID + ":import:" + jobID
+}
+
+// ExportJobKey cache key of the product export job, prefixed by the tenant
+func ExportJobKey(tenantID, jobID string) string {
+	return tenantID + ":export:" + jobID
+}
+
+// ExportFileKey cache key of a chunk of the file of the product export job, prefixed by the tenant
+func ExportFileKey(tenantID, jobID string, chunk int) string {
+	return tenantID + ":export:" + jobID + ":file:" + strconv.Itoa(chunk)
+}
+
 // RedisCache redis cache connection
 type RedisCache struct {
 	Client *redis.Client
@@ -23,3 +44,33 @@ func (r *RedisCache) Set(ctx context.Context, key string, value interface{}, exp
 func (r *RedisCache) Get(ctx context.Context, key string) *redis.StringCmd {
 	return r.Client.Get(ctx, key)
 }
+
+// MGet returns the values of the keys, nil for the missing keys
+func (r *RedisCache) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	return r.Client.MGet(ctx, keys...)
+}
+
+// SetNX put a new key value pair in cache only when the key does not exist
+func (r *RedisCache) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	return r.Client.SetNX(ctx, key, value, expiration)
+}
+
+// Del removes the keys
+func (r *RedisCache) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	return r.Client.Del(ctx, keys...)
+}
+
+// Exists returns how many of the keys exist
+func (r *RedisCache) Exists(ctx context.Context, keys ...string) *redis.IntCmd {
+	return r.Client.Exists(ctx, keys...)
+}
+
+// Pipelined executes the commands queued by fn in a single round trip
+func (r *RedisCache) Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	return r.Client.Pipelined(ctx, fn)
+}
+
+// Eval executes the lua script atomically
+func (r *RedisCache) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	return r.Client.Eval(ctx, script, keys, args...)
+}
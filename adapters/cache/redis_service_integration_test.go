@@ -0,0 +1,69 @@
+//go:build integration
+
+package cache_test
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/test/integration"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(integration.Run(m))
+}
+
+// TestRedisCacheKeys for test Set, Get, MGet, SetNX, Exists and Del
+func TestRedisCacheKeys(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	redisCache := integration.Redis(t)
+
+	assert.Nil(t, redisCache.Set(ctx, "tenant-a:product:1", "shoes", time.Minute).Err())
+	value, err := redisCache.Get(ctx, "tenant-a:product:1").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, "shoes", value)
+	_, err = redisCache.Get(ctx, "tenant-a:product:2").Result()
+	assert.Equal(t, redis.Nil, err)
+
+	values, err := redisCache.MGet(ctx, "tenant-a:product:1", "tenant-a:product:2").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"shoes", nil}, values)
+
+	created, err := redisCache.SetNX(ctx, "tenant-a:product:1", "boots", time.Minute).Result()
+	assert.Nil(t, err)
+	assert.False(t, created)
+
+	count, err := redisCache.Exists(ctx, "tenant-a:product:1", "tenant-a:product:2").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	assert.Nil(t, redisCache.Del(ctx, "tenant-a:product:1").Err())
+	count, err = redisCache.Exists(ctx, "tenant-a:product:1").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+// TestRedisCachePipelineAndScript for test Pipelined and Eval
+func TestRedisCachePipelineAndScript(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	redisCache := integration.Redis(t)
+
+	_, err := redisCache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "tenant-a:product:1", "shoes", time.Minute)
+		pipe.Set(ctx, "tenant-a:product:2", "boots", time.Minute)
+		return nil
+	})
+	assert.Nil(t, err)
+	values, err := redisCache.MGet(ctx, "tenant-a:product:1", "tenant-a:product:2").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"shoes", "boots"}, values)
+
+	result, err := redisCache.Eval(ctx, "return redis.call('INCRBY', KEYS[1], ARGV[1])", []string{"counter"}, 5).Int64()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), result)
+}
@@ -7,19 +7,53 @@ import (
 )
 
 // RedisCacheMock redis cache mock
-type RedisCacheMock struct{}
-
-var (
-	SetFunc func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
-	GetFunc func(ctx context.Context, key string) *redis.StringCmd
-)
+type RedisCacheMock struct {
+	SetFunc       func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	GetFunc       func(ctx context.Context, key string) *redis.StringCmd
+	MGetFunc      func(ctx context.Context, keys ...string) *redis.SliceCmd
+	SetNXFunc     func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	DelFunc       func(ctx context.Context, keys ...string) *redis.IntCmd
+	ExistsFunc    func(ctx context.Context, keys ...string) *redis.IntCmd
+	PipelinedFunc func(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+	EvalFunc      func(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+}
 
 // Set is the cache mock for Set func
 func (rc *RedisCacheMock) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
-	return SetFunc(ctx, key, value, expiration)
+	return rc.SetFunc(ctx, key, value, expiration)
 }
 
 // Get is the cache mock for Get func
 func (rc *RedisCacheMock) Get(ctx context.Context, key string) *redis.StringCmd {
-	return GetFunc(ctx, key)
+	return rc.GetFunc(ctx, key)
+}
+
+// MGet is the cache mock for MGet func
+func (rc *RedisCacheMock) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	return rc.MGetFunc(ctx, keys...)
+}
+
+// SetNX is the cache mock for SetNX func
+func (rc *RedisCacheMock) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	return rc.SetNXFunc(ctx, key, value, expiration)
+}
+
+// Del is the cache mock for Del func
+func (rc *RedisCacheMock) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	return rc.DelFunc(ctx, keys...)
+}
+
+// Exists is the cache mock for Exists func
+func (rc *RedisCacheMock) Exists(ctx context.Context, keys ...string) *redis.IntCmd {
+	return rc.ExistsFunc(ctx, keys...)
+}
+
+// Pipelined is the cache mock for Pipelined func
+func (rc *RedisCacheMock) Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	return rc.PipelinedFunc(ctx, fn)
+}
+
+// Eval is the cache mock for Eval func
+func (rc *RedisCacheMock) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	return rc.EvalFunc(ctx, script, keys, args...)
 }
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"time"
+)
+
+// CacheCommand command line adapter for the cache operations
+type CacheCommand struct {
+	log     *zap.SugaredLogger
+	service ports.ICacheWarmService
+}
+
+// NewCacheCommand create new cache command
+func NewCacheCommand(log *zap.SugaredLogger, service ports.ICacheWarmService) *CacheCommand {
+	return &CacheCommand{
+		log:     log,
+		service: service,
+	}
+}
+
+// Run execute the cache sub command. Usage: cache warm [-tenant=default] [-status=available] [-created-since=2024-01-02T15:04:05Z] [-page-size=500] [-rate=0]
+func (cc *CacheCommand) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "warm" {
+		return errors.New("usage: cache warm [flags]")
+	}
+
+	flags := flag.NewFlagSet("cache warm", flag.ContinueOnError)
+	tenant := flags.String("tenant", domain.DefaultTenantID, "tenant of the products")
+	status := flags.String("status", "", "only products with this status (available, pending or inactive)")
+	createdSince := flags.String("created-since", "", "only products created since this RFC3339 date")
+	pageSize := flags.Int("page-size", domain.DefaultCacheWarmPageSize, "products loaded per page")
+	rate := flags.Int("rate", 0, "max products cached per second, 0 is unlimited")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	request := &domain.CacheWarmRequest{
+		TenantID:      *tenant,
+		Filter:        domain.ProductFilter{Status: *status},
+		PageSize:      *pageSize,
+		RatePerSecond: *rate,
+	}
+	if *createdSince != "" {
+		since, err := time.Parse(time.RFC3339, *createdSince)
+		if err != nil {
+			return fmt.Errorf("invalid created-since date: %w", err)
+		}
+		request.Filter.CreatedSince = since
+	}
+
+	result, err := cc.service.Warm(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	cc.log.Infof("Cache warm up done. Loaded: %d, cached: %d, failed: %d, pages: %d, duration: %v",
+		result.Loaded, result.Cached, result.Failed, result.Pages, result.Duration)
+	return nil
+}
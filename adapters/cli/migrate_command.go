@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+)
+
+// MigrateCommand command line adapter for the database migrations
+type MigrateCommand struct {
+	log      *zap.SugaredLogger
+	migrator ports.IMigrator
+}
+
+// NewMigrateCommand create new migrate command
+func NewMigrateCommand(log *zap.SugaredLogger, migrator ports.IMigrator) *MigrateCommand {
+	return &MigrateCommand{
+		log:      log,
+		migrator: migrator,
+	}
+}
+
+// Run execute the migrate sub command. Usage: migrate up | migrate down [-steps=1] | migrate status
+func (mc *MigrateCommand) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up | down [-steps=1] | status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := mc.migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		mc.log.Infof("Database migrated. Applied migrations: %d", len(applied))
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of applied migrations to undo")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		reverted, err := mc.migrator.Down(ctx, *steps)
+		if err != nil {
+			return err
+		}
+		mc.log.Infof("Database reverted. Reverted migrations: %d", len(reverted))
+	case "status":
+		statuses, err := mc.migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			mc.logStatus(status)
+		}
+	default:
+		return errors.New("usage: migrate up | down [-steps=1] | status")
+	}
+	return nil
+}
+
+// logStatus log the state of the migration, the installed date only for the installed migrations
+func (mc *MigrateCommand) logStatus(status domain.MigrationStatus) {
+	if status.InstalledOn.IsZero() {
+		mc.log.Infof("%-8s %-40s %-8s undo: %v", status.Version, status.Description, status.State, status.Undoable)
+		return
+	}
+	mc.log.Infof("%-8s %-40s %-8s undo: %v installed on: %s", status.Version, status.Description, status.State,
+		status.Undoable, status.InstalledOn.Format("2006-01-02 15:04:05"))
+}
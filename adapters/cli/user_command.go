@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"io"
+	"strings"
+)
+
+// UserCommand command line adapter for the user operations
+type UserCommand struct {
+	log      *zap.SugaredLogger
+	validate *validator.Validate
+	service  ports.IUserService
+	stdin    io.Reader
+}
+
+// NewUserCommand create new user command
+func NewUserCommand(log *zap.SugaredLogger, validator *validator.Validate, service ports.IUserService, stdin io.Reader) *UserCommand {
+	_ = validator.RegisterValidation("not_blank", validators.NotBlank)
+	return &UserCommand{
+		log:      log,
+		validate: validator,
+		service:  service,
+		stdin:    stdin,
+	}
+}
+
+// Run execute the user sub command. Usage:
+//
+//	user create -username=john -roles=user,business [-tenant=default] [-password=secret]
+//	user roles -username=john -roles=admin
+//
+// When the password flag is empty the password is read from the standard input.
+func (uc *UserCommand) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: user create|roles [flags]")
+	}
+
+	switch args[0] {
+	case "create":
+		return uc.createUser(ctx, args[1:])
+	case "roles":
+		return uc.assignRoles(ctx, args[1:])
+	default:
+		return errors.New("usage: user create|roles [flags]")
+	}
+}
+
+func (uc *UserCommand) createUser(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+	username := flags.String("username", "", "user name")
+	password := flags.String("password", "", "user password, read from the standard input when empty")
+	roles := flags.String("roles", "", "comma separated roles: admin, business or user")
+	tenant := flags.String("tenant", domain.DefaultTenantID, "tenant of the user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *password == "" {
+		line, err := bufio.NewReader(uc.stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+
+	user := &domain.User{
+		Username: *username,
+		Password: *password,
+		Roles:    splitRoles(*roles),
+		TenantID: *tenant,
+	}
+	if err := uc.validate.Struct(user); err != nil {
+		return err
+	}
+
+	userModel, err := uc.service.CreateUser(ctx, user, "cli")
+	if err != nil {
+		return err
+	}
+
+	uc.log.Infof("User %s created with id %s, roles %v and tenant %s", userModel.Username, userModel.ID, userModel.Roles, userModel.TenantID)
+	return nil
+}
+
+func (uc *UserCommand) assignRoles(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("user roles", flag.ContinueOnError)
+	username := flags.String("username", "", "user name")
+	roles := flags.String("roles", "", "comma separated roles: admin, business or user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	userRoles := &domain.UserRoles{
+		Username: *username,
+		Roles:    splitRoles(*roles),
+	}
+	if err := uc.validate.Struct(userRoles); err != nil {
+		return err
+	}
+
+	return uc.service.AssignRoles(ctx, userRoles, "cli")
+}
+
+// splitRoles split the comma separated roles ignoring the empty values
+func splitRoles(roles string) []string {
+	result := []string{}
+	for _, role := range strings.Split(roles, ",") {
+		role = strings.TrimSpace(role)
+		if role != "" {
+			result = append(result, role)
+		}
+	}
+	return result
+}
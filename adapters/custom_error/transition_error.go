@@ -0,0 +1,29 @@
+package custom_error
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// TransitionError is the conflict error of an invalid status transition, with the statuses allowed from the current status
+type TransitionError struct {
+	*StatusError
+	From    string
+	To      string
+	Allowed []string
+}
+
+// NewTransitionError returns the conflict error of the transition from the current status to the requested status
+func NewTransitionError(from, to string, allowed []string) error {
+	return &TransitionError{
+		StatusError: &StatusError{http.StatusConflict, fmt.Sprintf("invalid status transition from %s to %s", from, to)},
+		From:        from,
+		To:          to,
+		Allowed:     allowed,
+	}
+}
+
+// Unwrap returns the status error, so the transition error is rendered with its status code
+func (e *TransitionError) Unwrap() error {
+	return e.StatusError
+}
@@ -0,0 +1,161 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"golang-api-hexagonal/core/domain"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// utf8BOM byte order mark so the spreadsheets read the CSV as UTF-8
+const utf8BOM = "\xEF\xBB\xBF"
+
+// formulaPrefixes first characters of the cells that the spreadsheets evaluate as formulas
+const formulaPrefixes = "=+-@\t\r"
+
+// ContentTypes content type of each export format
+var ContentTypes = map[string]string{
+	domain.ExportCSV:    "text/csv; charset=utf-8",
+	domain.ExportNDJSON: "application/x-ndjson",
+	domain.ExportJSON:   "application/json",
+}
+
+// FileExtensions file extension of each export format
+var FileExtensions = map[string]string{
+	domain.ExportCSV:    ".csv",
+	domain.ExportNDJSON: ".ndjson",
+	domain.ExportJSON:   ".json",
+}
+
+// ProductWriter writer of the selected columns of the products in the export format, the products are written one by one
+// so the export is never loaded in memory
+type ProductWriter struct {
+	buffer  *bufio.Writer
+	csv     *csv.Writer
+	query   domain.ProductExportQuery
+	written int
+}
+
+// NewProductWriter create new product writer of the query format and columns
+func NewProductWriter(writer io.Writer, query domain.ProductExportQuery) *ProductWriter {
+	buffer := bufio.NewWriter(writer)
+	productWriter := &ProductWriter{buffer: buffer, query: query}
+	if query.Format == domain.ExportCSV {
+		productWriter.csv = csv.NewWriter(buffer)
+		productWriter.csv.UseCRLF = query.Excel
+	}
+	return productWriter
+}
+
+// Write the product, the first call writes the CSV header or opens the JSON array
+func (pw *ProductWriter) Write(product *domain.ProductModel) error {
+	if pw.written == 0 {
+		if err := pw.open(); err != nil {
+			return err
+		}
+	}
+	pw.written++
+
+	switch pw.query.Format {
+	case domain.ExportCSV:
+		record := make([]string, 0, len(pw.query.Columns))
+		for _, column := range pw.query.Columns {
+			record = append(record, pw.cell(domain.ProductExportValue(product, column)))
+		}
+		return pw.csv.Write(record)
+	case domain.ExportJSON:
+		if pw.written > 1 {
+			if _, err := pw.buffer.WriteString(","); err != nil {
+				return err
+			}
+		}
+		return pw.writeObject(product)
+	default:
+		if err := pw.writeObject(product); err != nil {
+			return err
+		}
+		_, err := pw.buffer.WriteString("\n")
+		return err
+	}
+}
+
+// Close write the end of the export and flush it
+func (pw *ProductWriter) Close() error {
+	if pw.written == 0 {
+		if err := pw.open(); err != nil {
+			return err
+		}
+	}
+	if pw.csv != nil {
+		pw.csv.Flush()
+		if err := pw.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if pw.query.Format == domain.ExportJSON {
+		if _, err := pw.buffer.WriteString("]"); err != nil {
+			return err
+		}
+	}
+	return pw.buffer.Flush()
+}
+
+// open write the start of the export
+func (pw *ProductWriter) open() error {
+	switch pw.query.Format {
+	case domain.ExportCSV:
+		if pw.query.Excel {
+			if _, err := pw.buffer.WriteString(utf8BOM); err != nil {
+				return err
+			}
+		}
+		return pw.csv.Write(pw.query.Columns)
+	case domain.ExportJSON:
+		_, err := pw.buffer.WriteString("[")
+		return err
+	default:
+		return nil
+	}
+}
+
+// writeObject write the json object of the product with the columns in the selected order
+func (pw *ProductWriter) writeObject(product *domain.ProductModel) error {
+	object := make([]byte, 0, 256)
+	object = append(object, '{')
+	for index, column := range pw.query.Columns {
+		if index > 0 {
+			object = append(object, ',')
+		}
+		value, err := json.Marshal(domain.ProductExportValue(product, column))
+		if err != nil {
+			return err
+		}
+		object = strconv.AppendQuote(object, column)
+		object = append(object, ':')
+		object = append(object, value...)
+	}
+	object = append(object, '}')
+	_, err := pw.buffer.Write(object)
+	return err
+}
+
+// cell CSV text of the value, the formulas are escaped for the spreadsheets
+func (pw *ProductWriter) cell(value interface{}) string {
+	var text string
+	switch typed := value.(type) {
+	case string:
+		text = typed
+	case int64:
+		return strconv.FormatInt(typed, 10)
+	case time.Time:
+		return typed.Format(time.RFC3339Nano)
+	}
+	if pw.query.Excel && text != "" && strings.ContainsRune(formulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
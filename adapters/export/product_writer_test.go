@@ -0,0 +1,53 @@
+package export
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/core/domain"
+	"testing"
+	"time"
+)
+
+var exported = []*domain.ProductModel{
+	{ID: "1", Name: "=SUM(A1)", Version: 2, CreationDate: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	{ID: "2", Name: "shoe, red", Version: 1, CreationDate: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+}
+
+// TestProductWriterFormats for test the selected columns in each format
+func TestProductWriterFormats(t *testing.T) {
+	columns := []string{"id", "name", "version", "creationDate"}
+	tests := []struct {
+		query    domain.ProductExportQuery
+		expected string
+	}{
+		{domain.ProductExportQuery{Format: domain.ExportCSV, Columns: columns},
+			"id,name,version,creationDate\n1,=SUM(A1),2,2024-01-02T03:04:05Z\n2,\"shoe, red\",1,2024-01-03T00:00:00Z\n"},
+		{domain.ProductExportQuery{Format: domain.ExportCSV, Columns: columns[:2], Excel: true},
+			utf8BOM + "id,name\r\n1,'=SUM(A1)\r\n2,\"shoe, red\"\r\n"},
+		{domain.ProductExportQuery{Format: domain.ExportNDJSON, Columns: columns[:3]},
+			"{\"id\":\"1\",\"name\":\"=SUM(A1)\",\"version\":2}\n{\"id\":\"2\",\"name\":\"shoe, red\",\"version\":1}\n"},
+		{domain.ProductExportQuery{Format: domain.ExportJSON, Columns: []string{"creationDate", "id"}},
+			"[{\"creationDate\":\"2024-01-02T03:04:05Z\",\"id\":\"1\"},{\"creationDate\":\"2024-01-03T00:00:00Z\",\"id\":\"2\"}]"},
+	}
+
+	for _, test := range tests {
+		output := &bytes.Buffer{}
+		writer := NewProductWriter(output, test.query)
+		for _, product := range exported {
+			assert.NoError(t, writer.Write(product))
+		}
+		assert.NoError(t, writer.Close())
+		assert.Equal(t, test.expected, output.String())
+	}
+}
+
+// TestProductWriterWithoutProducts for test the empty exports
+func TestProductWriterWithoutProducts(t *testing.T) {
+	output := &bytes.Buffer{}
+	assert.NoError(t, NewProductWriter(output, domain.ProductExportQuery{Format: domain.ExportJSON, Columns: []string{"id"}}).Close())
+	assert.Equal(t, "[]", output.String())
+
+	output.Reset()
+	assert.NoError(t, NewProductWriter(output, domain.ProductExportQuery{Format: domain.ExportCSV, Columns: []string{"id"}}).Close())
+	assert.Equal(t, "id\n", output.String())
+}
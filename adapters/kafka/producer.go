@@ -3,6 +3,7 @@ package kafka
 import (
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"go.uber.org/zap"
+	"golang-api-hexagonal/core/domain"
 	"time"
 )
 
@@ -33,8 +34,8 @@ func (mp *MessageProducer) Close() {
 	mp.producer.Close()
 }
 
-// ProduceMessage produce the message in the kafka topic
-func (mp *MessageProducer) ProduceMessage(topicName, value, eventName, traceID string) {
+// ProduceMessage produce the message in the kafka topic with the event name and tenant headers
+func (mp *MessageProducer) ProduceMessage(topicName, value, eventName, tenantID, traceID string) {
 	deliveryChan := make(chan kafka.Event, 10000)
 
 	err := mp.producer.Produce(&kafka.Message{
@@ -42,7 +43,7 @@ func (mp *MessageProducer) ProduceMessage(topicName, value, eventName, traceID s
 		Value:          []byte(value),
 		Key:            []byte(traceID),
 		Timestamp:      time.Now().UTC(),
-		Headers:        []kafka.Header{{Key: eventNameKey, Value: []byte(eventName)}},
+		Headers:        []kafka.Header{{Key: eventNameKey, Value: []byte(eventName)}, {Key: domain.TenantHeader, Value: []byte(tenantID)}},
 	}, deliveryChan)
 	if err != nil {
 		mp.log.With("traceId", traceID).Errorf("Internal error to send the kafka message in topic: %v, with error: %v", topicName, err)
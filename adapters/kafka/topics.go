@@ -15,13 +15,19 @@ func CreateKafkaTopics(log *zap.SugaredLogger, config config.KafkaConfiguration,
 	}
 
 	// Create the topics with 3 partitions and replication for 1 broker, and set 60 seconds of timeout. If you have more brokers in your cluster, you can set more than 1.
-	results, err := adminClient.CreateTopics(
-		ctx, []kafka.TopicSpecification{{
-			Topic:             config.Consumer.Topics[0],
+	topics := []kafka.TopicSpecification{{
+		Topic:             config.Consumer.Topics[0],
+		NumPartitions:     3,
+		ReplicationFactor: 1,
+	}}
+	if config.Producer.AuditTopic != "" {
+		topics = append(topics, kafka.TopicSpecification{
+			Topic:             config.Producer.AuditTopic,
 			NumPartitions:     3,
 			ReplicationFactor: 1,
-		}},
-		kafka.SetAdminOperationTimeout(60000))
+		})
+	}
+	results, err := adminClient.CreateTopics(ctx, topics, kafka.SetAdminOperationTimeout(60000))
 	if err != nil {
 		log.Panicf("Error to create the kafka topics: %s", err)
 	}
@@ -33,5 +39,7 @@ func CreateKafkaTopics(log *zap.SugaredLogger, config config.KafkaConfiguration,
 		}
 	}
 
-	log.Infof("Kafka topic created: %s", config.Consumer.Topics[0])
+	for _, topic := range topics {
+		log.Infof("Kafka topic created: %s", topic.Topic)
+	}
 }
@@ -0,0 +1,57 @@
+package oidc
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"net/http"
+	"time"
+)
+
+// IssuerVerifier verify the tokens with the verifier of the token issuer
+type IssuerVerifier struct {
+	log       *zap.SugaredLogger
+	verifiers map[string]ports.ITokenVerifier
+}
+
+// NewIssuerVerifier create the verifier for the tokens of this service and of the trusted OIDC issuers.
+// An invalid issuer configuration is an error, so the service does not start with it
+func NewIssuerVerifier(log *zap.SugaredLogger, local ports.ITokenVerifier, conf config.OIDCConfiguration) (*IssuerVerifier, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	verifiers := map[string]ports.ITokenVerifier{domain.Issuer: local}
+	for _, issuer := range conf.Issuers {
+		verifier, err := NewVerifier(log, issuer, client)
+		if err != nil {
+			return nil, err
+		}
+		verifiers[issuer.Issuer] = verifier
+		log.Infof("Trusted OIDC issuer: %s, audience: %s", issuer.Issuer, issuer.Audience)
+	}
+
+	return &IssuerVerifier{
+		log:       log,
+		verifiers: verifiers,
+	}, nil
+}
+
+// ParseOauthToken dispatch the token to the verifier of its issuer
+func (iv *IssuerVerifier) ParseOauthToken(ctx context.Context, tokenString string) (*domain.AuthClaims, error) {
+	claims := jwt.MapClaims{}
+	// The issuer is only read to choose the verifier, the chosen verifier validates the signature and the claims
+	_, _, err := jwt.NewParser().ParseUnverified(tokenString, claims)
+	if err != nil {
+		iv.log.Errorf("invalid token: %v", err)
+		return nil, domain.ErrInvalidToken
+	}
+
+	issuer, _ := claims.GetIssuer()
+	verifier, ok := iv.verifiers[issuer]
+	if !ok {
+		iv.log.Errorf("untrusted token issuer: %s", issuer)
+		return nil, domain.ErrInvalidTokenIssuer
+	}
+	return verifier.ParseOauthToken(ctx, tokenString)
+}
@@ -0,0 +1,247 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	discoveryPath            = "/.well-known/openid-configuration"
+	defaultClockSkew         = 60 * time.Second
+	defaultJWKSRefresh       = 15 * time.Minute
+	minUnknownKeyRefresh     = 10 * time.Second
+	defaultUsernameClaimPath = "preferred_username"
+)
+
+// asymmetricMethods signing methods accepted from the external issuers
+var asymmetricMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// discoveryDocument subset of the OIDC discovery document
+type discoveryDocument struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// Verifier verify the tokens of an external OIDC issuer with the keys published in its JWKS
+type Verifier struct {
+	log         *zap.SugaredLogger
+	conf        config.OIDCIssuerConfiguration
+	client      *http.Client
+	clockSkew   time.Duration
+	refresh     time.Duration
+	paths       domain.ClaimPaths
+	lock        sync.RWMutex
+	keys        map[string]interface{}
+	lastRefresh time.Time
+	// refreshLock serialize the refreshes, the keys stay readable while the JWKS is fetched
+	refreshLock sync.Mutex
+	jwksURI     string
+}
+
+// NewVerifier create a new verifier for the OIDC issuer. The discovery and keys are loaded on the first token.
+// The audience is required, otherwise the tokens issued for any other client of the issuer would be accepted
+func NewVerifier(log *zap.SugaredLogger, conf config.OIDCIssuerConfiguration, client *http.Client) (*Verifier, error) {
+	if conf.Issuer == "" {
+		return nil, errors.New("the oidc issuer is required")
+	}
+	if conf.Audience == "" {
+		return nil, fmt.Errorf("issuer %s: the audience is required", conf.Issuer)
+	}
+
+	clockSkew := time.Duration(conf.ClockSkewSeconds) * time.Second
+	if clockSkew <= 0 {
+		clockSkew = defaultClockSkew
+	}
+	refresh := time.Duration(conf.JWKSRefreshMinutes) * time.Minute
+	if refresh <= 0 {
+		refresh = defaultJWKSRefresh
+	}
+
+	paths := domain.ClaimPaths{
+		Username: valueOrDefault(conf.UsernameClaim, defaultUsernameClaimPath),
+		Roles:    valueOrDefault(conf.RolesClaim, domain.RolesClaim),
+		Scopes:   valueOrDefault(conf.ScopesClaim, domain.ScopeClaim),
+		Tenant:   valueOrDefault(conf.TenantClaim, domain.TenantClaim),
+	}
+
+	return &Verifier{
+		log:       log,
+		conf:      conf,
+		client:    client,
+		clockSkew: clockSkew,
+		refresh:   refresh,
+		paths:     paths,
+		keys:      map[string]interface{}{},
+	}, nil
+}
+
+// Issuer returns the trusted issuer
+func (v *Verifier) Issuer() string {
+	return v.conf.Issuer
+}
+
+// ParseOauthToken verify the token of the issuer and map its claims
+func (v *Verifier) ParseOauthToken(ctx context.Context, tokenString string) (*domain.AuthClaims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(asymmetricMethods),
+		jwt.WithIssuer(v.conf.Issuer),
+		jwt.WithLeeway(v.clockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(v.conf.Audience),
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		return v.getKey(ctx, keyID)
+	}, options...)
+	if err != nil || !token.Valid {
+		v.log.Errorf("invalid token of issuer %s: %v", v.conf.Issuer, err)
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, domain.ErrTokenExpired
+		}
+		return nil, domain.ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		v.log.Errorf("claims not found")
+		return nil, domain.ErrInvalidToken
+	}
+
+	authClaims, err := domain.NewAuthClaims(claims, v.paths)
+	if err != nil {
+		v.log.Errorf("invalid claims of issuer %s: %v", v.conf.Issuer, err)
+		return nil, err
+	}
+	return authClaims, nil
+}
+
+// getKey returns the key by id, refreshing the cached keys when they are stale or the key is unknown
+func (v *Verifier) getKey(ctx context.Context, keyID string) (interface{}, error) {
+	v.lock.RLock()
+	key, found := v.keys[keyID]
+	stale := time.Since(v.lastRefresh) > v.refresh
+	recent := time.Since(v.lastRefresh) < minUnknownKeyRefresh
+	v.lock.RUnlock()
+
+	if found && !stale {
+		return key, nil
+	}
+	if !found && recent {
+		return nil, fmt.Errorf("unknown signing key: %s", keyID)
+	}
+
+	if err := v.refreshKeys(ctx); err != nil {
+		v.log.Errorf("Failed to refresh the keys of issuer %s: %v", v.conf.Issuer, err)
+		if found {
+			// Keep using the cached key while the issuer is unavailable
+			return key, nil
+		}
+		return nil, err
+	}
+
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+	if key, found = v.keys[keyID]; !found {
+		return nil, fmt.Errorf("unknown signing key: %s", keyID)
+	}
+	return key, nil
+}
+
+// refreshKeys discover the JWKS uri and load the issuer public keys. The keys are fetched without holding v.lock
+// and swapped under it, so the tokens signed with the cached keys are still verified during the fetch
+func (v *Verifier) refreshKeys(ctx context.Context) error {
+	v.refreshLock.Lock()
+	defer v.refreshLock.Unlock()
+
+	v.lock.Lock()
+	// Another request refreshed the keys while this one was waiting
+	if time.Since(v.lastRefresh) < minUnknownKeyRefresh {
+		v.lock.Unlock()
+		return nil
+	}
+	v.lastRefresh = time.Now()
+	v.lock.Unlock()
+
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	v.lock.Lock()
+	v.keys = keys
+	v.lock.Unlock()
+	v.log.Infof("Loaded %d keys of issuer %s", len(keys), v.conf.Issuer)
+	return nil
+}
+
+// fetchKeys load the signing keys of the issuer JWKS, the JWKS uri is discovered on the first call
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]interface{}, error) {
+	if v.jwksURI == "" {
+		var discovery discoveryDocument
+		if err := v.getJSON(ctx, strings.TrimSuffix(v.conf.Issuer, "/")+discoveryPath, &discovery); err != nil {
+			return nil, err
+		}
+		if discovery.Issuer != v.conf.Issuer {
+			return nil, fmt.Errorf("discovery issuer %s does not match %s", discovery.Issuer, v.conf.Issuer)
+		}
+		if discovery.JWKSURI == "" {
+			return nil, errors.New("discovery without jwks_uri")
+		}
+		v.jwksURI = discovery.JWKSURI
+	}
+
+	var jwks domain.JSONWebKeySet
+	if err := v.getJSON(ctx, v.jwksURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := publicKeyFromJWK(jwk)
+		if err != nil {
+			v.log.Warnf("Ignoring the key %s of issuer %s: %v", jwk.Kid, v.conf.Issuer, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func (v *Verifier) getJSON(ctx context.Context, url string, target interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := v.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", response.StatusCode, url)
+	}
+	return json.NewDecoder(response.Body).Decode(target)
+}
+
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
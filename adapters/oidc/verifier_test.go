@@ -0,0 +1,250 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+var log = config.NewLogger()
+
+// fakeIssuer local OIDC issuer serving the discovery document and the JWKS
+type fakeIssuer struct {
+	server   *httptest.Server
+	lock     sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	jwksHits int
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	issuer := &fakeIssuer{keys: map[string]*rsa.PrivateKey{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(discoveryDocument{Issuer: issuer.server.URL, JWKSURI: issuer.server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		issuer.lock.Lock()
+		defer issuer.lock.Unlock()
+		issuer.jwksHits++
+		jwks := domain.JSONWebKeySet{}
+		for kid, key := range issuer.keys {
+			jwks.Keys = append(jwks.Keys, domain.JSONWebKey{
+				Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256",
+				N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(jwks)
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	issuer.addKey(t, "key-1")
+	return issuer
+}
+
+func (fi *fakeIssuer) addKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	fi.lock.Lock()
+	fi.keys[kid] = key
+	fi.lock.Unlock()
+}
+
+func (fi *fakeIssuer) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	fi.lock.Lock()
+	signed, err := token.SignedString(fi.keys[kid])
+	fi.lock.Unlock()
+	assert.Nil(t, err)
+	return signed
+}
+
+func (fi *fakeIssuer) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                fi.server.URL,
+		"aud":                "golang-api-hexagonal",
+		"sub":                "f3a2",
+		"preferred_username": "john",
+		"realm_access":       map[string]interface{}{"roles": []string{"business", "user"}},
+		"exp":                time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func (fi *fakeIssuer) config() config.OIDCIssuerConfiguration {
+	return config.OIDCIssuerConfiguration{
+		Issuer:           fi.server.URL,
+		Audience:         "golang-api-hexagonal",
+		ClockSkewSeconds: 30,
+		UsernameClaim:    "preferred_username",
+		RolesClaim:       "realm_access.roles",
+	}
+}
+
+// TestVerifierMapsTheClaims for test ParseOauthToken
+func TestVerifierMapsTheClaims(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	verifier, err := NewVerifier(log, issuer.config(), issuer.server.Client())
+	assert.Nil(t, err)
+
+	claims, err := verifier.ParseOauthToken(context.Background(), issuer.sign(t, "key-1", issuer.claims()))
+	assert.Nil(t, err)
+	assert.Equal(t, "john", claims.Username)
+	assert.Equal(t, []string{"business", "user"}, claims.Roles)
+}
+
+// TestVerifierRequiresTheAudience for test NewIssuerVerifier
+func TestVerifierRequiresTheAudience(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	conf := issuer.config()
+	conf.Audience = ""
+
+	_, err := NewIssuerVerifier(log, &localVerifier{}, config.OIDCConfiguration{
+		Issuers: []config.OIDCIssuerConfiguration{conf},
+	})
+	assert.Equal(t, "issuer "+issuer.server.URL+": the audience is required", err.Error())
+}
+
+// TestVerifierValidatesTheClaims for test ParseOauthToken
+func TestVerifierValidatesTheClaims(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	verifier, err := NewVerifier(log, issuer.config(), issuer.server.Client())
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name   string
+		change func(claims jwt.MapClaims)
+		valid  bool
+	}{
+		{"wrong audience", func(claims jwt.MapClaims) { claims["aud"] = "other-service" }, false},
+		{"wrong issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://other-issuer" }, false},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }, false},
+		{"expired inside the clock skew", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-10 * time.Second).Unix() }, true},
+		{"not before in the future", func(claims jwt.MapClaims) { claims["nbf"] = time.Now().Add(time.Minute).Unix() }, false},
+		{"not before inside the clock skew", func(claims jwt.MapClaims) { claims["nbf"] = time.Now().Add(10 * time.Second).Unix() }, true},
+		{"without expiration", func(claims jwt.MapClaims) { delete(claims, "exp") }, false},
+		{"username from subject", func(claims jwt.MapClaims) { delete(claims, "preferred_username") }, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := issuer.claims()
+			test.change(claims)
+			_, err := verifier.ParseOauthToken(context.Background(), issuer.sign(t, "key-1", claims))
+			assert.Equal(t, test.valid, err == nil, err)
+		})
+	}
+}
+
+// TestVerifierRefreshesTheKeysForUnknownKeyID for test ParseOauthToken
+func TestVerifierRefreshesTheKeysForUnknownKeyID(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	verifier, err := NewVerifier(log, issuer.config(), issuer.server.Client())
+	assert.Nil(t, err)
+
+	_, err = verifier.ParseOauthToken(context.Background(), issuer.sign(t, "key-1", issuer.claims()))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, issuer.jwksHits)
+
+	// A rotated key is only fetched again after the minimum refresh interval
+	issuer.addKey(t, "key-2")
+	_, err = verifier.ParseOauthToken(context.Background(), issuer.sign(t, "key-2", issuer.claims()))
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, issuer.jwksHits)
+
+	verifier.lastRefresh = time.Now().Add(-minUnknownKeyRefresh)
+	claims, err := verifier.ParseOauthToken(context.Background(), issuer.sign(t, "key-2", issuer.claims()))
+	assert.Nil(t, err)
+	assert.Equal(t, "john", claims.Username)
+	assert.Equal(t, 2, issuer.jwksHits)
+}
+
+// TestVerifierVerifiesWithTheCachedKeysDuringTheRefresh for test ParseOauthToken
+func TestVerifierVerifiesWithTheCachedKeysDuringTheRefresh(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	verifier, err := NewVerifier(log, issuer.config(), issuer.server.Client())
+	assert.Nil(t, err)
+	_, err = verifier.ParseOauthToken(context.Background(), issuer.sign(t, "key-1", issuer.claims()))
+	assert.Nil(t, err)
+
+	// The JWKS fetch of the unknown key blocks until the cached key verified a token
+	fetching := make(chan struct{})
+	release := make(chan struct{})
+	issuer.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(fetching)
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	issuer.addKey(t, "key-2")
+	verifier.lastRefresh = time.Now().Add(-minUnknownKeyRefresh)
+	refreshed := make(chan error)
+	go func() {
+		_, err := verifier.ParseOauthToken(context.Background(), issuer.sign(t, "key-2", issuer.claims()))
+		refreshed <- err
+	}()
+	<-fetching
+
+	verified := make(chan error)
+	go func() {
+		_, err := verifier.ParseOauthToken(context.Background(), issuer.sign(t, "key-1", issuer.claims()))
+		verified <- err
+	}()
+	select {
+	case err = <-verified:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Error("the cached key is locked during the refresh")
+	}
+	close(release)
+	assert.NotNil(t, <-refreshed)
+}
+
+// localVerifier fake verifier for the tokens of this service
+type localVerifier struct{}
+
+func (lv *localVerifier) ParseOauthToken(ctx context.Context, token string) (*domain.AuthClaims, error) {
+	return &domain.AuthClaims{Username: "local"}, nil
+}
+
+// TestIssuerVerifierDispatchesByIssuer for test ParseOauthToken
+func TestIssuerVerifierDispatchesByIssuer(t *testing.T) {
+	first := newFakeIssuer(t)
+	second := newFakeIssuer(t)
+	verifier, err := NewIssuerVerifier(log, &localVerifier{}, config.OIDCConfiguration{
+		Issuers: []config.OIDCIssuerConfiguration{first.config(), second.config()},
+	})
+	assert.Nil(t, err)
+
+	claims, err := verifier.ParseOauthToken(context.Background(), second.sign(t, "key-1", second.claims()))
+	assert.Nil(t, err)
+	assert.Equal(t, "john", claims.Username)
+
+	// A token claiming the first issuer but signed by the second issuer keys
+	forged := second.claims()
+	forged["iss"] = first.server.URL
+	_, err = verifier.ParseOauthToken(context.Background(), second.sign(t, "key-1", forged))
+	assert.NotNil(t, err)
+
+	local := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": domain.Issuer})
+	localToken, _ := local.SignedString([]byte("secret"))
+	claims, err = verifier.ParseOauthToken(context.Background(), localToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "local", claims.Username)
+
+	unknown := second.claims()
+	unknown["iss"] = "https://untrusted"
+	_, err = verifier.ParseOauthToken(context.Background(), second.sign(t, "key-1", unknown))
+	assert.Equal(t, "invalid token issuer", err.Error())
+}
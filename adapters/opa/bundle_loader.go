@@ -0,0 +1,168 @@
+package opa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/keys"
+	"github.com/open-policy-agent/opa/rego"
+	"golang-api-hexagonal/config"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const bundleName = "authz"
+
+// policyLoader load the policies to compile
+type policyLoader interface {
+	// load returns the rego options with the policies and their revision, changed is false when the policies did not change since the last load
+	load(ctx context.Context) (options []func(*rego.Rego), revision string, changed bool, err error)
+}
+
+// fileLoader load the rego and data files of a file or directory
+type fileLoader struct {
+	path string
+}
+
+// load the files, the revision is the hash of the files
+func (fl *fileLoader) load(ctx context.Context) ([]func(*rego.Rego), string, bool, error) {
+	hash := sha256.New()
+	err := filepath.WalkDir(fl.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		hash.Write([]byte(path))
+		hash.Write(content)
+		return nil
+	})
+	if err != nil {
+		return nil, "", false, err
+	}
+	return []func(*rego.Rego){rego.Load([]string{fl.path}, nil)}, hex.EncodeToString(hash.Sum(nil))[:12], true, nil
+}
+
+// bundleLoader load an OPA bundle from a bundle server or a local tar.gz file or directory, verifying its signature
+type bundleLoader struct {
+	conf         config.PoliciesBundleConfiguration
+	client       *http.Client
+	verification *bundle.VerificationConfig
+	lock         sync.Mutex
+	etag         string
+}
+
+// newBundleLoader create the bundle loader, the verification key is loaded when a key id is configured
+func newBundleLoader(conf config.PoliciesBundleConfiguration, client *http.Client) (*bundleLoader, error) {
+	loader := &bundleLoader{
+		conf:   conf,
+		client: client,
+	}
+
+	if conf.Verification.KeyID != "" {
+		key, err := keys.NewKeyConfig(conf.Verification.PublicKeyFile, conf.Verification.Algorithm, conf.Verification.Scope)
+		if err != nil {
+			return nil, err
+		}
+		loader.verification = bundle.NewVerificationConfig(map[string]*bundle.KeyConfig{conf.Verification.KeyID: key},
+			conf.Verification.KeyID, conf.Verification.Scope, nil)
+	}
+	return loader, nil
+}
+
+// load the bundle, the revision is the manifest revision or the bundle ETag
+func (bl *bundleLoader) load(ctx context.Context) ([]func(*rego.Rego), string, bool, error) {
+	var policyBundle *bundle.Bundle
+	var err error
+	if bl.conf.URL != "" {
+		policyBundle, err = bl.download(ctx)
+	} else {
+		policyBundle, err = bl.readPath()
+	}
+	if err != nil || policyBundle == nil {
+		return nil, "", false, err
+	}
+	revision := policyBundle.Manifest.Revision
+	if revision == "" {
+		revision = policyBundle.Etag
+	}
+	return []func(*rego.Rego){rego.ParsedBundle(bundleName, policyBundle)}, revision, true, nil
+}
+
+// download the bundle from the bundle server, returns nil when the bundle was not modified since the last download
+func (bl *bundleLoader) download(ctx context.Context) (*bundle.Bundle, error) {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, bl.conf.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if bl.etag != "" {
+		request.Header.Set("If-None-Match", bl.etag)
+	}
+
+	response, err := bl.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusNotModified:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("unexpected status %d from the bundle server", response.StatusCode)
+	}
+
+	etag := response.Header.Get("ETag")
+	policyBundle, err := bl.read(response.Body, etag)
+	if err != nil {
+		return nil, err
+	}
+	bl.etag = etag
+	return policyBundle, nil
+}
+
+// readPath read the bundle tar.gz file or directory
+func (bl *bundleLoader) readPath() (*bundle.Bundle, error) {
+	info, err := os.Stat(bl.conf.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return bl.verify(bundle.NewCustomReader(bundle.NewDirectoryLoader(bl.conf.Path)))
+	}
+
+	file, err := os.Open(bl.conf.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return bl.read(file, "")
+}
+
+func (bl *bundleLoader) read(reader io.Reader, etag string) (*bundle.Bundle, error) {
+	return bl.verify(bundle.NewReader(reader).WithBundleEtag(etag))
+}
+
+func (bl *bundleLoader) verify(reader *bundle.Reader) (*bundle.Bundle, error) {
+	if bl.verification != nil {
+		reader = reader.WithBundleVerificationConfig(bl.verification)
+	}
+	policyBundle, err := reader.WithBundleName(bundleName).Read()
+	if err != nil {
+		return nil, err
+	}
+	return &policyBundle, nil
+}
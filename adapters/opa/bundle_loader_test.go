@@ -0,0 +1,152 @@
+package opa
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const ownershipPolicy = `package golangapitemplate.authz
+
+default allow = false
+
+allow {
+	input.EntityData.Type == "updateProduct"
+	data.owners[input.Token.Username][_] == input.EntityData.Owner
+}
+`
+
+// fakeBundleServer bundle server with ETag support
+type fakeBundleServer struct {
+	server   *httptest.Server
+	lock     sync.Mutex
+	bundle   []byte
+	version  int
+	requests int
+}
+
+func newFakeBundleServer(t *testing.T) *fakeBundleServer {
+	fake := &fakeBundleServer{}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.lock.Lock()
+		defer fake.lock.Unlock()
+		fake.requests++
+		etag := fmt.Sprintf(`"%d"`, fake.version)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write(fake.bundle)
+	}))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (fb *fakeBundleServer) publish(content []byte) {
+	fb.lock.Lock()
+	defer fb.lock.Unlock()
+	fb.bundle = content
+	fb.version++
+}
+
+// buildBundle write a bundle with the policy and data, signed when the private key is given
+func buildBundle(t *testing.T, policy string, data map[string]interface{}, privateKey string) []byte {
+	module, err := ast.ParseModule("authz.rego", policy)
+	assert.Nil(t, err)
+	policyBundle := bundle.Bundle{
+		Data:    data,
+		Modules: []bundle.ModuleFile{{URL: "authz.rego", Path: "authz.rego", Raw: []byte(policy), Parsed: module}},
+	}
+	policyBundle.Manifest.Init()
+	if privateKey != "" {
+		assert.Nil(t, policyBundle.GenerateSignature(bundle.NewSigningConfig(privateKey, "RS256", ""), "bundle-key", false))
+	}
+
+	var buffer bytes.Buffer
+	assert.Nil(t, bundle.NewWriter(&buffer).Write(policyBundle))
+	return buffer.Bytes()
+}
+
+// writeRSAKeys returns the PEM private key and the public key file
+func writeRSAKeys(t *testing.T) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+	publicKeyFile := filepath.Join(t.TempDir(), "bundle.pub")
+	assert.Nil(t, os.WriteFile(publicKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600))
+	return string(privateKey), publicKeyFile
+}
+
+// TestSignedBundleFromBundleServer for test Reload with a bundle server
+func TestSignedBundleFromBundleServer(t *testing.T) {
+	privateKey, publicKeyFile := writeRSAKeys(t)
+	server := newFakeBundleServer(t)
+	server.publish(buildBundle(t, ownershipPolicy, map[string]interface{}{
+		"owners": map[string]interface{}{"john": []interface{}{"alice"}},
+	}, privateKey))
+
+	service := NewPolicyService(config.PoliciesConfiguration{Bundle: config.PoliciesBundleConfiguration{
+		URL:          server.server.URL + "/bundles/authz.tar.gz",
+		Verification: config.BundleVerificationConfig{KeyID: "bundle-key", PublicKeyFile: publicKeyFile, Algorithm: "RS256"},
+	}}, log, prometheus.NewRegistry(), nil)
+	john := domain.AuthClaims{Username: "john", Roles: []string{"user"}}
+	assert.True(t, service.EvaluateApiPolicy(context.Background(), john, domain.UpdateProductOperation, domain.PolicyResource{Owner: "alice"}).Allow)
+	assert.False(t, service.EvaluateApiPolicy(context.Background(), john, domain.UpdateProductOperation, domain.PolicyResource{Owner: "bob"}).Allow)
+
+	// Not modified since the last download
+	assert.Nil(t, service.Reload(context.Background()))
+	assert.Equal(t, 2, server.requests)
+
+	// An unsigned bundle is rejected and the current policies are kept
+	server.publish(buildBundle(t, ownershipPolicy, map[string]interface{}{
+		"owners": map[string]interface{}{"john": []interface{}{"bob"}},
+	}, ""))
+	assert.NotNil(t, service.Reload(context.Background()))
+	assert.True(t, service.EvaluateApiPolicy(context.Background(), john, domain.UpdateProductOperation, domain.PolicyResource{Owner: "alice"}).Allow)
+
+	// A bundle signed by another key is rejected
+	otherKey, _ := writeRSAKeys(t)
+	server.publish(buildBundle(t, ownershipPolicy, map[string]interface{}{
+		"owners": map[string]interface{}{"john": []interface{}{"bob"}},
+	}, otherKey))
+	assert.NotNil(t, service.Reload(context.Background()))
+	assert.False(t, service.EvaluateApiPolicy(context.Background(), john, domain.UpdateProductOperation, domain.PolicyResource{Owner: "bob"}).Allow)
+
+	server.publish(buildBundle(t, ownershipPolicy, map[string]interface{}{
+		"owners": map[string]interface{}{"john": []interface{}{"bob"}},
+	}, privateKey))
+	assert.Nil(t, service.Reload(context.Background()))
+	assert.True(t, service.EvaluateApiPolicy(context.Background(), john, domain.UpdateProductOperation, domain.PolicyResource{Owner: "bob"}).Allow)
+	assert.False(t, service.EvaluateApiPolicy(context.Background(), john, domain.UpdateProductOperation, domain.PolicyResource{Owner: "alice"}).Allow)
+}
+
+// TestBundleFromPath for test Reload with a local bundle file
+func TestBundleFromPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	assert.Nil(t, os.WriteFile(path, buildBundle(t, ownershipPolicy, map[string]interface{}{
+		"owners": map[string]interface{}{"john": []interface{}{"alice"}},
+	}, ""), 0o600))
+
+	service := NewPolicyService(config.PoliciesConfiguration{Bundle: config.PoliciesBundleConfiguration{Path: path}}, log, prometheus.NewRegistry(), nil)
+	john := domain.AuthClaims{Username: "john", Roles: []string{"user"}}
+	assert.True(t, service.EvaluateApiPolicy(context.Background(), john, domain.UpdateProductOperation, domain.PolicyResource{Owner: "alice"}).Allow)
+}
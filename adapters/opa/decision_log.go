@@ -0,0 +1,138 @@
+package opa
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"strings"
+	"time"
+)
+
+const (
+	// DecisionLogStdout write the decisions in the application log
+	DecisionLogStdout = "stdout"
+	// DecisionLogKafka produce the decisions in the kafka audit topic
+	DecisionLogKafka = "kafka"
+
+	defaultDecisionLogBuffer = 1000
+	redactedValue            = "***"
+)
+
+// DecisionLog entry of the decision log
+type DecisionLog struct {
+	DecisionID string                 `json:"decisionId"`
+	TraceID    string                 `json:"traceId"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Input      map[string]interface{} `json:"input"`
+	Result     domain.PolicyDecision  `json:"result"`
+	Revision   string                 `json:"revision"`
+	LatencyMs  float64                `json:"latencyMs"`
+}
+
+// DecisionLogger write the policy decisions asynchronously, so the requests do not wait for the log sink
+type DecisionLogger struct {
+	log     *zap.SugaredLogger
+	sink    string
+	topic   string
+	message ports.IMessage
+	redact  [][]string
+	entries chan *DecisionLog
+}
+
+// NewDecisionLogger create the decision logger for the configured sink, returns nil when the decision log is disabled
+func NewDecisionLogger(log *zap.SugaredLogger, conf config.DecisionLogConfiguration, message ports.IMessage, topic string) *DecisionLogger {
+	if conf.Sink != DecisionLogStdout && conf.Sink != DecisionLogKafka {
+		log.Infof("Policy decision log disabled")
+		return nil
+	}
+
+	bufferSize := conf.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultDecisionLogBuffer
+	}
+	logger := &DecisionLogger{
+		log:     log.Named("decision"),
+		sink:    conf.Sink,
+		topic:   topic,
+		message: message,
+		entries: make(chan *DecisionLog, bufferSize),
+	}
+	for _, path := range conf.Redact {
+		logger.redact = append(logger.redact, strings.Split(path, "."))
+	}
+
+	go logger.run()
+	log.Infof("Policy decision log sent to %s", conf.Sink)
+	return logger
+}
+
+// Log enqueue the decision with the redacted input, the decision is dropped when the buffer is full
+func (dl *DecisionLogger) Log(input PolicyInput, result domain.PolicyDecision, revision, traceID string, latency time.Duration) {
+	entry := &DecisionLog{
+		DecisionID: uuid.NewString(),
+		TraceID:    traceID,
+		Timestamp:  time.Now().UTC(),
+		Input:      dl.redactInput(input),
+		Result:     result,
+		Revision:   revision,
+		LatencyMs:  float64(latency.Microseconds()) / 1000,
+	}
+
+	select {
+	case dl.entries <- entry:
+	default:
+		dl.log.With("traceId", traceID).Warnf("Policy decision log buffer full, dropping the decision %s", entry.DecisionID)
+	}
+}
+
+func (dl *DecisionLogger) run() {
+	for entry := range dl.entries {
+		switch dl.sink {
+		case DecisionLogKafka:
+			data, err := json.Marshal(entry)
+			if err != nil {
+				dl.log.With("traceId", entry.TraceID).Errorf("Internal error to marshal the policy decision: %v", err)
+				continue
+			}
+			tenantID := domain.DefaultTenantID
+			if entityData, ok := entry.Input["EntityData"].(map[string]interface{}); ok {
+				tenantID, _ = entityData["TenantID"].(string)
+			}
+			dl.message.ProduceMessage(dl.topic, string(data), domain.PolicyDecisionEventName, tenantID, entry.TraceID)
+		default:
+			dl.log.With("traceId", entry.TraceID).Infow("Policy decision",
+				"decisionId", entry.DecisionID, "input", entry.Input, "result", entry.Result,
+				"revision", entry.Revision, "latencyMs", entry.LatencyMs)
+		}
+	}
+}
+
+// redactInput convert the input to a map replacing the redacted fields
+func (dl *DecisionLogger) redactInput(input PolicyInput) map[string]interface{} {
+	result := map[string]interface{}{}
+	data, err := json.Marshal(input)
+	if err != nil || json.Unmarshal(data, &result) != nil {
+		return map[string]interface{}{}
+	}
+
+	for _, path := range dl.redact {
+		current := result
+		for index, key := range path {
+			value, ok := current[key]
+			if !ok {
+				break
+			}
+			if index == len(path)-1 {
+				current[key] = redactedValue
+				break
+			}
+			if current, ok = value.(map[string]interface{}); !ok {
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,48 @@
+package opa
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/adapters/kafka"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"testing"
+	"time"
+)
+
+// TestDecisionWithReasonsIsLogged for test EvaluateApiPolicy and DecisionLogger
+func TestDecisionWithReasonsIsLogged(t *testing.T) {
+	producer := &kafka.MessageProducerMock{}
+	events := make(chan string, 2)
+	producer.ProduceMessageFunc = func(topicName, value, eventName, tenantID, traceID string) {
+		assert.Equal(t, "audit", topicName)
+		assert.Equal(t, domain.PolicyDecisionEventName, eventName)
+		events <- value
+	}
+	decisionLogger := NewDecisionLogger(log, config.DecisionLogConfiguration{Sink: DecisionLogKafka, Redact: []string{"Token.Subject"}},
+		producer, "audit")
+	service := NewPolicyService(config.PoliciesConfiguration{Path: "../../resources/api_policies.rego"}, log, prometheus.NewRegistry(), decisionLogger)
+
+	user := domain.AuthClaims{Subject: "f3a2", Username: "john", Roles: []string{"user"}}
+	decision := service.EvaluateApiPolicy(context.Background(), user, domain.ViewProductOperation, domain.PolicyResource{})
+	assert.Equal(t, domain.PolicyDecision{Allow: true, Reasons: []string{"user_view_product"}, Obligations: []string{}}, decision)
+
+	decision = service.EvaluateApiPolicy(context.Background(), user, domain.CreateProductOperation, domain.PolicyResource{})
+	assert.Equal(t, domain.PolicyDecision{Allow: false, Reasons: []string{"operation_not_allowed"}, Obligations: []string{}}, decision)
+
+	for _, allow := range []bool{true, false} {
+		select {
+		case value := <-events:
+			entry := DecisionLog{}
+			assert.Nil(t, json.Unmarshal([]byte(value), &entry))
+			assert.Equal(t, allow, entry.Result.Allow)
+			assert.NotEmpty(t, entry.Revision)
+			assert.Equal(t, redactedValue, entry.Input["Token"].(map[string]interface{})["Subject"])
+			assert.Equal(t, "john", entry.Input["Token"].(map[string]interface{})["Username"])
+		case <-time.After(5 * time.Second):
+			t.Fatal("decision not logged")
+		}
+	}
+}
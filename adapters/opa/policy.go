@@ -3,14 +3,21 @@ package opa
 // Token define the rego auth token
 type Token struct {
 	Type     string
+	Subject  string
 	Username string
 	Roles    []string
+	Scopes   []string
+	TenantID string
+	Audience []string
 }
 
-// EntityData define the entity owner
+// EntityData define the operation and the entity owner, tenant, status and requested status
 type EntityData struct {
-	Type  string
-	Owner string
+	Type         string
+	Owner        string
+	TenantID     string
+	Status       string
+	TargetStatus string
 }
 
 // PolicyInput input policy data
@@ -2,61 +2,299 @@ package opa
 
 import (
 	"context"
+	"errors"
+	"github.com/fsnotify/fsnotify"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/open-policy-agent/opa/rego"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/zap"
+	"golang-api-hexagonal/config"
 	"golang-api-hexagonal/core/domain"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const (
+	// policyQuery evaluate the policy package, with the decision rule or only the allow rule
+	policyQuery = "x = data.golangapitemplate.authz"
+	// reloadDebounce groups the file events of a single policy change, e.g. an editor writing a temporary file and renaming it
+	reloadDebounce        = 500 * time.Millisecond
+	defaultBundlePolling  = 60 * time.Second
+	bundleDownloadTimeout = 30 * time.Second
+)
+
+// reasonCodePattern format of the reason codes returned to the clients
+var reasonCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// compiledPolicy compiled policy query with the revision of its policies
+type compiledPolicy struct {
+	query    rego.PreparedEvalQuery
+	revision string
+}
+
 // PolicyService policy service
 type PolicyService struct {
-	log    *zap.SugaredLogger
-	policy rego.PreparedEvalQuery
+	log            *zap.SugaredLogger
+	path           string
+	conf           config.PoliciesConfiguration
+	loader         policyLoader
+	policy         atomic.Pointer[compiledPolicy]
+	decisionLogger *DecisionLogger
+	reloadLock     sync.Mutex
+	reloadCounter  *prometheus.CounterVec
+	reloadTime     prometheus.Gauge
+}
+
+// NewPolicyService new policy service, the policies are loaded from the bundle when it is configured,
+// otherwise from the policy path, a rego file or a directory with rego and data files
+func NewPolicyService(conf config.PoliciesConfiguration, log *zap.SugaredLogger, registerer prometheus.Registerer,
+	decisionLogger *DecisionLogger) *PolicyService {
+	service := &PolicyService{
+		log:            log,
+		path:           conf.Path,
+		conf:           conf,
+		loader:         &fileLoader{path: conf.Path},
+		decisionLogger: decisionLogger,
+		reloadCounter: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Name: "opa_policy_reloads_total",
+			Help: "The total number of policy reloads by result",
+		}, []string{"result"}),
+		reloadTime: promauto.With(registerer).NewGauge(prometheus.GaugeOpts{
+			Name: "opa_policy_last_reload_success_timestamp_seconds",
+			Help: "The timestamp of the last successful policy reload",
+		}),
+	}
+
+	if conf.Bundle.URL != "" || conf.Bundle.Path != "" {
+		loader, err := newBundleLoader(conf.Bundle, &http.Client{Timeout: bundleDownloadTimeout})
+		if err != nil {
+			log.Fatalf("Failed to load the bundle verification key: %v", err)
+			return nil
+		}
+		service.loader = loader
+		service.path = conf.Bundle.Path
+		if conf.Bundle.URL != "" {
+			service.path = conf.Bundle.URL
+		}
+	}
+
+	if err := service.Reload(context.Background()); err != nil {
+		log.Fatalf("Failed to load the policies: %v", err)
+		return nil
+	}
+	return service
 }
 
-// NewPolicyService new policy service
-func NewPolicyService(regoFilePath string, log *zap.SugaredLogger) *PolicyService {
-	r := rego.New(rego.Query("x = data.golangapitemplate.authz.allow"), rego.Load([]string{regoFilePath}, nil))
+// Reload compile the policies and swap them only when the compilation succeeds, otherwise the current policies are kept
+func (p *PolicyService) Reload(ctx context.Context) error {
+	p.reloadLock.Lock()
+	defer p.reloadLock.Unlock()
 
-	eval, err := r.PrepareForEval(context.Background())
+	options, revision, changed, err := p.loader.load(ctx)
+	if err == nil && !changed {
+		p.log.Debugf("OPA policy of %s not modified", p.path)
+		return nil
+	}
+	var eval rego.PreparedEvalQuery
+	if err == nil {
+		eval, err = rego.New(append(options, rego.Query(policyQuery))...).PrepareForEval(ctx)
+	}
 	if err != nil {
-		log.Fatalf("Failed to load the policy file: %v", err)
+		p.reloadCounter.WithLabelValues("failure").Inc()
+		p.log.Errorf("Failed to load the policies of %s, keeping the current policies: %v", p.path, err)
+		return err
+	}
+
+	p.policy.Store(&compiledPolicy{query: eval, revision: revision})
+	p.reloadCounter.WithLabelValues("success").Inc()
+	p.reloadTime.SetToCurrentTime()
+	p.log.Infof("OPA policy revision %s loaded from %s", revision, p.path)
+	return nil
+}
+
+// Watch reload the policies when the files of the policy or bundle path change, or poll the bundle server, until the context is done
+func (p *PolicyService) Watch(ctx context.Context) error {
+	if p.conf.Bundle.URL != "" {
+		go p.poll(ctx)
 		return nil
 	}
 
-	log.Infof("OPA policy loaded")
-	return &PolicyService{
-		log:    log,
-		policy: eval,
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		_ = watcher.Close()
+		return err
+	}
+	// Watch the directory of a single file, the editors usually replace the file instead of writing it
+	watchedFile := ""
+	if info.IsDir() {
+		err = filepath.WalkDir(p.path, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.IsDir() {
+				return err
+			}
+			return watcher.Add(path)
+		})
+	} else {
+		watchedFile = filepath.Clean(p.path)
+		err = watcher.Add(filepath.Dir(p.path))
+	}
+	if err != nil {
+		_ = watcher.Close()
+		return err
+	}
+
+	p.log.Infof("Watching the policies of %s", p.path)
+	go p.watchEvents(ctx, watcher, watchedFile)
+	return nil
+}
+
+func (p *PolicyService) watchEvents(ctx context.Context, watcher *fsnotify.Watcher, watchedFile string) {
+	defer watcher.Close()
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if watchedFile != "" && filepath.Clean(event.Name) != watchedFile {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = watcher.Add(event.Name)
+				}
+			}
+			debounce.Reset(reloadDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			p.log.Errorf("Policy watcher error: %v", err)
+		case <-debounce.C:
+			_ = p.Reload(ctx)
+		}
+	}
+}
+
+// poll download the bundle from the bundle server, it is only activated when it was modified
+func (p *PolicyService) poll(ctx context.Context) {
+	interval := time.Duration(p.conf.Bundle.PollingSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultBundlePolling
+	}
+	p.log.Infof("Polling the policies bundle of %s every %v", p.path, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = p.Reload(ctx)
+		}
 	}
 }
 
-// EvaluateApiPolicy evaluate the api policy roles
-func (p *PolicyService) EvaluateApiPolicy(ctx context.Context, claims domain.AuthClaims, operation, ownerUserName string) bool {
-	traceID := ctx.Value(middleware.RequestIDKey)
+// EvaluateApiPolicy evaluate the api policy of the operation on the resource and log the decision
+func (p *PolicyService) EvaluateApiPolicy(ctx context.Context, claims domain.AuthClaims, operation domain.Operation, resource domain.PolicyResource) domain.PolicyDecision {
+	traceID, _ := ctx.Value(middleware.RequestIDKey).(string)
 
 	token := Token{
+		Subject:  claims.Subject,
 		Username: claims.Username,
-		Roles:    []string{claims.Role},
+		Roles:    claims.Roles,
+		Scopes:   claims.Scopes,
+		TenantID: claims.TenantID,
+		Audience: claims.Audience,
+	}
+	tenantID := resource.TenantID
+	if tenantID == "" {
+		tenantID = domain.TenantFromContext(ctx)
 	}
 	data := EntityData{
-		Type:  operation,
-		Owner: ownerUserName,
+		Type:         string(operation),
+		Owner:        resource.Owner,
+		TenantID:     tenantID,
+		Status:       resource.Status,
+		TargetStatus: resource.TargetStatus,
 	}
 	input := PolicyInput{
 		Token:      token,
 		EntityData: data,
 	}
 
-	result, err := p.policy.Eval(ctx, rego.EvalInput(input))
+	policy := p.policy.Load()
+	startTime := time.Now()
+	result, err := policy.query.Eval(ctx, rego.EvalInput(input))
+	latency := time.Since(startTime)
+
+	decision := domain.PolicyDecision{Reasons: []string{domain.PolicyErrorReason}, Obligations: []string{}}
 	if err != nil || len(result) == 0 {
 		p.log.With("traceId", traceID).Warnf("Policy evaluation failed: %s", err)
-		return false
-	} else if _, ok := result[0].Bindings["x"].(bool); !ok {
+	} else if document, ok := result[0].Bindings["x"].(map[string]interface{}); !ok {
 		p.log.With("traceId", traceID).Warn("Policy evaluation failed")
-		return false
+	} else if decision, err = decisionFromDocument(document); err != nil {
+		p.log.With("traceId", traceID).Warnf("Policy evaluation failed: %s", err)
 	} else {
-		p.log.With("traceId", traceID).Infof("Policy result: %v", result)
-		return result[0].Bindings["x"].(bool)
+		p.log.With("traceId", traceID).Infof("Policy result: %v", decision)
+	}
+
+	if p.decisionLogger != nil {
+		p.decisionLogger.Log(input, decision, policy.revision, traceID, latency)
+	}
+	return decision
+}
+
+// decisionFromDocument read the decision of the policy package, the policies without decision rule only define allow
+func decisionFromDocument(document map[string]interface{}) (domain.PolicyDecision, error) {
+	decision := domain.PolicyDecision{Reasons: []string{}, Obligations: []string{}}
+	value, ok := document["decision"].(map[string]interface{})
+	if !ok {
+		allow, ok := document["allow"].(bool)
+		if !ok {
+			return domain.PolicyDecision{Reasons: []string{domain.PolicyErrorReason}, Obligations: []string{}}, errors.New("no decision or allow rule")
+		}
+		decision.Allow = allow
+		return decision, nil
+	}
+
+	if decision.Allow, ok = value["allow"].(bool); !ok {
+		return domain.PolicyDecision{Reasons: []string{domain.PolicyErrorReason}, Obligations: []string{}}, errors.New("decision without allow")
+	}
+	decision.Reasons = safeCodes(value["reasons"])
+	decision.Obligations = safeCodes(value["obligations"])
+	return decision, nil
+}
+
+// safeCodes returns the values that are reason codes, so no policy data is returned to the clients
+func safeCodes(value interface{}) []string {
+	codes := []string{}
+	values, _ := value.([]interface{})
+	for _, item := range values {
+		if code, ok := item.(string); ok && reasonCodePattern.MatchString(code) {
+			codes = append(codes, code)
+		}
 	}
+	sort.Strings(codes)
+	return codes
 }
@@ -0,0 +1,141 @@
+package opa
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var log = config.NewLogger()
+
+const adminPolicy = `package golangapitemplate.authz
+
+default allow = false
+
+allow {
+	input.Token.Roles[_] == "admin"
+}
+`
+
+const userPolicy = `package golangapitemplate.authz
+
+default allow = false
+
+allow {
+	input.Token.Roles[_] == "user"
+}
+`
+
+func writePolicy(t *testing.T, path, policy string) {
+	assert.Nil(t, os.WriteFile(path, []byte(policy), 0o600))
+}
+
+// TestReloadKeepsThePolicyWhenTheCompilationFails for test Reload
+func TestReloadKeepsThePolicyWhenTheCompilationFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_policies.rego")
+	writePolicy(t, path, adminPolicy)
+	service := NewPolicyService(config.PoliciesConfiguration{Path: path}, log, prometheus.NewRegistry(), nil)
+	admin := domain.AuthClaims{Username: "john", Roles: []string{"admin"}}
+
+	writePolicy(t, path, "package golangapitemplate.authz\n\nallow {")
+	assert.NotNil(t, service.Reload(context.Background()))
+	assert.True(t, service.EvaluateApiPolicy(context.Background(), admin, domain.ViewProductOperation, domain.PolicyResource{}).Allow)
+	assert.Equal(t, 1.0, testutil.ToFloat64(service.reloadCounter.WithLabelValues("failure")))
+
+	writePolicy(t, path, userPolicy)
+	assert.Nil(t, service.Reload(context.Background()))
+	assert.False(t, service.EvaluateApiPolicy(context.Background(), admin, domain.ViewProductOperation, domain.PolicyResource{}).Allow)
+	assert.Equal(t, 2.0, testutil.ToFloat64(service.reloadCounter.WithLabelValues("success")))
+}
+
+// TestWatchReloadsThePolicyDirectory for test Watch
+func TestWatchReloadsThePolicyDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, filepath.Join(dir, "api_policies.rego"), adminPolicy)
+	service := NewPolicyService(config.PoliciesConfiguration{Path: dir}, log, prometheus.NewRegistry(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.Nil(t, service.Watch(ctx))
+
+	user := domain.AuthClaims{Username: "john", Roles: []string{"user"}}
+	assert.False(t, service.EvaluateApiPolicy(ctx, user, domain.ViewProductOperation, domain.PolicyResource{}).Allow)
+
+	writePolicy(t, filepath.Join(dir, "api_policies.rego"), userPolicy)
+	assert.Eventually(t, func() bool {
+		return service.EvaluateApiPolicy(ctx, user, domain.ViewProductOperation, domain.PolicyResource{}).Allow
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+// TestEvaluateApiPolicy for test EvaluateApiPolicy with the compiled api policies
+func TestEvaluateApiPolicy(t *testing.T) {
+	service := NewPolicyService(config.PoliciesConfiguration{Path: apiPolicyFile}, log, prometheus.NewRegistry(), nil)
+
+	tests := []struct {
+		name        string
+		claims      domain.AuthClaims
+		operation   domain.Operation
+		resource    domain.PolicyResource
+		allow       bool
+		reasons     []string
+		obligations []string
+	}{
+		{"admin creates product", domain.AuthClaims{Username: "root", Roles: []string{"admin"}},
+			domain.CreateProductOperation, domain.PolicyResource{}, true, []string{"admin"}, []string{}},
+		{"admin reloads policies", domain.AuthClaims{Username: "root", Roles: []string{"admin"}},
+			domain.ReloadPoliciesOperation, domain.PolicyResource{}, true, []string{"admin"}, []string{}},
+		{"business updates product", domain.AuthClaims{Username: "john", Roles: []string{"business"}},
+			domain.UpdateProductOperation, domain.PolicyResource{Owner: "alice"}, true, []string{"business_update_product"}, []string{}},
+		{"business views product", domain.AuthClaims{Username: "john", Roles: []string{"business"}},
+			domain.ViewProductOperation, domain.PolicyResource{Owner: "alice"}, true, []string{"business_view_product"}, []string{}},
+		{"business can not create product", domain.AuthClaims{Username: "john", Roles: []string{"business"}},
+			domain.CreateProductOperation, domain.PolicyResource{}, false, []string{"operation_not_allowed"}, []string{}},
+		{"business_main_id creates product", domain.AuthClaims{Username: "business_main_id", Roles: []string{"business"}},
+			domain.CreateProductOperation, domain.PolicyResource{}, true, []string{"business_main_create_product"}, []string{}},
+		{"user views product", domain.AuthClaims{Username: "john", Roles: []string{"user"}},
+			domain.ViewProductOperation, domain.PolicyResource{Owner: "alice"}, true, []string{"user_view_product"}, []string{}},
+		{"user can not update product", domain.AuthClaims{Username: "john", Roles: []string{"user"}},
+			domain.UpdateProductOperation, domain.PolicyResource{Owner: "alice"}, false, []string{"operation_not_allowed"}, []string{}},
+		{"user can not manage api keys", domain.AuthClaims{Username: "john", Roles: []string{"user"}},
+			domain.ManageApiKeysOperation, domain.PolicyResource{}, false, []string{"operation_not_allowed"}, []string{}},
+		{"user searches products", domain.AuthClaims{Username: "john", Roles: []string{"user"}},
+			domain.SearchProductsOperation, domain.PolicyResource{}, true, []string{"search_products"}, []string{}},
+		{"business publishes product", domain.AuthClaims{Username: "john", Roles: []string{"business"}},
+			domain.ChangeProductStatusOperation, domain.PolicyResource{Owner: "alice", Status: "pending", TargetStatus: "available"},
+			true, []string{"business_change_product_status"}, []string{}},
+		{"business can not reactivate product", domain.AuthClaims{Username: "john", Roles: []string{"business"}},
+			domain.ChangeProductStatusOperation, domain.PolicyResource{Owner: "alice", Status: "inactive", TargetStatus: "pending"},
+			false, []string{"operation_not_allowed"}, []string{}},
+		{"auditor views product history", domain.AuthClaims{Username: "carol", Roles: []string{"auditor"}},
+			domain.ViewProductHistoryOperation, domain.PolicyResource{Owner: "alice"}, true, []string{"auditor_view_product_history"}, []string{}},
+		{"user can not view product history", domain.AuthClaims{Username: "john", Roles: []string{"user"}},
+			domain.ViewProductHistoryOperation, domain.PolicyResource{Owner: "alice"}, false, []string{"operation_not_allowed"}, []string{}},
+		{"business_main_id imports products", domain.AuthClaims{Username: "business_main_id", Roles: []string{"business"}},
+			domain.ImportProductsOperation, domain.PolicyResource{}, true, []string{"business_main_import_products"}, []string{}},
+		{"business can not view the import of other user", domain.AuthClaims{Username: "john", Roles: []string{"business"}},
+			domain.ImportProductsOperation, domain.PolicyResource{Owner: "business_main_id"}, false, []string{"operation_not_allowed"}, []string{}},
+		{"auditor exports products", domain.AuthClaims{Username: "carol", Roles: []string{"auditor"}},
+			domain.ExportProductsOperation, domain.PolicyResource{}, true, []string{"auditor_export_products"}, []string{}},
+		{"user can not export products", domain.AuthClaims{Username: "john", Roles: []string{"user"}},
+			domain.ExportProductsOperation, domain.PolicyResource{}, false, []string{"operation_not_allowed"}, []string{}},
+		{"owner updates product", domain.AuthClaims{Username: "alice", Roles: []string{"user"}},
+			domain.UpdateProductOperation, domain.PolicyResource{Owner: "alice", Status: "available"}, true, []string{"owner"}, []string{}},
+		{"without roles", domain.AuthClaims{Username: "bob", Roles: []string{}},
+			domain.ViewProductOperation, domain.PolicyResource{Owner: "alice"}, false, []string{"no_roles"}, []string{}},
+		{"api key views product", domain.AuthClaims{Subject: domain.ApiKeySubjectPrefix + "f3a2", Username: "batch", Roles: []string{"user"}},
+			domain.ViewProductOperation, domain.PolicyResource{Owner: "alice"}, true, []string{"user_view_product"}, []string{"audit_api_key_access"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decision := service.EvaluateApiPolicy(context.Background(), test.claims, test.operation, test.resource)
+			assert.Equal(t, domain.PolicyDecision{Allow: test.allow, Reasons: test.reasons, Obligations: test.obligations}, decision)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package opa
+
+import (
+	"context"
+	"github.com/open-policy-agent/opa/cover"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/tester"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const (
+	apiPolicyFile     = "../../resources/api_policies.rego"
+	apiPolicyTestFile = "../../resources/api_policies_test.rego"
+	// minPolicyCoverage minimum coverage of the api policies by the rego tests
+	minPolicyCoverage = 100.0
+)
+
+// TestRegoPolicies run the _test.rego tests of the api policies with coverage
+func TestRegoPolicies(t *testing.T) {
+	ctx := context.Background()
+	modules, store, err := tester.Load([]string{apiPolicyFile, apiPolicyTestFile}, nil)
+	assert.Nil(t, err)
+
+	txn, err := store.NewTransaction(ctx, storage.WriteParams)
+	assert.Nil(t, err)
+	defer store.Abort(ctx, txn)
+
+	coverage := cover.New()
+	results, err := tester.NewRunner().
+		SetStore(store).
+		SetModules(modules).
+		SetCoverageQueryTracer(coverage).
+		RunTests(ctx, txn)
+	assert.Nil(t, err)
+
+	count := 0
+	for result := range results {
+		count++
+		t.Run(result.Name, func(t *testing.T) {
+			assert.Nil(t, result.Error)
+			assert.True(t, result.Pass(), "%s failed, defined at %v", result.Name, result.Location)
+		})
+	}
+	assert.NotZero(t, count)
+
+	report := coverage.Report(modules)
+	policyReport, ok := report.Files[apiPolicyFile]
+	assert.True(t, ok)
+	t.Logf("Policy coverage: %.2f%%, not covered: %v", policyReport.Coverage, policyReport.NotCovered)
+	assert.GreaterOrEqual(t, policyReport.Coverage, minPolicyCoverage)
+}
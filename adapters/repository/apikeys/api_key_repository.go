@@ -0,0 +1,91 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"github.com/uptrace/bun"
+	"golang-api-hexagonal/core/domain"
+	"strings"
+	"time"
+)
+
+// ApiKeyRepository repository implementation for api keys
+type ApiKeyRepository struct {
+	db bun.IDB
+}
+
+// NewApiKeyRepository creates a new api key repository instance
+func NewApiKeyRepository(db bun.IDB) *ApiKeyRepository {
+	return &ApiKeyRepository{
+		db: db,
+	}
+}
+
+// Create a new api key
+func (repo *ApiKeyRepository) Create(ctx context.Context, model *domain.ApiKeyModel) (*domain.ApiKeyModel, error) {
+	resp, err := repo.db.NewInsert().Model(model).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	affectedRows, err := resp.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affectedRows == 0 {
+		return nil, errors.New("no rows inserted")
+	}
+	return model, nil
+}
+
+// GetApiKeyByPrefix get the api key by the lookup prefix
+func (repo *ApiKeyRepository) GetApiKeyByPrefix(ctx context.Context, prefix string) (*domain.ApiKeyModel, error) {
+	var apiKey domain.ApiKeyModel
+
+	err := repo.db.NewSelect().
+		Model(&apiKey).
+		Where("prefix = ?", prefix).
+		Scan(ctx)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+// ListApiKeys list the api keys of the tenant, newest first
+func (repo *ApiKeyRepository) ListApiKeys(ctx context.Context) ([]*domain.ApiKeyModel, error) {
+	apiKeys := []*domain.ApiKeyModel{}
+
+	err := repo.db.NewSelect().
+		Model(&apiKeys).
+		Where("tenant_id = ?", domain.TenantFromContext(ctx)).
+		OrderExpr("creation_date DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+// Revoke the api key of the tenant, returns false when the api key does not exist or is already revoked
+func (repo *ApiKeyRepository) Revoke(ctx context.Context, apiKeyID string) (bool, error) {
+	resp, err := repo.db.NewUpdate().
+		Model((*domain.ApiKeyModel)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("id = ?", apiKeyID).
+		Where("tenant_id = ?", domain.TenantFromContext(ctx)).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	affectedRows, err := resp.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affectedRows > 0, nil
+}
@@ -0,0 +1,34 @@
+package apikeys
+
+import (
+	"context"
+	"golang-api-hexagonal/core/domain"
+)
+
+// ApiKeyRepositoryMock api key repository mock
+type ApiKeyRepositoryMock struct {
+	CreateFunc            func(ctx context.Context, model *domain.ApiKeyModel) (*domain.ApiKeyModel, error)
+	GetApiKeyByPrefixFunc func(ctx context.Context, prefix string) (*domain.ApiKeyModel, error)
+	ListApiKeysFunc       func(ctx context.Context) ([]*domain.ApiKeyModel, error)
+	RevokeFunc            func(ctx context.Context, apiKeyID string) (bool, error)
+}
+
+// Create is the repository mock for Create func
+func (ar *ApiKeyRepositoryMock) Create(ctx context.Context, model *domain.ApiKeyModel) (*domain.ApiKeyModel, error) {
+	return ar.CreateFunc(ctx, model)
+}
+
+// GetApiKeyByPrefix is the repository mock for GetApiKeyByPrefix func
+func (ar *ApiKeyRepositoryMock) GetApiKeyByPrefix(ctx context.Context, prefix string) (*domain.ApiKeyModel, error) {
+	return ar.GetApiKeyByPrefixFunc(ctx, prefix)
+}
+
+// ListApiKeys is the repository mock for ListApiKeys func
+func (ar *ApiKeyRepositoryMock) ListApiKeys(ctx context.Context) ([]*domain.ApiKeyModel, error) {
+	return ar.ListApiKeysFunc(ctx)
+}
+
+// Revoke is the repository mock for Revoke func
+func (ar *ApiKeyRepositoryMock) Revoke(ctx context.Context, apiKeyID string) (bool, error) {
+	return ar.RevokeFunc(ctx, apiKeyID)
+}
@@ -0,0 +1,456 @@
+package migrations
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/core/domain"
+	"hash/crc32"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// HistoryTable the Flyway schema history table, the databases migrated with Flyway keep their history
+const HistoryTable = "flyway_schema_history"
+
+// lockKey key of the postgres advisory lock held while migrating, so the parallel instances migrate one by one
+const lockKey = 4_113_727_046
+
+// Delays between the attempts to take the migration lock, the lock is polled so the wait is not cut by the read timeout
+const (
+	lockRetryDelay    = time.Millisecond * 100
+	lockRetryMaxDelay = time.Second * 5
+)
+
+// scriptName Flyway name of the versioned (V) and undo (U) migrations, e.g.: V1_0__database.sql
+var scriptName = regexp.MustCompile(`^([VU])(\d+(?:[._]\d+)*)__(.+)\.sql$`)
+
+// createHistoryTable the Flyway schema history table of postgres
+const createHistoryTable = `CREATE TABLE IF NOT EXISTS ` + HistoryTable + ` (
+	installed_rank INT NOT NULL CONSTRAINT ` + HistoryTable + `_pk PRIMARY KEY,
+	version VARCHAR(50),
+	description VARCHAR(200) NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	script VARCHAR(1000) NOT NULL,
+	checksum INT,
+	installed_by VARCHAR(100) NOT NULL,
+	installed_on TIMESTAMP NOT NULL DEFAULT now(),
+	execution_time INT NOT NULL,
+	success BOOLEAN NOT NULL
+);
+CREATE INDEX IF NOT EXISTS ` + HistoryTable + `_s_idx ON ` + HistoryTable + ` (success);`
+
+// script SQL file of a migration
+type script struct {
+	version     string
+	parts       []int
+	description string
+	name        string
+	sql         string
+	checksum    int32
+}
+
+// historyModel row of the schema history table
+type historyModel struct {
+	bun.BaseModel `bun:"table:flyway_schema_history"`
+
+	InstalledRank int       `bun:"installed_rank,pk"`
+	Version       *string   `bun:"version"`
+	Description   string    `bun:"description"`
+	Type          string    `bun:"type"`
+	Script        string    `bun:"script"`
+	Checksum      *int32    `bun:"checksum"`
+	InstalledBy   string    `bun:"installed_by"`
+	InstalledOn   time.Time `bun:"installed_on"`
+	ExecutionTime int       `bun:"execution_time"`
+	Success       bool      `bun:"success"`
+}
+
+// Migrator runner of the embedded migrations compatible with the Flyway naming and schema history
+type Migrator struct {
+	log        *zap.SugaredLogger
+	db         *bun.DB
+	migrations []script
+	rollbacks  map[string]script
+}
+
+// NewMigrator create new migrator of the V<version>__<description>.sql migrations and U<version>__<description>.sql rollbacks
+func NewMigrator(log *zap.SugaredLogger, db *bun.DB, migrations, rollbacks fs.FS) (*Migrator, error) {
+	versioned, err := loadScripts(migrations, "V")
+	if err != nil {
+		return nil, err
+	}
+	undo, err := loadScripts(rollbacks, "U")
+	if err != nil {
+		return nil, err
+	}
+	migrator := &Migrator{
+		log:        log,
+		db:         db,
+		migrations: versioned,
+		rollbacks:  make(map[string]script, len(undo)),
+	}
+	for _, rollback := range undo {
+		migrator.rollbacks[rollback.version] = rollback
+	}
+	return migrator, nil
+}
+
+// Up apply the pending migrations in version order, each one in its own transaction. Returns the applied migrations
+func (m *Migrator) Up(ctx context.Context) ([]domain.MigrationStatus, error) {
+	var applied []domain.MigrationStatus
+	err := m.withLock(ctx, func(conn bun.Conn) error {
+		statuses, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err = validate(statuses); err != nil {
+			return err
+		}
+
+		for index, migration := range m.migrations {
+			if statuses[index].State != domain.MigrationPending {
+				continue
+			}
+			if err = m.apply(ctx, conn, migration); err != nil {
+				return fmt.Errorf("migration %s failed: %w", migration.name, err)
+			}
+			status := statuses[index]
+			status.State = domain.MigrationApplied
+			status.InstalledOn = time.Now()
+			applied = append(applied, status)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down undo the last applied migrations, each one in its own transaction. Returns the reverted migrations
+func (m *Migrator) Down(ctx context.Context, steps int) ([]domain.MigrationStatus, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("invalid steps %d", steps)
+	}
+	var reverted []domain.MigrationStatus
+	err := m.withLock(ctx, func(conn bun.Conn) error {
+		var history []historyModel
+		err := conn.NewSelect().Model(&history).
+			Where("type = 'SQL'").
+			Where("version IS NOT NULL").
+			Where("success").
+			OrderExpr("installed_rank DESC").
+			Limit(steps).
+			Scan(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range history {
+			rollback, ok := m.rollbacks[*row.Version]
+			if !ok {
+				return fmt.Errorf("migration %s has no undo migration", row.Script)
+			}
+			err = conn.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+				// the scripts are executed without the bun formatting, so their ? are not placeholders
+				if _, err := tx.Tx.ExecContext(ctx, rollback.sql); err != nil {
+					return err
+				}
+				_, err := tx.NewDelete().Model((*historyModel)(nil)).Where("installed_rank = ?", row.InstalledRank).Exec(ctx)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("undo migration %s failed: %w", rollback.name, err)
+			}
+			m.log.Infof("Migration %s reverted by %s", row.Script, rollback.name)
+			reverted = append(reverted, domain.MigrationStatus{
+				Version:     *row.Version,
+				Description: row.Description,
+				Script:      row.Script,
+				State:       domain.MigrationPending,
+				Undoable:    true,
+			})
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status state of the migrations of the service and of the migrations in the history that are not in the service
+func (m *Migrator) Status(ctx context.Context) ([]domain.MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, createHistoryTable); err != nil {
+		return nil, err
+	}
+	return m.status(ctx, conn)
+}
+
+// withLock run the function in a connection holding the migration advisory lock, the history table is created when it is missing
+func (m *Migrator) withLock(ctx context.Context, fn func(conn bun.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err = m.lock(ctx, conn); err != nil {
+		return err
+	}
+	defer func() {
+		// the lock is released with the session too, the error only leaves it until the connection is closed
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(?)", lockKey); err != nil {
+			m.log.Errorf("Error to release the migration lock: %v", err)
+		}
+	}()
+
+	if _, err = conn.ExecContext(ctx, createHistoryTable); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// lock take the migration advisory lock in the connection, waiting with backoff while another instance holds it
+func (m *Migrator) lock(ctx context.Context, conn bun.Conn) error {
+	for delay := lockRetryDelay; ; delay = min(delay*2, lockRetryMaxDelay) {
+		var locked bool
+		if err := conn.NewRaw("SELECT pg_try_advisory_lock(?)", lockKey).Scan(ctx, &locked); err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+		m.log.Infof("The migration lock is held by another instance, retrying in %s", delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// status merge the migrations with the history, the statuses of the migrations keep their index
+func (m *Migrator) status(ctx context.Context, conn bun.Conn) ([]domain.MigrationStatus, error) {
+	var history []historyModel
+	if err := conn.NewSelect().Model(&history).Where("version IS NOT NULL").OrderExpr("installed_rank").Scan(ctx); err != nil {
+		return nil, err
+	}
+	rows := make(map[string]historyModel, len(history))
+	var baseline []int
+	for _, row := range history {
+		if row.Type == "BASELINE" {
+			baseline = versionParts(*row.Version)
+		}
+		rows[*row.Version] = row
+	}
+
+	statuses := make([]domain.MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		_, undoable := m.rollbacks[migration.version]
+		status := domain.MigrationStatus{
+			Version:     migration.version,
+			Description: migration.description,
+			Script:      migration.name,
+			State:       domain.MigrationPending,
+			Undoable:    undoable,
+		}
+		row, ok := rows[migration.version]
+		switch {
+		case ok && !row.Success:
+			status.State = domain.MigrationFailed
+			status.InstalledOn = row.InstalledOn
+		case ok && row.Type == "SQL" && (row.Checksum == nil || *row.Checksum != migration.checksum):
+			status.State = domain.MigrationChanged
+			status.InstalledOn = row.InstalledOn
+		case ok:
+			status.State = domain.MigrationApplied
+			status.InstalledOn = row.InstalledOn
+		case baseline != nil && compareVersions(migration.parts, baseline) <= 0:
+			// the migrations below the Flyway baseline are already in the database
+			status.State = domain.MigrationApplied
+		}
+		delete(rows, migration.version)
+		statuses = append(statuses, status)
+	}
+
+	for _, row := range history {
+		if _, ok := rows[*row.Version]; !ok || row.Type != "SQL" {
+			continue
+		}
+		statuses = append(statuses, domain.MigrationStatus{
+			Version:     *row.Version,
+			Description: row.Description,
+			Script:      row.Script,
+			State:       domain.MigrationMissing,
+			InstalledOn: row.InstalledOn,
+		})
+	}
+	return statuses, nil
+}
+
+// apply execute the migration and save it in the history in the same transaction
+func (m *Migrator) apply(ctx context.Context, conn bun.Conn, migration script) error {
+	return conn.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		start := time.Now()
+		// the scripts are executed without the bun formatting, so their ? are not placeholders
+		if _, err := tx.Tx.ExecContext(ctx, migration.sql); err != nil {
+			return err
+		}
+		executionTime := time.Since(start).Milliseconds()
+
+		_, err := tx.ExecContext(ctx, `INSERT INTO `+HistoryTable+`
+			(installed_rank, version, description, type, script, checksum, installed_by, execution_time, success)
+			SELECT coalesce(max(installed_rank), 0) + 1, ?, ?, 'SQL', ?, ?, current_user, ?, true FROM `+HistoryTable,
+			migration.version, migration.description, migration.name, migration.checksum, executionTime)
+		if err != nil {
+			return err
+		}
+		m.log.Infof("Migration %s applied in %dms", migration.name, executionTime)
+		return nil
+	})
+}
+
+// validate check the history before migrating: the failed migrations need a manual repair, the applied migrations
+// can not change and the pending migrations can not be older than the last applied migration
+func validate(statuses []domain.MigrationStatus) error {
+	lastApplied := -1
+	for index, status := range statuses {
+		switch status.State {
+		case domain.MigrationFailed:
+			return fmt.Errorf("migration %s failed, repair the schema history", status.Script)
+		case domain.MigrationChanged:
+			return fmt.Errorf("migration %s was changed after it was applied", status.Script)
+		case domain.MigrationApplied, domain.MigrationMissing:
+			lastApplied = index
+		}
+	}
+	for _, status := range statuses[:lastApplied+1] {
+		if status.State == domain.MigrationPending {
+			return fmt.Errorf("migration %s is older than the last applied migration %s", status.Script, statuses[lastApplied].Script)
+		}
+	}
+	return nil
+}
+
+// loadScripts the SQL scripts of the prefix in version order, the scripts can be in sub directories
+func loadScripts(files fs.FS, prefix string) ([]script, error) {
+	var scripts []script
+	versions := map[string][]int{}
+	err := fs.WalkDir(files, ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		name := path.Base(filePath)
+		match := scriptName.FindStringSubmatch(name)
+		if match == nil || match[1] != prefix {
+			return nil
+		}
+		content, err := fs.ReadFile(files, filePath)
+		if err != nil {
+			return err
+		}
+
+		version := strings.ReplaceAll(match[2], "_", ".")
+		parts := versionParts(version)
+		for other, otherParts := range versions {
+			if compareVersions(parts, otherParts) == 0 {
+				return fmt.Errorf("migrations %s and %s have the same version %s", other, name, version)
+			}
+		}
+		versions[name] = parts
+		scripts = append(scripts, script{
+			version:     version,
+			parts:       parts,
+			description: strings.ReplaceAll(match[3], "_", " "),
+			name:        name,
+			sql:         string(content),
+			checksum:    Checksum(content),
+		})
+		return nil
+	})
+	sort.Slice(scripts, func(i, j int) bool {
+		return compareVersions(scripts[i].parts, scripts[j].parts) < 0
+	})
+	return scripts, err
+}
+
+// Checksum the Flyway checksum of the script: the CRC32 of its lines without the line endings and the byte order mark
+func Checksum(content []byte) int32 {
+	content = bytes.TrimPrefix(content, []byte("\xEF\xBB\xBF"))
+	hash := crc32.NewIEEE()
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	scanner.Split(scanLines)
+	for scanner.Scan() {
+		_, _ = hash.Write(scanner.Bytes())
+	}
+	return int32(hash.Sum32())
+}
+
+// scanLines split the lines ended by \n, \r\n or \r like the Java readers
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if index := bytes.IndexAny(data, "\r\n"); index >= 0 {
+		if data[index] == '\n' {
+			return index + 1, data[:index], nil
+		}
+		if index+1 < len(data) {
+			if data[index+1] == '\n' {
+				return index + 2, data[:index], nil
+			}
+			return index + 1, data[:index], nil
+		}
+		if atEOF {
+			return index + 1, data[:index], nil
+		}
+		// a \r at the end of the buffer can be followed by a \n
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// versionParts the numbers of the dotted version
+func versionParts(version string) []int {
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		number, _ := strconv.Atoi(part)
+		parts = append(parts, number)
+	}
+	return parts
+}
+
+// compareVersions compare the versions by number, the missing numbers are zeros so 1 and 1.0 are the same version
+func compareVersions(a, b []int) int {
+	for index := 0; index < len(a) || index < len(b); index++ {
+		var left, right int
+		if index < len(a) {
+			left = a[index]
+		}
+		if index < len(b) {
+			right = b[index]
+		}
+		if left != right {
+			if left < right {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
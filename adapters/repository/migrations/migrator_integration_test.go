@@ -0,0 +1,82 @@
+//go:build integration
+
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/resources"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// TestMigrateThrowawayDatabase for test the migrations up and down in a new database of the TEST_DATABASE_DSN server
+func TestMigrateThrowawayDatabase(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	ctx := context.Background()
+	server := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn))), pgdialect.New())
+	defer server.Close()
+
+	name := fmt.Sprintf("migrations_%d", time.Now().UnixNano())
+	_, err := server.ExecContext(ctx, "CREATE DATABASE "+name)
+	require.Nil(t, err)
+	defer server.ExecContext(ctx, "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)")
+
+	databaseURL, err := url.Parse(dsn)
+	require.Nil(t, err)
+	databaseURL.Path = "/" + name
+	db := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(databaseURL.String()))), pgdialect.New())
+	defer db.Close()
+
+	migrator, err := NewMigrator(zap.NewNop().Sugar(), db, resources.DatabaseMigrations, resources.DatabaseRollbacks)
+	require.Nil(t, err)
+
+	// another instance holds the lock, the migration waits for it
+	holder, err := db.Conn(ctx)
+	require.Nil(t, err)
+	_, err = holder.ExecContext(ctx, "SELECT pg_advisory_lock(?)", lockKey)
+	require.Nil(t, err)
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	_, err = migrator.Up(waitCtx)
+	cancel()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = holder.ExecContext(ctx, "SELECT pg_advisory_unlock(?)", lockKey)
+	require.Nil(t, err)
+	require.Nil(t, holder.Close())
+
+	applied, err := migrator.Up(ctx)
+	require.Nil(t, err)
+	assert.Len(t, applied, len(migrator.migrations))
+
+	applied, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := migrator.Status(ctx)
+	assert.Nil(t, err)
+	for _, status := range statuses {
+		assert.Equal(t, domain.MigrationApplied, status.State, status.Script)
+	}
+
+	reverted, err := migrator.Down(ctx, len(migrator.migrations))
+	require.Nil(t, err)
+	assert.Len(t, reverted, len(migrator.migrations))
+
+	applied, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, len(migrator.migrations))
+}
@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/resources"
+	"hash/crc32"
+	"testing"
+	"testing/fstest"
+)
+
+// TestLoadScripts for test the Flyway names and the version order of the scripts
+func TestLoadScripts(t *testing.T) {
+	files := fstest.MapFS{
+		"sql/V1_10__product_tags.sql": {Data: []byte("select 10;")},
+		"sql/V1_2__api_keys.sql":      {Data: []byte("select 2;")},
+		"sql/V1__init.sql":            {Data: []byte("select 1;")},
+		"sql/U1_2__api_keys.sql":      {Data: []byte("select -2;")},
+		"sql/README.md":               {Data: []byte("not a migration")},
+	}
+
+	scripts, err := loadScripts(files, "V")
+	assert.Nil(t, err)
+	assert.Len(t, scripts, 3)
+	assert.Equal(t, "1", scripts[0].version)
+	assert.Equal(t, "1.2", scripts[1].version)
+	assert.Equal(t, "api keys", scripts[1].description)
+	assert.Equal(t, "V1_2__api_keys.sql", scripts[1].name)
+	assert.Equal(t, "1.10", scripts[2].version)
+
+	files["sql/V1_02__duplicated.sql"] = &fstest.MapFile{Data: []byte("select 2;")}
+	_, err = loadScripts(files, "V")
+	assert.NotNil(t, err)
+}
+
+// TestEmbeddedScripts for test that every embedded migration has its undo migration
+func TestEmbeddedScripts(t *testing.T) {
+	migrator, err := NewMigrator(nil, nil, resources.DatabaseMigrations, resources.DatabaseRollbacks)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, migrator.migrations)
+	for _, migration := range migrator.migrations {
+		_, ok := migrator.rollbacks[migration.version]
+		assert.True(t, ok, migration.name)
+	}
+}
+
+// TestChecksum for test that the checksum ignores the line endings and the byte order mark like Flyway
+func TestChecksum(t *testing.T) {
+	expected := int32(crc32.ChecksumIEEE([]byte("create table products (id text);drop table old;")))
+
+	assert.Equal(t, expected, Checksum([]byte("create table products (id text);\ndrop table old;\n")))
+	assert.Equal(t, expected, Checksum([]byte("create table products (id text);\r\ndrop table old;")))
+	assert.Equal(t, expected, Checksum([]byte("\xEF\xBB\xBFcreate table products (id text);\rdrop table old;\r\n")))
+	assert.NotEqual(t, expected, Checksum([]byte("create table products (id text);\n\ndrop table old; ")))
+}
+
+// TestValidate for test the history that can not be migrated
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []string
+		valid  bool
+	}{
+		{"pending", []string{domain.MigrationApplied, domain.MigrationPending, domain.MigrationPending}, true},
+		{"up to date", []string{domain.MigrationApplied, domain.MigrationApplied}, true},
+		{"empty database", []string{domain.MigrationPending}, true},
+		{"failed", []string{domain.MigrationApplied, domain.MigrationFailed}, false},
+		{"changed", []string{domain.MigrationChanged, domain.MigrationPending}, false},
+		{"out of order", []string{domain.MigrationPending, domain.MigrationApplied}, false},
+		{"out of order of missing", []string{domain.MigrationPending, domain.MigrationMissing}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statuses := make([]domain.MigrationStatus, 0, len(test.states))
+			for _, state := range test.states {
+				statuses = append(statuses, domain.MigrationStatus{State: state})
+			}
+			assert.Equal(t, test.valid, validate(statuses) == nil)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package products
 import (
 	"context"
 	"errors"
+	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 	"golang-api-hexagonal/core/domain"
 	"strings"
@@ -22,29 +23,56 @@ func NewProductRepository(db bun.IDB) *ProductRepository {
 	}
 }
 
-// Create a new product
-func (repo *ProductRepository) Create(ctx context.Context, model *domain.ProductModel) (*domain.ProductModel, error) {
-	resp, err := repo.db.NewInsert().Model(model).Exec(ctx)
-	if err != nil {
-		return nil, err
-	}
-	affectedRows, err := resp.RowsAffected()
+// Create a new product in the tenant of the model with its audit entry in the same transaction
+func (repo *ProductRepository) Create(ctx context.Context, model *domain.ProductModel, audit *domain.ProductAuditModel) (*domain.ProductModel, error) {
+	err := repo.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		resp, err := tx.NewInsert().Model(model).Exec(ctx)
+		if err != nil {
+			return err
+		}
+		affectedRows, err := resp.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affectedRows == 0 {
+			return errors.New("no rows inserted")
+		}
+		_, err = tx.NewInsert().Model(audit).Exec(ctx)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	if affectedRows == 0 {
-		return nil, errors.New("no rows inserted")
-	}
 	return model, nil
 }
 
-// ProductAlreadyExist product already exist?
+// CreateBatch create the products of the tenant with their audit entries in a single transaction
+func (repo *ProductRepository) CreateBatch(ctx context.Context, models []*domain.ProductModel, audits []*domain.ProductAuditModel) error {
+	return repo.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		resp, err := tx.NewInsert().Model(&models).Exec(ctx)
+		if err != nil {
+			return err
+		}
+		affectedRows, err := resp.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affectedRows != int64(len(models)) {
+			return errors.New("not all the rows were inserted")
+		}
+		_, err = tx.NewInsert().Model(&audits).Exec(ctx)
+		return err
+	})
+}
+
+// ProductAlreadyExist product already exist in the tenant?
 func (repo *ProductRepository) ProductAlreadyExist(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
 	var product domain.ProductModel
 	repo.lockSelect.RLock()
 
 	err := repo.db.NewSelect().
 		Model((*domain.ProductModel)(nil)).
+		Where("tenant_id = ?", domain.TenantFromContext(ctx)).
 		Where("name = ?", name).
 		Where("unit_type = ?", unitType).
 		Where("unit = ?", unit).
@@ -64,14 +92,19 @@ func (repo *ProductRepository) ProductAlreadyExist(ctx context.Context, name, un
 	return true, nil
 }
 
-// GetProductById get the product by id
+// GetProductById get the product by id, the products of other tenants and the ids that are not UUIDs are not found
 func (repo *ProductRepository) GetProductById(ctx context.Context, productID string) (*domain.ProductModel, error) {
+	ids := productUUIDs([]string{productID})
+	if len(ids) == 0 {
+		return nil, nil
+	}
 	var product domain.ProductModel
 	repo.lockSelect.RLock()
 
 	err := repo.db.NewSelect().
 		Model((*domain.ProductModel)(nil)).
-		Where("id = ?", productID).
+		Where("tenant_id = ?", domain.TenantFromContext(ctx)).
+		Where("id = ?", ids[0]).
 		Scan(ctx, &product)
 
 	repo.lockSelect.RUnlock()
@@ -84,3 +117,163 @@ func (repo *ProductRepository) GetProductById(ctx context.Context, productID str
 
 	return &product, nil
 }
+
+// GetProductsByIds get the products by ids with a single query, the products not found, of other tenants and the ids that are not
+// UUIDs are not returned
+func (repo *ProductRepository) GetProductsByIds(ctx context.Context, productIDs []string) ([]*domain.ProductModel, error) {
+	var products []*domain.ProductModel
+	ids := productUUIDs(productIDs)
+	if len(ids) == 0 {
+		return products, nil
+	}
+	repo.lockSelect.RLock()
+
+	err := repo.db.NewSelect().
+		Model(&products).
+		Where("tenant_id = ?", domain.TenantFromContext(ctx)).
+		Where("id IN (?)", bun.In(ids)).
+		Scan(ctx)
+
+	repo.lockSelect.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// productUUIDs the ids in the UUID format of the id column, the other ids would fail the query so they can not be found
+func productUUIDs(productIDs []string) []string {
+	ids := make([]string, 0, len(productIDs))
+	for _, productID := range productIDs {
+		if id, err := uuid.Parse(productID); err == nil {
+			ids = append(ids, id.String())
+		}
+	}
+	return ids
+}
+
+// Update the product of the tenant only when it has the expected version, with its audit entry in the same transaction.
+// Returns false when the product was modified or not found
+func (repo *ProductRepository) Update(ctx context.Context, model *domain.ProductModel, expectedVersion int64, audit *domain.ProductAuditModel) (bool, error) {
+	updated := false
+	err := repo.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		resp, err := tx.NewUpdate().
+			Model(model).
+			ExcludeColumn("id", "tenant_id", "audit_user", "creation_date").
+			Where("tenant_id = ?", domain.TenantFromContext(ctx)).
+			Where("id = ?", model.ID).
+			Where("version = ?", expectedVersion).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		affectedRows, err := resp.RowsAffected()
+		if err != nil || affectedRows != 1 {
+			return err
+		}
+		updated = true
+		_, err = tx.NewInsert().Model(audit).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return updated, nil
+}
+
+// GetProductHistory get the audit entries of the product in the tenant, newest first, before the given audit id when it is not zero
+func (repo *ProductRepository) GetProductHistory(ctx context.Context, productID string, beforeID int64, limit int) ([]*domain.ProductAuditModel, error) {
+	var history []*domain.ProductAuditModel
+	repo.lockSelect.RLock()
+
+	query := repo.db.NewSelect().
+		Model(&history).
+		Where("tenant_id = ?", domain.TenantFromContext(ctx)).
+		Where("product_id = ?", productID).
+		OrderExpr("id DESC").
+		Limit(limit)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	err := query.Scan(ctx)
+
+	repo.lockSelect.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// ListProducts list the products of the tenant ordered by id, starting after the given id
+func (repo *ProductRepository) ListProducts(ctx context.Context, filter domain.ProductFilter, afterID string, limit int) ([]*domain.ProductModel, error) {
+	var products []*domain.ProductModel
+	repo.lockSelect.RLock()
+
+	query := filtered(ctx, repo.db.NewSelect().Model(&products), filter).
+		OrderExpr("id ASC").
+		Limit(limit)
+	if afterID != "" {
+		query = query.Where("id > ?", afterID)
+	}
+	err := query.Scan(ctx)
+
+	repo.lockSelect.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// CountProducts count the products of the tenant
+func (repo *ProductRepository) CountProducts(ctx context.Context, filter domain.ProductFilter) (int, error) {
+	return filtered(ctx, repo.db.NewSelect().Model((*domain.ProductModel)(nil)), filter).Count(ctx)
+}
+
+// ExportProducts read the products of the tenant ordered by id with a cursor, each product is passed to fn
+// so the products are never loaded together in memory. An error of fn stops the export
+func (repo *ProductRepository) ExportProducts(ctx context.Context, filter domain.ProductFilter, fn func(product *domain.ProductModel) error) error {
+	query := filtered(ctx, repo.db.NewSelect().Model((*domain.ProductModel)(nil)), filter).OrderExpr("id ASC")
+	rows, err := query.Rows(ctx)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		product := &domain.ProductModel{}
+		if err = query.DB().ScanRow(ctx, rows, product); err != nil {
+			return err
+		}
+		if err = fn(product); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// filtered add the tenant and the product filters to the query
+func filtered(ctx context.Context, query *bun.SelectQuery, filter domain.ProductFilter) *bun.SelectQuery {
+	query = query.Where("tenant_id = ?", domain.TenantFromContext(ctx))
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Brand != "" {
+		query = query.Where("brand = ?", filter.Brand)
+	}
+	if filter.Color != "" {
+		query = query.Where("color = ?", filter.Color)
+	}
+	if filter.Style != "" {
+		query = query.Where("style = ?", filter.Style)
+	}
+	if filter.UnitType != "" {
+		query = query.Where("unit_type = ?", filter.UnitType)
+	}
+	if !filter.CreatedSince.IsZero() {
+		query = query.Where("creation_date >= ?", filter.CreatedSince)
+	}
+	return query
+}
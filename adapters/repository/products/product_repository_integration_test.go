@@ -0,0 +1,147 @@
+//go:build integration
+
+package products
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/test/integration"
+	"os"
+	"testing"
+	"time"
+)
+
+var tenantContext = domain.WithTenant(context.Background(), "tenant-a")
+
+func TestMain(m *testing.M) {
+	os.Exit(integration.Run(m))
+}
+
+// newProduct product model of the tenant of the context
+func newProduct(ctx context.Context, name, brand, status string) *domain.ProductModel {
+	return domain.FromProductToProductModel(&domain.Product{Name: name, Description: "description", UnitType: "unit", Unit: "1",
+		Brand: brand, Color: "red", Style: "classic", Status: status}, "john", domain.TenantFromContext(ctx))
+}
+
+// createProduct create the product with its audit entry
+func createProduct(t *testing.T, repository *ProductRepository, ctx context.Context, model *domain.ProductModel) {
+	t.Helper()
+	_, err := repository.Create(ctx, model, domain.NewProductAudit(domain.ProductCreatedAudit, nil, model, "john", "trace"))
+	require.Nil(t, err)
+}
+
+// TestCreateAndGetProduct for test Create, GetProductById and ProductAlreadyExist in the tenant
+func TestCreateAndGetProduct(t *testing.T) {
+	t.Parallel()
+	repository := NewProductRepository(integration.Database(t))
+	model := newProduct(tenantContext, "shoes", "acme", domain.ProductPending)
+	createProduct(t, repository, tenantContext, model)
+
+	product, err := repository.GetProductById(tenantContext, model.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "shoes", product.Name)
+	assert.Equal(t, int64(1), product.Version)
+
+	exist, err := repository.ProductAlreadyExist(tenantContext, "shoes", "unit", "1", "acme", "red", "classic")
+	assert.Nil(t, err)
+	assert.True(t, exist)
+
+	otherTenant := domain.WithTenant(context.Background(), "tenant-b")
+	product, err = repository.GetProductById(otherTenant, model.ID)
+	assert.Nil(t, err)
+	assert.Nil(t, product)
+	product, err = repository.GetProductById(tenantContext, "unknown")
+	assert.Nil(t, err)
+	assert.Nil(t, product)
+	exist, err = repository.ProductAlreadyExist(otherTenant, "shoes", "unit", "1", "acme", "red", "classic")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+}
+
+// TestUpdateProductWithVersion for test Update with the optimistic lock and GetProductHistory
+func TestUpdateProductWithVersion(t *testing.T) {
+	t.Parallel()
+	repository := NewProductRepository(integration.Database(t))
+	model := newProduct(tenantContext, "shoes", "acme", domain.ProductPending)
+	createProduct(t, repository, tenantContext, model)
+
+	before := domain.FromProductModelToProductResponse(model)
+	model.Description = "new description"
+	model.Version = 2
+	model.UpdateDate = time.Now()
+	updated, err := repository.Update(tenantContext, model, 1, domain.NewProductAudit(domain.ProductUpdatedAudit, before, model, "john", "trace"))
+	assert.Nil(t, err)
+	assert.True(t, updated)
+
+	// the product was already updated to the version 2
+	updated, err = repository.Update(tenantContext, model, 1, domain.NewProductAudit(domain.ProductUpdatedAudit, before, model, "john", "trace"))
+	assert.Nil(t, err)
+	assert.False(t, updated)
+
+	history, err := repository.GetProductHistory(tenantContext, model.ID, 0, 10)
+	assert.Nil(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, domain.ProductUpdatedAudit, history[0].Operation)
+	assert.Equal(t, domain.ProductCreatedAudit, history[1].Operation)
+
+	history, err = repository.GetProductHistory(tenantContext, model.ID, history[0].ID, 10)
+	assert.Nil(t, err)
+	assert.Len(t, history, 1)
+}
+
+// TestListCountAndExportProducts for test CreateBatch, GetProductsByIds, ListProducts, CountProducts and ExportProducts with filters
+func TestListCountAndExportProducts(t *testing.T) {
+	t.Parallel()
+	repository := NewProductRepository(integration.Database(t))
+	models := []*domain.ProductModel{
+		newProduct(tenantContext, "shoes", "acme", domain.ProductPending),
+		newProduct(tenantContext, "boots", "acme", domain.ProductPending),
+		newProduct(tenantContext, "hat", "other", domain.ProductPending),
+	}
+	audits := make([]*domain.ProductAuditModel, 0, len(models))
+	for _, model := range models {
+		audits = append(audits, domain.NewProductAudit(domain.ProductCreatedAudit, nil, model, "john", "trace"))
+	}
+	require.Nil(t, repository.CreateBatch(tenantContext, models, audits))
+	createProduct(t, repository, domain.WithTenant(context.Background(), "tenant-b"),
+		newProduct(domain.WithTenant(context.Background(), "tenant-b"), "shoes", "acme", domain.ProductPending))
+
+	found, err := repository.GetProductsByIds(tenantContext, []string{models[0].ID, models[2].ID, uuid.NewString(), "unknown"})
+	assert.Nil(t, err)
+	assert.Len(t, found, 2)
+	found, err = repository.GetProductsByIds(tenantContext, []string{"unknown"})
+	assert.Nil(t, err)
+	assert.Empty(t, found)
+
+	first, err := repository.ListProducts(tenantContext, domain.ProductFilter{}, "", 2)
+	assert.Nil(t, err)
+	require.Len(t, first, 2)
+	next, err := repository.ListProducts(tenantContext, domain.ProductFilter{}, first[1].ID, 2)
+	assert.Nil(t, err)
+	assert.Len(t, next, 1)
+
+	count, err := repository.CountProducts(tenantContext, domain.ProductFilter{Brand: "acme"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	count, err = repository.CountProducts(tenantContext, domain.ProductFilter{CreatedSince: time.Now().Add(time.Hour)})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	var exported []string
+	err = repository.ExportProducts(tenantContext, domain.ProductFilter{}, func(product *domain.ProductModel) error {
+		exported = append(exported, product.ID)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Len(t, exported, 3)
+
+	stop := errors.New("stop")
+	err = repository.ExportProducts(tenantContext, domain.ProductFilter{}, func(product *domain.ProductModel) error {
+		return stop
+	})
+	assert.Equal(t, stop, err)
+}
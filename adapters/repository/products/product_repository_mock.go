@@ -6,25 +6,65 @@ import (
 )
 
 // ProductRepositoryMock product repository mock
-type ProductRepositoryMock struct{}
-
-var (
-	CreateFunc              func(ctx context.Context, model *domain.ProductModel) (*domain.ProductModel, error)
+type ProductRepositoryMock struct {
+	CreateFunc              func(ctx context.Context, model *domain.ProductModel, audit *domain.ProductAuditModel) (*domain.ProductModel, error)
+	CreateBatchFunc         func(ctx context.Context, models []*domain.ProductModel, audits []*domain.ProductAuditModel) error
 	ProductAlreadyExistFunc func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error)
 	GetProductByIdFunc      func(ctx context.Context, productID string) (*domain.ProductModel, error)
-)
+	GetProductsByIdsFunc    func(ctx context.Context, productIDs []string) ([]*domain.ProductModel, error)
+	UpdateFunc              func(ctx context.Context, model *domain.ProductModel, expectedVersion int64, audit *domain.ProductAuditModel) (bool, error)
+	GetProductHistoryFunc   func(ctx context.Context, productID string, beforeID int64, limit int) ([]*domain.ProductAuditModel, error)
+	ListProductsFunc        func(ctx context.Context, filter domain.ProductFilter, afterID string, limit int) ([]*domain.ProductModel, error)
+	CountProductsFunc       func(ctx context.Context, filter domain.ProductFilter) (int, error)
+	ExportProductsFunc      func(ctx context.Context, filter domain.ProductFilter, fn func(product *domain.ProductModel) error) error
+}
 
 // Create is the repository mock for Create func
-func (pr *ProductRepositoryMock) Create(ctx context.Context, model *domain.ProductModel) (*domain.ProductModel, error) {
-	return CreateFunc(ctx, model)
+func (pr *ProductRepositoryMock) Create(ctx context.Context, model *domain.ProductModel, audit *domain.ProductAuditModel) (*domain.ProductModel, error) {
+	return pr.CreateFunc(ctx, model, audit)
+}
+
+// CreateBatch is the repository mock for CreateBatch func
+func (pr *ProductRepositoryMock) CreateBatch(ctx context.Context, models []*domain.ProductModel, audits []*domain.ProductAuditModel) error {
+	return pr.CreateBatchFunc(ctx, models, audits)
 }
 
 // ProductAlreadyExist is the repository mock for ProductAlreadyExist func
 func (pr *ProductRepositoryMock) ProductAlreadyExist(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
-	return ProductAlreadyExistFunc(ctx, name, unitType, unit, brand, color, style)
+	return pr.ProductAlreadyExistFunc(ctx, name, unitType, unit, brand, color, style)
 }
 
 // GetProductById is the repository mock for GetProductById func
 func (pr *ProductRepositoryMock) GetProductById(ctx context.Context, productID string) (*domain.ProductModel, error) {
-	return GetProductByIdFunc(ctx, productID)
+	return pr.GetProductByIdFunc(ctx, productID)
+}
+
+// GetProductsByIds is the repository mock for GetProductsByIds func
+func (pr *ProductRepositoryMock) GetProductsByIds(ctx context.Context, productIDs []string) ([]*domain.ProductModel, error) {
+	return pr.GetProductsByIdsFunc(ctx, productIDs)
+}
+
+// Update is the repository mock for Update func
+func (pr *ProductRepositoryMock) Update(ctx context.Context, model *domain.ProductModel, expectedVersion int64, audit *domain.ProductAuditModel) (bool, error) {
+	return pr.UpdateFunc(ctx, model, expectedVersion, audit)
+}
+
+// GetProductHistory is the repository mock for GetProductHistory func
+func (pr *ProductRepositoryMock) GetProductHistory(ctx context.Context, productID string, beforeID int64, limit int) ([]*domain.ProductAuditModel, error) {
+	return pr.GetProductHistoryFunc(ctx, productID, beforeID, limit)
+}
+
+// ListProducts is the repository mock for ListProducts func
+func (pr *ProductRepositoryMock) ListProducts(ctx context.Context, filter domain.ProductFilter, afterID string, limit int) ([]*domain.ProductModel, error) {
+	return pr.ListProductsFunc(ctx, filter, afterID, limit)
+}
+
+// CountProducts is the repository mock for CountProducts func
+func (pr *ProductRepositoryMock) CountProducts(ctx context.Context, filter domain.ProductFilter) (int, error) {
+	return pr.CountProductsFunc(ctx, filter)
+}
+
+// ExportProducts is the repository mock for ExportProducts func
+func (pr *ProductRepositoryMock) ExportProducts(ctx context.Context, filter domain.ProductFilter, fn func(product *domain.ProductModel) error) error {
+	return pr.ExportProductsFunc(ctx, filter, fn)
 }
@@ -0,0 +1,75 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"golang-api-hexagonal/core/domain"
+	"strings"
+	"time"
+)
+
+// UserRepository repository implementation for users
+type UserRepository struct {
+	db bun.IDB
+}
+
+// NewUserRepository creates a new user repository instance
+func NewUserRepository(db bun.IDB) *UserRepository {
+	return &UserRepository{
+		db: db,
+	}
+}
+
+// Create a new user
+func (repo *UserRepository) Create(ctx context.Context, model *domain.UserModel) (*domain.UserModel, error) {
+	resp, err := repo.db.NewInsert().Model(model).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	affectedRows, err := resp.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affectedRows == 0 {
+		return nil, errors.New("no rows inserted")
+	}
+	return model, nil
+}
+
+// GetUserByUsername get the user by username
+func (repo *UserRepository) GetUserByUsername(ctx context.Context, username string) (*domain.UserModel, error) {
+	var user domain.UserModel
+
+	err := repo.db.NewSelect().
+		Model(&user).
+		Where("username = ?", username).
+		Scan(ctx)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// UpdateRoles replace the roles of the user, returns false when the user does not exist
+func (repo *UserRepository) UpdateRoles(ctx context.Context, username string, roles []string) (bool, error) {
+	resp, err := repo.db.NewUpdate().
+		Model((*domain.UserModel)(nil)).
+		Set("roles = ?", pgdialect.Array(roles)).
+		Set("update_date = ?", time.Now()).
+		Where("username = ?", username).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	affectedRows, err := resp.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affectedRows > 0, nil
+}
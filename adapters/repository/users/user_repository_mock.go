@@ -0,0 +1,28 @@
+package users
+
+import (
+	"context"
+	"golang-api-hexagonal/core/domain"
+)
+
+// UserRepositoryMock user repository mock
+type UserRepositoryMock struct {
+	CreateFunc            func(ctx context.Context, model *domain.UserModel) (*domain.UserModel, error)
+	GetUserByUsernameFunc func(ctx context.Context, username string) (*domain.UserModel, error)
+	UpdateRolesFunc       func(ctx context.Context, username string, roles []string) (bool, error)
+}
+
+// Create is the repository mock for Create func
+func (ur *UserRepositoryMock) Create(ctx context.Context, model *domain.UserModel) (*domain.UserModel, error) {
+	return ur.CreateFunc(ctx, model)
+}
+
+// GetUserByUsername is the repository mock for GetUserByUsername func
+func (ur *UserRepositoryMock) GetUserByUsername(ctx context.Context, username string) (*domain.UserModel, error) {
+	return ur.GetUserByUsernameFunc(ctx, username)
+}
+
+// UpdateRoles is the repository mock for UpdateRoles func
+func (ur *UserRepositoryMock) UpdateRoles(ctx context.Context, username string, roles []string) (bool, error) {
+	return ur.UpdateRolesFunc(ctx, username, roles)
+}
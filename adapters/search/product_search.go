@@ -0,0 +1,205 @@
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/uptrace/bun"
+	"golang-api-hexagonal/core/domain"
+	"time"
+)
+
+// rankExpr relevance of the product for the search text, the name has more weight than the description
+const rankExpr = "ts_rank(search_vector, websearch_to_tsquery('english', ?))::float8"
+
+// facetColumns columns of the facet fields
+var facetColumns = map[string]string{
+	"brand":    "brand",
+	"color":    "color",
+	"style":    "style",
+	"unitType": "unit_type",
+	"status":   "status",
+}
+
+// productSearchRow product found with its relevance
+type productSearchRow struct {
+	domain.ProductModel `bun:",extend"`
+	Rank                float64 `bun:"rank,scanonly"`
+}
+
+// searchCursor position after the last product of a page, the sort value and id of the product
+type searchCursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    string      `json:"id"`
+}
+
+// PostgresProductSearch product search with the Postgres full text search on the name and description
+type PostgresProductSearch struct {
+	db bun.IDB
+}
+
+// NewPostgresProductSearch creates a new Postgres product search
+func NewPostgresProductSearch(db bun.IDB) *PostgresProductSearch {
+	return &PostgresProductSearch{
+		db: db,
+	}
+}
+
+// SearchProducts search a page of products of the tenant sorted by relevance, name or creation date, with the facet counts
+func (ps *PostgresProductSearch) SearchProducts(ctx context.Context, query domain.ProductSearchQuery) (*domain.ProductSearchResult, error) {
+	sort := query.Sort
+	if sort == "" || (sort == domain.SortByRelevance && query.Text == "") {
+		sort = domain.SortByCreationDateDesc
+		if query.Text != "" {
+			sort = domain.SortByRelevance
+		}
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = domain.DefaultProductSearchLimit
+	}
+
+	var rows []*productSearchRow
+	selectQuery := ps.filtered(ctx, ps.db.NewSelect().Model(&rows).ColumnExpr("?TableColumns"), query, "")
+	sortExpr, direction, sortArgs := ps.sortExpr(sort, query.Text)
+	if sort == domain.SortByRelevance {
+		selectQuery = selectQuery.ColumnExpr(rankExpr+" AS rank", query.Text)
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor, sort)
+		if err != nil {
+			return nil, err
+		}
+		operator := ">"
+		if direction == "DESC" {
+			operator = "<"
+		}
+		selectQuery = selectQuery.Where(fmt.Sprintf("(%s, id) %s (?, ?)", sortExpr, operator), append(sortArgs, cursor.Value, cursor.ID)...)
+	}
+	err := selectQuery.
+		OrderExpr(fmt.Sprintf("%s %s, id %s", sortExpr, direction, direction), sortArgs...).
+		Limit(limit + 1).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.ProductSearchResult{Products: make([]*domain.ProductModel, 0, len(rows))}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		if result.NextCursor, err = encodeCursor(sort, rows[limit-1]); err != nil {
+			return nil, err
+		}
+	}
+	for _, row := range rows {
+		product := row.ProductModel
+		result.Products = append(result.Products, &product)
+	}
+
+	if result.Facets, err = ps.facets(ctx, query); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// facets count the products by value of each facet field, with the filters of the other fields
+func (ps *PostgresProductSearch) facets(ctx context.Context, query domain.ProductSearchQuery) (map[string][]domain.FacetCount, error) {
+	facets := map[string][]domain.FacetCount{}
+	for _, field := range domain.ProductFacets {
+		counts := []domain.FacetCount{}
+		err := ps.filtered(ctx, ps.db.NewSelect().Model((*domain.ProductModel)(nil)), query, field).
+			ColumnExpr("?::text AS value", bun.Ident(facetColumns[field])).
+			ColumnExpr("count(*) AS count").
+			GroupExpr("?", bun.Ident(facetColumns[field])).
+			OrderExpr(`"count" DESC, "value" ASC`).
+			Limit(domain.MaxFacetValues).
+			Scan(ctx, &counts)
+		if err != nil {
+			return nil, err
+		}
+		facets[field] = counts
+	}
+	return facets, nil
+}
+
+// filtered add the tenant, text and facet filters to the query, except the filter of the excluded facet
+func (ps *PostgresProductSearch) filtered(ctx context.Context, selectQuery *bun.SelectQuery, query domain.ProductSearchQuery, excludedFacet string) *bun.SelectQuery {
+	selectQuery = selectQuery.Where("tenant_id = ?", domain.TenantFromContext(ctx))
+	if query.Text != "" {
+		selectQuery = selectQuery.Where("search_vector @@ websearch_to_tsquery('english', ?)", query.Text)
+	}
+	filters := query.Filters()
+	for _, field := range domain.ProductFacets {
+		if value := filters[field]; value != "" && field != excludedFacet {
+			selectQuery = selectQuery.Where("? = ?", bun.Ident(facetColumns[field]), value)
+		}
+	}
+	return selectQuery
+}
+
+// sortExpr expression, direction and arguments of the sort
+func (ps *PostgresProductSearch) sortExpr(sort, text string) (string, string, []interface{}) {
+	switch sort {
+	case domain.SortByRelevance:
+		return rankExpr, "DESC", []interface{}{text}
+	case domain.SortByName:
+		return "name", "ASC", nil
+	case domain.SortByNameDesc:
+		return "name", "DESC", nil
+	case domain.SortByCreationDate:
+		return "creation_date", "ASC", nil
+	default:
+		return "creation_date", "DESC", nil
+	}
+}
+
+// encodeCursor the cursor after the product
+func encodeCursor(sort string, row *productSearchRow) (string, error) {
+	cursor := searchCursor{Sort: sort, ID: row.ID}
+	switch sort {
+	case domain.SortByRelevance:
+		cursor.Value = row.Rank
+	case domain.SortByName, domain.SortByNameDesc:
+		cursor.Value = row.Name
+	default:
+		cursor.Value = row.CreationDate.Format(time.RFC3339Nano)
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor the cursor of the sort, the sort value is converted to the type of the sort column
+func decodeCursor(value, sort string) (*searchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, domain.ErrInvalidSearchCursor
+	}
+	cursor := &searchCursor{}
+	if json.Unmarshal(data, cursor) != nil || cursor.Sort != sort || cursor.ID == "" {
+		return nil, domain.ErrInvalidSearchCursor
+	}
+
+	switch sort {
+	case domain.SortByRelevance:
+		if _, ok := cursor.Value.(float64); !ok {
+			return nil, domain.ErrInvalidSearchCursor
+		}
+	case domain.SortByName, domain.SortByNameDesc:
+		if _, ok := cursor.Value.(string); !ok {
+			return nil, domain.ErrInvalidSearchCursor
+		}
+	default:
+		text, _ := cursor.Value.(string)
+		creationDate, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return nil, domain.ErrInvalidSearchCursor
+		}
+		cursor.Value = creationDate
+	}
+	return cursor, nil
+}
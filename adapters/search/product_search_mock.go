@@ -0,0 +1,16 @@
+package search
+
+import (
+	"context"
+	"golang-api-hexagonal/core/domain"
+)
+
+// ProductSearchMock product search mock
+type ProductSearchMock struct {
+	SearchProductsFunc func(ctx context.Context, query domain.ProductSearchQuery) (*domain.ProductSearchResult, error)
+}
+
+// SearchProducts is the search mock for SearchProducts func
+func (ps *ProductSearchMock) SearchProducts(ctx context.Context, query domain.ProductSearchQuery) (*domain.ProductSearchResult, error) {
+	return ps.SearchProductsFunc(ctx, query)
+}
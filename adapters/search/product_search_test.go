@@ -0,0 +1,46 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/core/domain"
+	"testing"
+	"time"
+)
+
+// TestSearchCursor for test the cursors of each sort
+func TestSearchCursor(t *testing.T) {
+	creationDate := time.Date(2024, 1, 2, 15, 4, 5, 123456000, time.UTC)
+	row := &productSearchRow{ProductModel: domain.ProductModel{ID: "6a1b", Name: "red shoes", CreationDate: creationDate}, Rank: 0.0607927}
+
+	tests := []struct {
+		sort  string
+		value interface{}
+	}{
+		{domain.SortByRelevance, 0.0607927},
+		{domain.SortByName, "red shoes"},
+		{domain.SortByNameDesc, "red shoes"},
+		{domain.SortByCreationDate, creationDate},
+		{domain.SortByCreationDateDesc, creationDate},
+	}
+	for _, test := range tests {
+		t.Run(test.sort, func(t *testing.T) {
+			encoded, err := encodeCursor(test.sort, row)
+			assert.Nil(t, err)
+
+			cursor, err := decodeCursor(encoded, test.sort)
+			assert.Nil(t, err)
+			assert.Equal(t, test.value, cursor.Value)
+			assert.Equal(t, "6a1b", cursor.ID)
+		})
+	}
+}
+
+// TestSearchCursorOfAnotherSort for test the invalid cursors
+func TestSearchCursorOfAnotherSort(t *testing.T) {
+	encoded, _ := encodeCursor(domain.SortByName, &productSearchRow{ProductModel: domain.ProductModel{ID: "6a1b", Name: "red shoes"}})
+
+	_, err := decodeCursor(encoded, domain.SortByRelevance)
+	assert.Equal(t, domain.ErrInvalidSearchCursor, err)
+	_, err = decodeCursor("not a cursor", domain.SortByName)
+	assert.Equal(t, domain.ErrInvalidSearchCursor, err)
+}
@@ -2,14 +2,23 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"golang-api-hexagonal/adapters/api/controller"
 	middleware2 "golang-api-hexagonal/adapters/api/middleware"
 	"golang-api-hexagonal/adapters/api/router"
+	"golang-api-hexagonal/adapters/cli"
 	"golang-api-hexagonal/adapters/kafka"
+	"golang-api-hexagonal/adapters/oidc"
 	"golang-api-hexagonal/adapters/opa"
+	"golang-api-hexagonal/adapters/repository/apikeys"
+	"golang-api-hexagonal/adapters/repository/migrations"
 	"golang-api-hexagonal/adapters/repository/products"
+	"golang-api-hexagonal/adapters/repository/users"
+	"golang-api-hexagonal/adapters/search"
 	"golang-api-hexagonal/config"
 	"golang-api-hexagonal/core/services"
+	"golang-api-hexagonal/resources"
+	"os"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -23,14 +32,75 @@ func main() {
 	database := config.NewDatabaseConnection(logger, configs.DB)
 	defer config.CloseDatabaseConnection(database)
 
-	// Opa Policies
-	policies := opa.NewPolicyService(configs.Policies.Path, logger)
+	// Embedded database migrations, their connection without the read timeout of the pool is only opened while migrating
+	migrate := func(run func(migrator *migrations.Migrator) error) error {
+		migrationDatabase := config.NewMigrationDatabaseConnection(logger, configs.DB)
+		defer config.CloseDatabaseConnection(migrationDatabase)
+		migrator, err := migrations.NewMigrator(logger, migrationDatabase, resources.DatabaseMigrations, resources.DatabaseRollbacks)
+		if err != nil {
+			return fmt.Errorf("invalid database migrations: %w", err)
+		}
+		return run(migrator)
+	}
 
 	// Redis
 	redisCache := config.NewRedisCache(logger, configs.Redis)
 
 	// Repositories
 	productsRepository := products.NewProductRepository(database)
+	usersRepository := users.NewUserRepository(database)
+	apiKeysRepository := apikeys.NewApiKeyRepository(database)
+	productSearch := search.NewPostgresProductSearch(database)
+
+	// Cache warm up and users
+	cacheWarmService := services.NewCacheWarmService(logger, productsRepository, redisCache)
+	userService := services.NewUserService(logger, usersRepository)
+	valid := validator.New()
+
+	// Command line sub commands, e.g.: go run cmd/main.go cache warm, go run cmd/main.go migrate up
+	// or go run cmd/main.go user create -username=john -roles=user
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "cache":
+			err = cli.NewCacheCommand(logger, cacheWarmService).Run(context.Background(), os.Args[2:])
+		case "migrate":
+			err = migrate(func(migrator *migrations.Migrator) error {
+				return cli.NewMigrateCommand(logger, migrator).Run(context.Background(), os.Args[2:])
+			})
+		case "user":
+			err = cli.NewUserCommand(logger, valid, userService, os.Stdin).Run(context.Background(), os.Args[2:])
+		default:
+			logger.Fatalf("Unknown command: %s", os.Args[1])
+		}
+		if err != nil {
+			logger.Fatalf("Command %s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	if configs.DB.MigrateOnStartup {
+		err := migrate(func(migrator *migrations.Migrator) error {
+			_, err := migrator.Up(context.Background())
+			return err
+		})
+		if err != nil {
+			logger.Fatalf("Fail to migrate the database: %v", err)
+		}
+	}
+
+	if configs.Redis.WarmUp.OnStartup {
+		warmRequest, err := services.NewCacheWarmRequest(configs.Redis.WarmUp)
+		if err != nil {
+			logger.Fatalf("Invalid cache warm up configuration: %v", err)
+		}
+		go func() {
+			_, _ = cacheWarmService.Warm(context.Background(), warmRequest)
+		}()
+	}
+
+	// Metrics
+	prometheusMetrics := middleware2.NewPrometheusMiddleware(configs.Service.Name)
 
 	// Start Kafka Producer and Consumer with a new context
 	ctx := context.Background()
@@ -41,20 +111,42 @@ func main() {
 	defer kafka.CloseConsumer(consumer)
 	go kafka.ConsumeMessages(logger, configs.Kafka, consumer)
 
+	// Opa Policies
+	decisionLogger := opa.NewDecisionLogger(logger, configs.Policies.DecisionLog, producer, configs.Kafka.Producer.AuditTopic)
+	policies := opa.NewPolicyService(configs.Policies, logger, prometheusMetrics, decisionLogger)
+	if configs.Policies.Watch {
+		if err := policies.Watch(context.Background()); err != nil {
+			logger.Errorf("Failed to watch the policies, they can still be reloaded by the admin endpoint: %v", err)
+		}
+	}
+
 	// Config Domain Services
 	productService := services.NewProductService(logger, productsRepository, redisCache, producer, configs.Kafka)
-	authService := services.NewAuthService(logger, configs.Oauth)
+	authService := services.NewAuthService(logger, configs.Oauth, config.NewOauthKeys(logger, configs.Oauth), usersRepository, redisCache)
+	apiKeyService := services.NewApiKeyService(logger, apiKeysRepository)
+	productSearchService := services.NewProductSearchService(logger, productSearch)
+	productImportService := services.NewProductImportService(logger, productsRepository, redisCache, producer, configs.Kafka)
+	productExportService := services.NewProductExportService(logger, productsRepository, redisCache)
 
-	// Metrics
-	prometheusMetrics := middleware2.NewPrometheusMiddleware(configs.Service.Name)
-	jwtHandler := middleware2.NewJWTHandler(logger, authService)
+	// Token verification
+	tokenVerifier, err := oidc.NewIssuerVerifier(logger, authService, configs.OIDC)
+	if err != nil {
+		logger.Fatalf("Invalid OIDC configuration: %v", err)
+	}
+	jwtHandler := middleware2.NewJWTHandler(logger, tokenVerifier, apiKeyService)
 
 	// Config Http Routers and Controllers
-	route := router.NewHTTPRouter(prometheusMetrics)
-	valid := validator.New()
+	rateLimiter := config.NewRateLimiter(logger, configs.RateLimit, redisCache)
+	route := router.NewHTTPRouter(prometheusMetrics, rateLimiter)
 	controller.NewHealthCheckController(route, prometheusMetrics)
 	controller.NewAuthController(route, logger, valid, authService)
-	controller.NewProductController(route, logger, valid, prometheusMetrics, productService, jwtHandler, policies)
+	idempotencyHandler := middleware2.NewIdempotencyHandler(logger, redisCache)
+	controller.NewProductController(route, logger, valid, prometheusMetrics, productService, jwtHandler, idempotencyHandler, policies)
+	controller.NewProductSearchController(route, logger, valid, productSearchService, jwtHandler, policies)
+	controller.NewProductImportController(route, logger, valid, productImportService, jwtHandler, policies)
+	controller.NewProductExportController(route, logger, productExportService, jwtHandler, policies)
+	controller.NewApiKeyController(route, logger, valid, apiKeyService, jwtHandler, policies)
+	controller.NewPolicyController(route, logger, jwtHandler, policies)
 
 	config.StartHttpServer(logger, configs.Server, route)
 }
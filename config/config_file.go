@@ -9,13 +9,15 @@ import (
 
 // Configurations Configurations from config file
 type Configurations struct {
-	Server   ServerConfigurations   `yaml:"server"`
-	Service  ServiceConfigurations  `yaml:"service"`
-	DB       DatabaseConfigurations `yaml:"database"`
-	Kafka    KafkaConfiguration     `yaml:"kafka"`
-	Redis    RedisConfiguration     `yaml:"redis"`
-	Oauth    Oauth                  `yaml:"oauth"`
-	Policies PoliciesConfiguration  `yaml:"policies"`
+	Server    ServerConfigurations   `yaml:"server"`
+	Service   ServiceConfigurations  `yaml:"service"`
+	DB        DatabaseConfigurations `yaml:"database"`
+	Kafka     KafkaConfiguration     `yaml:"kafka"`
+	Redis     RedisConfiguration     `yaml:"redis"`
+	Oauth     Oauth                  `yaml:"oauth"`
+	OIDC      OIDCConfiguration      `yaml:"oidc"`
+	Policies  PoliciesConfiguration  `yaml:"policies"`
+	RateLimit RateLimitConfiguration `yaml:"rate-limit"`
 }
 
 // ServerConfigurations Server configurations
@@ -23,6 +25,23 @@ type ServerConfigurations struct {
 	Port string `yaml:"port"`
 }
 
+// RateLimitConfiguration rate limit of the clients, the routes without rule use the default rule
+type RateLimitConfiguration struct {
+	Enabled bool            `yaml:"enabled"`
+	Default RateLimitRule   `yaml:"default"`
+	Routes  []RateLimitRule `yaml:"routes"`
+}
+
+// RateLimitRule limit of requests by period for a route pattern, an empty method applies to every method,
+// the burst is the number of requests allowed at once and defaults to the requests
+type RateLimitRule struct {
+	Method        string `yaml:"method"`
+	Pattern       string `yaml:"pattern"`
+	Requests      int    `yaml:"requests"`
+	PeriodSeconds int    `yaml:"period-seconds"`
+	Burst         int    `yaml:"burst"`
+}
+
 // ServiceConfigurations Service configurations
 type ServiceConfigurations struct {
 	Name string `yaml:"name"`
@@ -39,6 +58,10 @@ type DatabaseConfigurations struct {
 	Insecure bool   `yaml:"insecure"`
 	Pool     int    `yaml:"pool"`
 	Timeout  int    `yaml:"timeout"`
+	// MigrateOnStartup apply the pending migrations before serving, the parallel instances wait for the migration lock
+	MigrateOnStartup bool `yaml:"migrate-on-startup"`
+	// MigrationTimeout read timeout in minutes of the migration statements, 0 is without timeout
+	MigrationTimeout int `yaml:"migration-timeout"`
 }
 
 // KafkaConfiguration kafka connection and producer configuration
@@ -55,20 +78,32 @@ type KafkaConfiguration struct {
 
 // RedisConfiguration redis connection configuration
 type RedisConfiguration struct {
-	Localhost        bool   `yaml:"localhost"`
-	URL              string `yaml:"url"`
-	User             string `yaml:"user"`
-	Pass             string `yaml:"pass"`
-	DB               int    `yaml:"db"`
-	PublicKeyFile    string `yaml:"public-key-file"`
-	PrivateKeyFile   string `yaml:"private-key-file"`
-	CaCertFile       string `yaml:"ca-cert-file"`
-	TimeOutInSeconds int64  `yaml:"time-out-in-seconds"`
+	Localhost        bool                     `yaml:"localhost"`
+	URL              string                   `yaml:"url"`
+	User             string                   `yaml:"user"`
+	Pass             string                   `yaml:"pass"`
+	DB               int                      `yaml:"db"`
+	PublicKeyFile    string                   `yaml:"public-key-file"`
+	PrivateKeyFile   string                   `yaml:"private-key-file"`
+	CaCertFile       string                   `yaml:"ca-cert-file"`
+	TimeOutInSeconds int64                    `yaml:"time-out-in-seconds"`
+	WarmUp           CacheWarmUpConfiguration `yaml:"warm-up"`
+}
+
+// CacheWarmUpConfiguration cache warm up configuration
+type CacheWarmUpConfiguration struct {
+	OnStartup     bool   `yaml:"on-startup"`
+	Tenant        string `yaml:"tenant"`
+	PageSize      int    `yaml:"page-size"`
+	RatePerSecond int    `yaml:"rate-per-second"`
+	Status        string `yaml:"status"`
+	CreatedSince  string `yaml:"created-since"`
 }
 
 // KafkaProducerConfiguration kafka producer configuration
 type KafkaProducerConfiguration struct {
 	ProductTopic string `yaml:"product-topic-event"`
+	AuditTopic   string `yaml:"audit-topic-event"`
 }
 
 // KafkaConsumerConfiguration kafka consumer configuration
@@ -78,14 +113,69 @@ type KafkaConsumerConfiguration struct {
 	MaxRecords int      `yaml:"max-records"`
 }
 
-// Oauth secret key
+// Oauth token signing keys and lifetimes
 type Oauth struct {
-	Secret string `yaml:"secret"`
+	Secret             string     `yaml:"secret"`
+	Algorithm          string     `yaml:"algorithm"`
+	SigningKeyID       string     `yaml:"signing-key-id"`
+	Keys               []OauthKey `yaml:"keys"`
+	AccessTokenMinutes int        `yaml:"access-token-minutes"`
+	RefreshTokenHours  int        `yaml:"refresh-token-hours"`
+}
+
+// OauthKey asymmetric key pair, the private key is only required for the signing key
+type OauthKey struct {
+	ID             string `yaml:"id"`
+	PrivateKeyFile string `yaml:"private-key-file"`
+	PublicKeyFile  string `yaml:"public-key-file"`
+}
+
+// OIDCConfiguration trusted external OIDC identity providers
+type OIDCConfiguration struct {
+	Issuers []OIDCIssuerConfiguration `yaml:"issuers"`
+}
+
+// OIDCIssuerConfiguration trusted OIDC issuer, its audience and the claim paths mapped to the auth claims
+type OIDCIssuerConfiguration struct {
+	Issuer             string `yaml:"issuer"`
+	Audience           string `yaml:"audience"`
+	ClockSkewSeconds   int    `yaml:"clock-skew-seconds"`
+	JWKSRefreshMinutes int    `yaml:"jwks-refresh-minutes"`
+	UsernameClaim      string `yaml:"username-claim"`
+	RolesClaim         string `yaml:"roles-claim"`
+	ScopesClaim        string `yaml:"scopes-claim"`
+	TenantClaim        string `yaml:"tenant-claim"`
 }
 
 // PoliciesConfiguration policies configuration
 type PoliciesConfiguration struct {
-	Path string `yaml:"path"`
+	Path        string                      `yaml:"path"`
+	Watch       bool                        `yaml:"watch"`
+	Bundle      PoliciesBundleConfiguration `yaml:"bundle"`
+	DecisionLog DecisionLogConfiguration    `yaml:"decision-log"`
+}
+
+// DecisionLogConfiguration policy decision log configuration, the sink is stdout, kafka or none
+type DecisionLogConfiguration struct {
+	Sink       string   `yaml:"sink"`
+	Redact     []string `yaml:"redact"`
+	BufferSize int      `yaml:"buffer-size"`
+}
+
+// PoliciesBundleConfiguration OPA bundle configuration, the bundle replaces the policies path when it is defined
+type PoliciesBundleConfiguration struct {
+	URL            string                   `yaml:"url"`
+	Path           string                   `yaml:"path"`
+	PollingSeconds int                      `yaml:"polling-seconds"`
+	Verification   BundleVerificationConfig `yaml:"verification"`
+}
+
+// BundleVerificationConfig public key to verify the bundle signature, the bundles are not verified without key id
+type BundleVerificationConfig struct {
+	KeyID         string `yaml:"key-id"`
+	PublicKeyFile string `yaml:"public-key-file"`
+	Algorithm     string `yaml:"algorithm"`
+	Scope         string `yaml:"scope"`
 }
 
 // LoadConfigFile Load the yml config file and environment variables
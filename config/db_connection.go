@@ -21,13 +21,7 @@ import (
 //			pgdriver.WithWriteTimeout(time.Duration(config.Timeout)*time.Second),
 //		))
 func NewDatabaseConnection(log *zap.SugaredLogger, config DatabaseConfigurations) *bun.DB {
-	sqlDB := sql.OpenDB(pgdriver.NewConnector(
-		pgdriver.WithAddr(config.Addr+":"+config.Port),
-		pgdriver.WithUser(config.User),
-		pgdriver.WithPassword(config.Pass),
-		pgdriver.WithDatabase(config.DBName),
-		pgdriver.WithInsecure(config.Insecure),
-	))
+	sqlDB := sql.OpenDB(databaseConnector(config))
 
 	sqlDB.SetMaxOpenConns(config.Pool)
 	sqlDB.SetMaxIdleConns(config.Pool)
@@ -45,6 +39,31 @@ func NewDatabaseConnection(log *zap.SugaredLogger, config DatabaseConfigurations
 	return db
 }
 
+// NewMigrationDatabaseConnection Initializes the connection of the database migrations, the migration statements can be longer
+// than the read timeout of the pool so their read timeout is the migration timeout. The connection is not kept idle
+func NewMigrationDatabaseConnection(log *zap.SugaredLogger, config DatabaseConfigurations) *bun.DB {
+	sqlDB := sql.OpenDB(databaseConnector(config, pgdriver.WithReadTimeout(time.Duration(config.MigrationTimeout)*time.Minute)))
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetMaxIdleConns(0)
+
+	err := sqlDB.Ping()
+	if err != nil {
+		log.Fatalf("Fail to connect to DB: %v", err)
+	}
+	return bun.NewDB(sqlDB, pgdialect.New())
+}
+
+// databaseConnector postgres connector of the configuration with the extra options
+func databaseConnector(config DatabaseConfigurations, options ...pgdriver.Option) *pgdriver.Connector {
+	return pgdriver.NewConnector(append([]pgdriver.Option{
+		pgdriver.WithAddr(config.Addr + ":" + config.Port),
+		pgdriver.WithUser(config.User),
+		pgdriver.WithPassword(config.Pass),
+		pgdriver.WithDatabase(config.DBName),
+		pgdriver.WithInsecure(config.Insecure),
+	}, options...)...)
+}
+
 // CloseDatabaseConnection Close the database connection
 func CloseDatabaseConnection(database *bun.DB) {
 	_ = database.Close()
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/core/domain"
+	"math/big"
+	"os"
+	"path/filepath"
+)
+
+// OauthKeys keys to sign and verify the oauth tokens
+type OauthKeys struct {
+	Method           jwt.SigningMethod
+	SigningKeyID     string
+	SigningKey       interface{}
+	VerificationKeys map[string]interface{}
+}
+
+// NewOauthKeys load the oauth signing and verification keys
+func NewOauthKeys(log *zap.SugaredLogger, config Oauth) *OauthKeys {
+	keys, err := LoadOauthKeys(config)
+	if err != nil {
+		log.Fatalf("Failed to load the oauth keys: %v", err)
+	}
+
+	log.Infof("Oauth keys loaded. Algorithm: %s, signing key: %q, verification keys: %d",
+		keys.Method.Alg(), keys.SigningKeyID, len(keys.VerificationKeys))
+	return keys
+}
+
+// LoadOauthKeys load the oauth keys from the PEM files of the configuration
+func LoadOauthKeys(config Oauth) (*OauthKeys, error) {
+	algorithm := config.Algorithm
+	if algorithm == "" {
+		algorithm = jwt.SigningMethodHS256.Alg()
+	}
+
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil || method == jwt.SigningMethodNone {
+		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+
+	keys := &OauthKeys{
+		Method:           method,
+		SigningKeyID:     config.SigningKeyID,
+		VerificationKeys: map[string]interface{}{},
+	}
+
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		if config.Secret == "" {
+			return nil, errors.New("the oauth secret is required")
+		}
+		keys.SigningKey = []byte(config.Secret)
+		keys.VerificationKeys[config.SigningKeyID] = []byte(config.Secret)
+		return keys, nil
+	}
+
+	for _, key := range config.Keys {
+		if key.ID == "" {
+			return nil, errors.New("the oauth key id is required")
+		}
+
+		var publicKey interface{}
+		if key.PrivateKeyFile != "" {
+			privateKey, err := loadPrivateKey(method, key.PrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", key.ID, err)
+			}
+			if key.ID == config.SigningKeyID {
+				keys.SigningKey = privateKey
+			}
+			publicKey = publicKeyOf(privateKey)
+		}
+		if key.PublicKeyFile != "" {
+			var err error
+			publicKey, err = loadPublicKey(method, key.PublicKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", key.ID, err)
+			}
+		}
+		if publicKey == nil {
+			return nil, fmt.Errorf("key %s: a private or public key file is required", key.ID)
+		}
+		keys.VerificationKeys[key.ID] = publicKey
+	}
+
+	if keys.SigningKey == nil {
+		return nil, fmt.Errorf("the private key of the signing key id %q was not found", config.SigningKeyID)
+	}
+	return keys, nil
+}
+
+// JWKS returns the public verification keys as a JSON web key set
+func (k *OauthKeys) JWKS() *domain.JSONWebKeySet {
+	jwks := &domain.JSONWebKeySet{Keys: []domain.JSONWebKey{}}
+	for kid, key := range k.VerificationKeys {
+		jwk := domain.JSONWebKey{Kid: kid, Use: "sig", Alg: k.Method.Alg()}
+		switch publicKey := key.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (publicKey.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = publicKey.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			// Symmetric secrets are never published
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+func loadPrivateKey(method jwt.SigningMethod, file string) (interface{}, error) {
+	pem, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, err
+	}
+
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return jwt.ParseRSAPrivateKeyFromPEM(pem)
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPrivateKeyFromPEM(pem)
+	case *jwt.SigningMethodEd25519:
+		return jwt.ParseEdPrivateKeyFromPEM(pem)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", method.Alg())
+	}
+}
+
+func loadPublicKey(method jwt.SigningMethod, file string) (interface{}, error) {
+	pem, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, err
+	}
+
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return jwt.ParseRSAPublicKeyFromPEM(pem)
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPublicKeyFromPEM(pem)
+	case *jwt.SigningMethodEd25519:
+		return jwt.ParseEdPublicKeyFromPEM(pem)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", method.Alg())
+	}
+}
+
+func publicKeyOf(privateKey interface{}) interface{} {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &key.PublicKey
+	case *ecdsa.PrivateKey:
+		return &key.PublicKey
+	case ed25519.PrivateKey:
+		return key.Public()
+	default:
+		return nil
+	}
+}
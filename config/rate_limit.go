@@ -0,0 +1,31 @@
+package config
+
+import (
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/api/middleware"
+	"golang-api-hexagonal/core/ports"
+	"time"
+)
+
+// NewRateLimiter create the rate limiter of the configured routes
+func NewRateLimiter(log *zap.SugaredLogger, config RateLimitConfiguration, cache ports.IRedis) *middleware.RateLimiter {
+	rules := make([]middleware.RateLimitRule, 0, len(config.Routes))
+	for _, route := range config.Routes {
+		rules = append(rules, toRateLimitRule(route))
+	}
+
+	if config.Enabled {
+		log.Infof("Rate limit enabled with %d route rules", len(rules))
+	}
+	return middleware.NewRateLimiter(log, cache, config.Enabled, toRateLimitRule(config.Default), rules)
+}
+
+func toRateLimitRule(rule RateLimitRule) middleware.RateLimitRule {
+	return middleware.RateLimitRule{
+		Method:   rule.Method,
+		Pattern:  rule.Pattern,
+		Requests: rule.Requests,
+		Period:   time.Duration(rule.PeriodSeconds) * time.Second,
+		Burst:    rule.Burst,
+	}
+}
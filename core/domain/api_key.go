@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+const (
+	// ApiKeyHeader header with the api key
+	ApiKeyHeader = "X-API-Key"
+	// ApiKeyPrefix prefix of the api keys: ghx_<lookup prefix>_<secret>
+	ApiKeyPrefix = "ghx"
+	// ApiKeySubjectPrefix prefix of the auth claims subject of an api key
+	ApiKeySubjectPrefix = "apikey:"
+)
+
+// ApiKey request api key
+type ApiKey struct {
+	Name      string     `json:"name" validate:"required,not_blank,min=2,max=256"`
+	Roles     []string   `json:"roles" validate:"required,min=1,dive,oneof=admin business user"`
+	ExpiresAt *time.Time `json:"expiresAt" validate:"omitempty"`
+}
+
+// ApiKeyModel api key database model, only the hash of the key secret is stored
+type ApiKeyModel struct {
+	bun.BaseModel `bun:"table:api_keys" json:"-"`
+	ID            string     `bun:"id,pk" json:"id"`
+	Name          string     `bun:"name" json:"name"`
+	Prefix        string     `bun:"prefix" json:"prefix"`
+	KeyHash       string     `bun:"key_hash" json:"-"`
+	Roles         []string   `bun:"roles,array" json:"roles"`
+	TenantID      string     `bun:"tenant_id" json:"tenantId"`
+	AuditUser     string     `bun:"audit_user" json:"auditUser"`
+	ExpiresAt     *time.Time `bun:"expires_at" json:"expiresAt"`
+	RevokedAt     *time.Time `bun:"revoked_at" json:"revokedAt"`
+	CreationDate  time.Time  `bun:"creation_date" json:"creationDate"`
+}
+
+// ApiKeyResponse api key response, the key is only returned when it is created
+type ApiKeyResponse struct {
+	ID           string     `json:"id"`
+	Key          string     `json:"key,omitempty"`
+	Name         string     `json:"name"`
+	Prefix       string     `json:"prefix"`
+	Roles        []string   `json:"roles"`
+	TenantID     string     `json:"tenantId"`
+	AuditUser    string     `json:"auditUser"`
+	ExpiresAt    *time.Time `json:"expiresAt"`
+	RevokedAt    *time.Time `json:"revokedAt"`
+	CreationDate time.Time  `json:"creationDate"`
+}
+
+// FromApiKeyToApiKeyModel convert from Api Key Request to Api Key Database Model
+func FromApiKeyToApiKeyModel(request *ApiKey, prefix, keyHash, auditUser, tenantID string) *ApiKeyModel {
+	return &ApiKeyModel{
+		ID:           uuid.NewString(),
+		Name:         request.Name,
+		Prefix:       prefix,
+		KeyHash:      keyHash,
+		Roles:        request.Roles,
+		TenantID:     tenantID,
+		AuditUser:    auditUser,
+		ExpiresAt:    request.ExpiresAt,
+		CreationDate: time.Now(),
+	}
+}
+
+// FromApiKeyModelToApiKeyResponse convert from Api Key database model to Api Key response
+func FromApiKeyModelToApiKeyResponse(model *ApiKeyModel) *ApiKeyResponse {
+	return &ApiKeyResponse{
+		ID:           model.ID,
+		Name:         model.Name,
+		Prefix:       model.Prefix,
+		Roles:        model.Roles,
+		TenantID:     model.TenantID,
+		AuditUser:    model.AuditUser,
+		ExpiresAt:    model.ExpiresAt,
+		RevokedAt:    model.RevokedAt,
+		CreationDate: model.CreationDate,
+	}
+}
@@ -5,14 +5,78 @@ const (
 	SuperRole   = "admin"
 	ClaimsKey   = "claims"
 	JwtTokenKey = "jwt"
+	TokenType   = "Bearer"
+	// Claim names of the tokens issued by this service
+	SubjectClaim  = "sub"
+	UsernameClaim = "username"
+	RolesClaim    = "roles"
+	ScopeClaim    = "scope"
+	TenantClaim   = "tenant_id"
+	AudienceClaim = "aud"
 )
 
+// Auth user credentials
 type Auth struct {
 	Username string `json:"username" validate:"required,not_blank,min=2,max=256"`
 	Password string `json:"password" validate:"required,not_blank,min=8,max=256"`
 }
 
+// AuthClaims claims of the authenticated token
 type AuthClaims struct {
+	Subject  string   `json:"subject"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+	Scopes   []string `json:"scopes"`
+	TenantID string   `json:"tenantId"`
+	Audience []string `json:"audience"`
+}
+
+// TokenResponse oauth2 token response
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+}
+
+// RefreshTokenRequest request to rotate the refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required,not_blank"`
+}
+
+// RevokeTokenRequest request to revoke an access or refresh token
+type RevokeTokenRequest struct {
+	Token         string `json:"token" validate:"required,not_blank"`
+	TokenTypeHint string `json:"token_type_hint" validate:"omitempty,oneof=access_token refresh_token"`
+}
+
+// RefreshTokenData data stored for each issued refresh token
+type RefreshTokenData struct {
 	Username string `json:"username"`
-	Role     string `json:"role"`
+	FamilyID string `json:"familyId"`
+}
+
+// TokenFamilyData data stored for each refresh token family
+type TokenFamilyData struct {
+	Username        string `json:"username"`
+	AccessTokenID   string `json:"accessTokenId"`
+	AccessExpiresAt int64  `json:"accessExpiresAt"`
+}
+
+// JSONWebKey public key to verify the tokens, see RFC 7517
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet set of public keys to verify the tokens
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
 }
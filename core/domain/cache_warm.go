@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// DefaultCacheWarmPageSize default number of products loaded per page during the cache warm up
+const DefaultCacheWarmPageSize = 500
+
+// ProductFilter filters to select products, the empty fields are not filtered
+type ProductFilter struct {
+	Status       string
+	Brand        string
+	Color        string
+	Style        string
+	UnitType     string
+	CreatedSince time.Time
+}
+
+// CacheWarmRequest cache warm up request, an empty tenant is the default tenant
+type CacheWarmRequest struct {
+	TenantID      string
+	Filter        ProductFilter
+	PageSize      int
+	RatePerSecond int
+}
+
+// CacheWarmResult cache warm up result
+type CacheWarmResult struct {
+	Loaded   int
+	Cached   int
+	Failed   int
+	Pages    int
+	Duration time.Duration
+}
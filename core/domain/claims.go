@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidToken the token signature, format or claims are invalid
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrInvalidTokenIssuer the token issuer is not trusted
+	ErrInvalidTokenIssuer = errors.New("invalid token issuer")
+	// ErrTokenExpired the token expiration is in the past
+	ErrTokenExpired = errors.New("token expired")
+	// ErrTokenRevoked the token id is in the denylist
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+// ClaimError a required token claim is missing or has an unexpected type
+type ClaimError struct {
+	Claim  string
+	Reason string
+}
+
+// Error default func that return the error message
+func (e *ClaimError) Error() string {
+	return fmt.Sprintf("invalid token claim %s: %s", e.Claim, e.Reason)
+}
+
+// Is a claim error is an invalid token error
+func (e *ClaimError) Is(target error) bool {
+	return target == ErrInvalidToken
+}
+
+// ClaimValue returns the claim value of a dot separated path, e.g.: realm_access.roles
+func ClaimValue(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[part]
+	}
+	return value
+}
+
+// StringClaim returns the string claim of the path
+func StringClaim(claims map[string]interface{}, path string, required bool) (string, error) {
+	switch value := ClaimValue(claims, path).(type) {
+	case nil:
+		if required {
+			return "", &ClaimError{Claim: path, Reason: "missing"}
+		}
+		return "", nil
+	case string:
+		if required && value == "" {
+			return "", &ClaimError{Claim: path, Reason: "empty"}
+		}
+		return value, nil
+	default:
+		return "", &ClaimError{Claim: path, Reason: fmt.Sprintf("expected a string, got %T", value)}
+	}
+}
+
+// StringsClaim returns the claim of the path with a list of strings or a space separated string, like the scope claim
+func StringsClaim(claims map[string]interface{}, path string) ([]string, error) {
+	switch values := ClaimValue(claims, path).(type) {
+	case nil:
+		return []string{}, nil
+	case string:
+		return strings.Fields(values), nil
+	case []string:
+		return values, nil
+	case []interface{}:
+		result := make([]string, 0, len(values))
+		for _, item := range values {
+			s, ok := item.(string)
+			if !ok {
+				return nil, &ClaimError{Claim: path, Reason: fmt.Sprintf("expected a list of strings, got an item %T", item)}
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	default:
+		return nil, &ClaimError{Claim: path, Reason: fmt.Sprintf("expected a list of strings, got %T", values)}
+	}
+}
+
+// ClaimPaths dot separated paths of the token claims mapped to the auth claims
+type ClaimPaths struct {
+	Username string
+	Roles    string
+	Scopes   string
+	Tenant   string
+}
+
+// DefaultClaimPaths claim paths of the tokens issued by this service
+var DefaultClaimPaths = ClaimPaths{
+	Username: UsernameClaim,
+	Roles:    RolesClaim,
+	Scopes:   ScopeClaim,
+	Tenant:   TenantClaim,
+}
+
+// NewAuthClaims map the token claims to the auth claims. The username falls back to the subject
+func NewAuthClaims(claims map[string]interface{}, paths ClaimPaths) (*AuthClaims, error) {
+	subject, err := StringClaim(claims, SubjectClaim, false)
+	if err != nil {
+		return nil, err
+	}
+	username, err := StringClaim(claims, paths.Username, false)
+	if err != nil {
+		return nil, err
+	}
+	if username == "" {
+		username = subject
+	}
+	if username == "" {
+		return nil, &ClaimError{Claim: paths.Username, Reason: "missing"}
+	}
+	roles, err := StringsClaim(claims, paths.Roles)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := StringsClaim(claims, paths.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	tenantID, err := StringClaim(claims, paths.Tenant, false)
+	if err != nil {
+		return nil, err
+	}
+	audience, err := StringsClaim(claims, AudienceClaim)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthClaims{
+		Subject:  subject,
+		Username: username,
+		Roles:    roles,
+		Scopes:   scopes,
+		TenantID: tenantID,
+		Audience: audience,
+	}, nil
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// States of the database migrations
+const (
+	MigrationApplied = "applied"
+	MigrationPending = "pending"
+	MigrationFailed  = "failed"
+	MigrationChanged = "changed"
+	MigrationMissing = "missing"
+)
+
+// MigrationStatus state of a database migration, the installed date is zero for the pending migrations.
+// A changed migration was modified after it was applied and a missing migration was applied but is not in the service
+type MigrationStatus struct {
+	Version     string
+	Description string
+	Script      string
+	State       string
+	Undoable    bool
+	InstalledOn time.Time
+}
@@ -0,0 +1,49 @@
+package domain
+
+// PolicyDecisionEventName policy decision kafka event name
+const PolicyDecisionEventName = "policy.decision.event"
+
+// PolicyErrorReason reason of the decisions denied because the policy could not be evaluated
+const PolicyErrorReason = "policy_error"
+
+// Operation operation authorized by the policy
+type Operation string
+
+// Operations of the authorization policy
+const (
+	CreateProductOperation       Operation = "createProduct"
+	ViewProductOperation         Operation = "viewProduct"
+	UpdateProductOperation       Operation = "updateProduct"
+	ChangeProductStatusOperation Operation = "changeProductStatus"
+	ViewProductHistoryOperation  Operation = "viewProductHistory"
+	SearchProductsOperation      Operation = "searchProducts"
+	ImportProductsOperation      Operation = "importProducts"
+	ExportProductsOperation      Operation = "exportProducts"
+	ManageApiKeysOperation       Operation = "manageApiKeys"
+	ReloadPoliciesOperation      Operation = "reloadPolicies"
+)
+
+// PolicyResource resource of the operation, empty for the operations without resource. An empty tenant is the request tenant,
+// the target status is the requested status of the status transitions
+type PolicyResource struct {
+	Owner        string
+	TenantID     string
+	Status       string
+	TargetStatus string
+}
+
+// ProductPolicyResource policy resource of the product
+func ProductPolicyResource(product *ProductResponse) PolicyResource {
+	return PolicyResource{
+		Owner:    product.AuditUser,
+		TenantID: product.TenantID,
+		Status:   product.Status,
+	}
+}
+
+// PolicyDecision decision of the authorization policy, the reasons are safe codes that can be returned to the clients
+type PolicyDecision struct {
+	Allow       bool     `json:"allow"`
+	Reasons     []string `json:"reasons"`
+	Obligations []string `json:"obligations"`
+}
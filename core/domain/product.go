@@ -6,8 +6,12 @@ import (
 	"time"
 )
 
-// ProductEventName product kafka event name
-const ProductEventName = "create.product.event"
+const (
+	// ProductEventName product kafka event name
+	ProductEventName = "create.product.event"
+	// ProductUpdatedEventName product updated kafka event name
+	ProductUpdatedEventName = "update.product.event"
+)
 
 // Product request product
 type Product struct {
@@ -25,6 +29,7 @@ type Product struct {
 type ProductModel struct {
 	bun.BaseModel `bun:"table:products" json:"-"`
 	ID            string    `bun:"id,pk" json:"id"`
+	TenantID      string    `bun:"tenant_id" json:"tenantId"`
 	Name          string    `bun:"name" json:"name"`
 	Description   string    `bun:"description" json:"description"`
 	UnitType      string    `bun:"unit_type" json:"unitType"`
@@ -33,6 +38,7 @@ type ProductModel struct {
 	Color         string    `bun:"color" json:"color"`
 	Style         string    `bun:"style" json:"style"`
 	Status        string    `bun:"status" json:"status"`
+	Version       int64     `bun:"version" json:"version"`
 	AuditUser     string    `bun:"audit_user" json:"auditUser"`
 	CreationDate  time.Time `bun:"creation_date" json:"creationDate"`
 	UpdateDate    time.Time `bun:"update_date" json:"updateDate"`
@@ -41,6 +47,7 @@ type ProductModel struct {
 // ProductResponse product response
 type ProductResponse struct {
 	ID           string    `json:"id"`
+	TenantID     string    `json:"tenantId"`
 	Name         string    `json:"name"`
 	Description  string    `json:"description"`
 	UnitType     string    `json:"unitType"`
@@ -49,16 +56,18 @@ type ProductResponse struct {
 	Color        string    `json:"color"`
 	Style        string    `json:"style"`
 	Status       string    `json:"status"`
+	Version      int64     `json:"version"`
 	AuditUser    string    `json:"auditUser"`
 	CreationDate time.Time `json:"creationDate"`
 	UpdateDate   time.Time `json:"updateDate"`
 }
 
 // FromProductToProductModel convert from Product Request to Product Database Model
-func FromProductToProductModel(request *Product, auditUser string) *ProductModel {
+func FromProductToProductModel(request *Product, auditUser, tenantID string) *ProductModel {
 	currentTime := time.Now()
 	return &ProductModel{
 		ID:           uuid.NewString(),
+		TenantID:     tenantID,
 		Name:         request.Name,
 		Description:  request.Description,
 		UnitType:     request.UnitType,
@@ -67,6 +76,7 @@ func FromProductToProductModel(request *Product, auditUser string) *ProductModel
 		Color:        request.Color,
 		Style:        request.Style,
 		Status:       request.Status,
+		Version:      1,
 		AuditUser:    auditUser,
 		CreationDate: currentTime,
 		UpdateDate:   currentTime,
@@ -77,6 +87,7 @@ func FromProductToProductModel(request *Product, auditUser string) *ProductModel
 func FromProductModelToProductResponse(productModel *ProductModel) *ProductResponse {
 	return &ProductResponse{
 		ID:           productModel.ID,
+		TenantID:     productModel.TenantID,
 		Name:         productModel.Name,
 		Description:  productModel.Description,
 		UnitType:     productModel.UnitType,
@@ -85,8 +96,43 @@ func FromProductModelToProductResponse(productModel *ProductModel) *ProductRespo
 		Color:        productModel.Color,
 		Style:        productModel.Style,
 		Status:       productModel.Status,
+		Version:      productModel.Version,
 		AuditUser:    productModel.AuditUser,
 		CreationDate: productModel.CreationDate,
 		UpdateDate:   productModel.UpdateDate,
 	}
 }
+
+// FromProductToUpdatedProductModel convert the Product Request to the next version of the product, the owner and creation date are kept
+func FromProductToUpdatedProductModel(request *Product, product *ProductResponse) *ProductModel {
+	return &ProductModel{
+		ID:           product.ID,
+		TenantID:     product.TenantID,
+		Name:         request.Name,
+		Description:  request.Description,
+		UnitType:     request.UnitType,
+		Unit:         request.Unit,
+		Brand:        request.Brand,
+		Color:        request.Color,
+		Style:        request.Style,
+		Status:       request.Status,
+		Version:      product.Version + 1,
+		AuditUser:    product.AuditUser,
+		CreationDate: product.CreationDate,
+		UpdateDate:   time.Now(),
+	}
+}
+
+// FromProductResponseToProduct convert the Product response to the Product Request with its current values
+func FromProductResponseToProduct(product *ProductResponse) *Product {
+	return &Product{
+		Name:        product.Name,
+		Description: product.Description,
+		UnitType:    product.UnitType,
+		Unit:        product.Unit,
+		Brand:       product.Brand,
+		Color:       product.Color,
+		Style:       product.Style,
+		Status:      product.Status,
+	}
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"encoding/json"
+	"github.com/uptrace/bun"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Operations of the product audit trail
+const (
+	ProductCreatedAudit       = "create"
+	ProductUpdatedAudit       = "update"
+	ProductStatusChangedAudit = "status"
+)
+
+const (
+	// DefaultProductHistoryLimit default number of audit entries by page
+	DefaultProductHistoryLimit = 20
+	// MaxProductHistoryLimit max number of audit entries by page
+	MaxProductHistoryLimit = 100
+)
+
+// ProductChange value of a product field before and after the change, before is null on the creation
+type ProductChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// ProductAuditModel product audit trail database model
+type ProductAuditModel struct {
+	bun.BaseModel `bun:"table:product_audit" json:"-"`
+	ID            int64                    `bun:"id,pk,autoincrement" json:"id"`
+	TenantID      string                   `bun:"tenant_id" json:"tenantId"`
+	ProductID     string                   `bun:"product_id" json:"productId"`
+	Operation     string                   `bun:"operation" json:"operation"`
+	AuditUser     string                   `bun:"audit_user" json:"auditUser"`
+	TraceID       string                   `bun:"trace_id" json:"traceId"`
+	Reason        string                   `bun:"reason,nullzero" json:"reason,omitempty"`
+	Changes       map[string]ProductChange `bun:"changes,type:jsonb" json:"changes"`
+	CreationDate  time.Time                `bun:"creation_date" json:"creationDate"`
+}
+
+// ProductAuditResponse product audit trail entry response
+type ProductAuditResponse struct {
+	ID        int64                    `json:"id"`
+	Operation string                   `json:"operation"`
+	User      string                   `json:"user"`
+	TraceID   string                   `json:"traceId"`
+	Reason    string                   `json:"reason,omitempty"`
+	Changes   map[string]ProductChange `json:"changes"`
+	Date      time.Time                `json:"date"`
+}
+
+// ProductHistoryResponse page of the product audit trail, newest first. The next cursor is empty on the last page
+type ProductHistoryResponse struct {
+	Items      []*ProductAuditResponse `json:"items"`
+	NextCursor string                  `json:"nextCursor,omitempty"`
+}
+
+// NewProductAudit create the audit entry of the product change with the changed fields, before is nil on the creation
+func NewProductAudit(operation string, before *ProductResponse, after *ProductModel, auditUser, traceID string) *ProductAuditModel {
+	return &ProductAuditModel{
+		TenantID:     after.TenantID,
+		ProductID:    after.ID,
+		Operation:    operation,
+		AuditUser:    auditUser,
+		TraceID:      traceID,
+		Changes:      ProductChanges(before, after),
+		CreationDate: after.UpdateDate,
+	}
+}
+
+// ProductChanges the fields with different values of the products, the update date is the date of the audit entry
+func ProductChanges(before, after interface{}) map[string]ProductChange {
+	beforeFields := productFields(before)
+	afterFields := productFields(after)
+
+	changes := map[string]ProductChange{}
+	for field, value := range afterFields {
+		if previous, ok := beforeFields[field]; !ok || !reflect.DeepEqual(previous, value) {
+			changes[field] = ProductChange{Before: previous, After: value}
+		}
+	}
+	for field, previous := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			changes[field] = ProductChange{Before: previous}
+		}
+	}
+	delete(changes, "updateDate")
+	return changes
+}
+
+// productFields the json fields of the product, the nil products have no fields
+func productFields(product interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	data, err := json.Marshal(product)
+	if err == nil {
+		_ = json.Unmarshal(data, &fields)
+	}
+	return fields
+}
+
+// FromProductAuditModelsToProductHistoryResponse convert the audit entries to the history page, the models have one more entry than the limit when there is a next page
+func FromProductAuditModelsToProductHistoryResponse(models []*ProductAuditModel, limit int) *ProductHistoryResponse {
+	response := &ProductHistoryResponse{Items: make([]*ProductAuditResponse, 0, len(models))}
+	if len(models) > limit {
+		models = models[:limit]
+		response.NextCursor = strconv.FormatInt(models[limit-1].ID, 10)
+	}
+	for _, model := range models {
+		response.Items = append(response.Items, &ProductAuditResponse{
+			ID:        model.ID,
+			Operation: model.Operation,
+			User:      model.AuditUser,
+			TraceID:   model.TraceID,
+			Reason:    model.Reason,
+			Changes:   model.Changes,
+			Date:      model.CreationDate,
+		})
+	}
+	return response
+}
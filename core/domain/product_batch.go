@@ -0,0 +1,22 @@
+package domain
+
+// MaxProductBatchGetIds max number of product ids of a batch get
+const MaxProductBatchGetIds = 100
+
+// ProductBatchGetRequest ids of the products to get
+type ProductBatchGetRequest struct {
+	IDs []string `json:"ids" validate:"required,min=1,max=100,dive,required,uuid"`
+}
+
+// ProductBatchItem product of a requested id, the error is the reason when the product is not returned
+type ProductBatchItem struct {
+	ID      string           `json:"id"`
+	Found   bool             `json:"found"`
+	Product *ProductResponse `json:"product,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// ProductBatchGetResponse products in the order of the requested ids
+type ProductBatchGetResponse struct {
+	Items []*ProductBatchItem `json:"items"`
+}
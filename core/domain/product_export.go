@@ -0,0 +1,100 @@
+package domain
+
+import "time"
+
+// Formats of the product export
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportJSON   = "json"
+)
+
+// Statuses of the product export jobs
+const (
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+)
+
+const (
+	// MaxSyncExportRows max number of products streamed in the response, the larger exports are asynchronous jobs
+	MaxSyncExportRows = 10000
+	// ExportJobTimeout max duration of an export job
+	ExportJobTimeout = time.Minute * 30
+	// ExportJobDuration expiration time of the export jobs and their files
+	ExportJobDuration = time.Hour * 24
+	// ExportFileChunkBytes max size of the chunks of the compressed export files in cache
+	ExportFileChunkBytes = 1 << 20
+)
+
+// ProductExportColumns columns of the product export, the json names of the product fields
+var ProductExportColumns = []string{"id", "name", "description", "unitType", "unit", "brand", "color", "style", "status",
+	"version", "auditUser", "creationDate", "updateDate"}
+
+// ProductExportQuery filters, format and columns of the export. Excel is the CSV for spreadsheets, with a byte order mark
+// and the formulas escaped
+type ProductExportQuery struct {
+	Filter  ProductFilter
+	Format  string
+	Columns []string
+	Excel   bool
+}
+
+// ProductExportJob asynchronous export job, the file can be downloaded once it is completed. The file is kept in cache in Chunks keys
+type ProductExportJob struct {
+	ID           string    `json:"id"`
+	TenantID     string    `json:"tenantId"`
+	User         string    `json:"user"`
+	Status       string    `json:"status"`
+	Format       string    `json:"format"`
+	Total        int       `json:"total"`
+	Exported     int       `json:"exported"`
+	Error        string    `json:"error,omitempty"`
+	Chunks       int       `json:"chunks,omitempty"`
+	CreationDate time.Time `json:"creationDate"`
+	UpdateDate   time.Time `json:"updateDate"`
+}
+
+// ProductExportValue value of the column of the product
+func ProductExportValue(product *ProductModel, column string) interface{} {
+	switch column {
+	case "id":
+		return product.ID
+	case "name":
+		return product.Name
+	case "description":
+		return product.Description
+	case "unitType":
+		return product.UnitType
+	case "unit":
+		return product.Unit
+	case "brand":
+		return product.Brand
+	case "color":
+		return product.Color
+	case "style":
+		return product.Style
+	case "status":
+		return product.Status
+	case "version":
+		return product.Version
+	case "auditUser":
+		return product.AuditUser
+	case "creationDate":
+		return product.CreationDate
+	case "updateDate":
+		return product.UpdateDate
+	default:
+		return nil
+	}
+}
+
+// IsProductExportColumn the column can be exported
+func IsProductExportColumn(column string) bool {
+	for _, exportColumn := range ProductExportColumns {
+		if exportColumn == column {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Statuses of the product import jobs
+const (
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+const (
+	// MaxImportRows max number of products of an import
+	MaxImportRows = 10000
+	// MaxImportBytes max size of the import body
+	MaxImportBytes = 10 << 20
+	// ImportBatchSize number of products inserted by transaction
+	ImportBatchSize = 100
+	// ImportJobDuration expiration time of the import jobs and their reports
+	ImportJobDuration = time.Hour * 24
+)
+
+// ProductImportRow product of an import row, the error is the parsing or validation error of the row
+type ProductImportRow struct {
+	Row     int
+	Product *Product
+	Error   string
+}
+
+// ProductImportError error of an import row
+type ProductImportError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// ProductImportJob asynchronous import job with the per row error report
+type ProductImportJob struct {
+	ID           string               `json:"id"`
+	TenantID     string               `json:"tenantId"`
+	User         string               `json:"user"`
+	Status       string               `json:"status"`
+	Total        int                  `json:"total"`
+	Processed    int                  `json:"processed"`
+	Created      int                  `json:"created"`
+	Failed       int                  `json:"failed"`
+	Errors       []ProductImportError `json:"errors"`
+	CreationDate time.Time            `json:"creationDate"`
+	UpdateDate   time.Time            `json:"updateDate"`
+}
+
+// ProductIdentity the fields that identify a product in the tenant
+func ProductIdentity(product *Product) string {
+	return product.Name + "\x00" + product.UnitType + "\x00" + product.Unit + "\x00" + product.Brand + "\x00" + product.Color + "\x00" + product.Style
+}
+
+// NewProductImportJob create the running import job of the rows
+func NewProductImportJob(tenantID, user string, total int) *ProductImportJob {
+	now := time.Now()
+	return &ProductImportJob{
+		ID:           uuid.NewString(),
+		TenantID:     tenantID,
+		User:         user,
+		Status:       ImportRunning,
+		Total:        total,
+		Errors:       []ProductImportError{},
+		CreationDate: now,
+		UpdateDate:   now,
+	}
+}
+
+// AddError add the error of the row to the report
+func (job *ProductImportJob) AddError(row int, message string) {
+	job.Failed++
+	job.Errors = append(job.Errors, ProductImportError{Row: row, Message: message})
+}
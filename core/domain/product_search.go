@@ -0,0 +1,84 @@
+package domain
+
+import "errors"
+
+// ErrInvalidSearchCursor the cursor is not a cursor of the search engine
+var ErrInvalidSearchCursor = errors.New("invalid cursor")
+
+// Sorts of the product search, the - prefix is the descending order
+const (
+	SortByRelevance        = "relevance"
+	SortByName             = "name"
+	SortByNameDesc         = "-name"
+	SortByCreationDate     = "creationDate"
+	SortByCreationDateDesc = "-creationDate"
+)
+
+const (
+	// DefaultProductSearchLimit default number of products by page
+	DefaultProductSearchLimit = 20
+	// MaxFacetValues max number of values by facet, the most frequent first
+	MaxFacetValues = 20
+)
+
+// ProductFacets fields with facet counts
+var ProductFacets = []string{"brand", "color", "style", "unitType", "status"}
+
+// ProductSearchQuery product search query, the text matches the name and description, the empty filters are ignored.
+// The relevance sort is the default with a text, the newest products first without text
+type ProductSearchQuery struct {
+	Text     string `validate:"omitempty,max=256"`
+	Brand    string `validate:"omitempty,max=50"`
+	Color    string `validate:"omitempty,max=50"`
+	Style    string `validate:"omitempty,max=50"`
+	UnitType string `validate:"omitempty,oneof=unit kilos grams liters box size"`
+	Status   string `validate:"omitempty,oneof=available pending inactive"`
+	Sort     string `validate:"omitempty,oneof=relevance name -name creationDate -creationDate"`
+	Cursor   string `validate:"omitempty,max=512"`
+	Limit    int    `validate:"min=1,max=100"`
+}
+
+// FacetCount number of products with the facet value
+type FacetCount struct {
+	Value string `bun:"value" json:"value"`
+	Count int64  `bun:"count" json:"count"`
+}
+
+// ProductSearchResult page of the products found, the facets count the products of each value of the facet fields
+// with the other filters. The next cursor is empty on the last page
+type ProductSearchResult struct {
+	Products   []*ProductModel
+	Facets     map[string][]FacetCount
+	NextCursor string
+}
+
+// ProductSearchResponse product search response
+type ProductSearchResponse struct {
+	Items      []*ProductResponse      `json:"items"`
+	Facets     map[string][]FacetCount `json:"facets"`
+	NextCursor string                  `json:"nextCursor,omitempty"`
+}
+
+// Filters the filters of the query by facet field
+func (q *ProductSearchQuery) Filters() map[string]string {
+	return map[string]string{
+		"brand":    q.Brand,
+		"color":    q.Color,
+		"style":    q.Style,
+		"unitType": q.UnitType,
+		"status":   q.Status,
+	}
+}
+
+// FromProductSearchResultToProductSearchResponse convert the search result to the search response
+func FromProductSearchResultToProductSearchResponse(result *ProductSearchResult) *ProductSearchResponse {
+	response := &ProductSearchResponse{
+		Items:      make([]*ProductResponse, 0, len(result.Products)),
+		Facets:     result.Facets,
+		NextCursor: result.NextCursor,
+	}
+	for _, product := range result.Products {
+		response.Items = append(response.Items, FromProductModelToProductResponse(product))
+	}
+	return response
+}
@@ -0,0 +1,83 @@
+package domain
+
+import "time"
+
+// Product statuses
+const (
+	ProductPending   = "pending"
+	ProductAvailable = "available"
+	ProductInactive  = "inactive"
+)
+
+// StatusNotUpdatedMessage error of the updates and patches that change the product status, the status is only changed by the status endpoint
+const StatusNotUpdatedMessage = "the product status is changed by POST /v1/product/{id}/status"
+
+// ProductStatusTransition allowed transition of the product status and its kafka event name
+type ProductStatusTransition struct {
+	From      string
+	To        string
+	EventName string
+}
+
+// productStatusTransitions the product lifecycle, the inactive products are reactivated as pending so they are reviewed again
+var productStatusTransitions = []ProductStatusTransition{
+	{From: ProductPending, To: ProductAvailable, EventName: "publish.product.event"},
+	{From: ProductPending, To: ProductInactive, EventName: "reject.product.event"},
+	{From: ProductAvailable, To: ProductInactive, EventName: "deactivate.product.event"},
+	{From: ProductInactive, To: ProductPending, EventName: "reactivate.product.event"},
+}
+
+// productInitialStatuses statuses of the new products
+var productInitialStatuses = []string{ProductPending, ProductAvailable}
+
+// ProductStatusChange request to change the product status with the reason of the change
+type ProductStatusChange struct {
+	Status string `json:"status" validate:"required,oneof=available pending inactive"`
+	Reason string `json:"reason" validate:"required,not_blank,min=2,max=256"`
+}
+
+// ProductStatusEvent kafka event of a product status transition
+type ProductStatusEvent struct {
+	ProductID string    `json:"productId"`
+	TenantID  string    `json:"tenantId"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Reason    string    `json:"reason"`
+	User      string    `json:"user"`
+	Version   int64     `json:"version"`
+	Date      time.Time `json:"date"`
+}
+
+// FindProductStatusTransition returns the transition from the current status to the requested status, false when it is not allowed
+func FindProductStatusTransition(from, to string) (ProductStatusTransition, bool) {
+	for _, transition := range productStatusTransitions {
+		if transition.From == from && transition.To == to {
+			return transition, true
+		}
+	}
+	return ProductStatusTransition{}, false
+}
+
+// AllowedProductStatuses returns the statuses allowed from the current status, the initial statuses when it is empty
+func AllowedProductStatuses(from string) []string {
+	if from == "" {
+		return append([]string{}, productInitialStatuses...)
+	}
+	allowed := []string{}
+	for _, transition := range productStatusTransitions {
+		if transition.From == from {
+			allowed = append(allowed, transition.To)
+		}
+	}
+	return allowed
+}
+
+// IsProductInitialStatus the new products can be created with the status
+func IsProductInitialStatus(status string) bool {
+	for _, initial := range productInitialStatuses {
+		if initial == status {
+			return true
+		}
+	}
+	return false
+}
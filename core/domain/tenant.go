@@ -0,0 +1,28 @@
+package domain
+
+import "context"
+
+const (
+	// TenantKey context key of the tenant of the request
+	TenantKey = "tenant"
+	// DefaultTenantID tenant of the requests and tokens without a tenant
+	DefaultTenantID = "default"
+	// TenantHeader kafka header with the tenant of the event
+	TenantHeader = "tenant.id"
+)
+
+// WithTenant returns a copy of the context carrying the tenant, an empty tenant is the default tenant
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	if tenantID == "" {
+		tenantID = DefaultTenantID
+	}
+	return context.WithValue(ctx, TenantKey, tenantID)
+}
+
+// TenantFromContext returns the tenant carried in the context or the default tenant
+func TenantFromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(TenantKey).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return DefaultTenantID
+}
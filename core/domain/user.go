@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// User request user
+type User struct {
+	Username string   `json:"username" validate:"required,not_blank,min=2,max=256"`
+	Password string   `json:"password" validate:"required,not_blank,min=8,max=256"`
+	Roles    []string `json:"roles" validate:"dive,oneof=admin business user"`
+	TenantID string   `json:"tenantId" validate:"omitempty,not_blank,max=64"`
+}
+
+// UserRoles request to assign roles to a user
+type UserRoles struct {
+	Username string   `json:"username" validate:"required,not_blank,min=2,max=256"`
+	Roles    []string `json:"roles" validate:"dive,oneof=admin business user"`
+}
+
+// UserModel user database model
+type UserModel struct {
+	bun.BaseModel `bun:"table:users" json:"-"`
+	ID            string    `bun:"id,pk" json:"id"`
+	Username      string    `bun:"username" json:"username"`
+	PasswordHash  string    `bun:"password_hash" json:"-"`
+	Roles         []string  `bun:"roles,array" json:"roles"`
+	TenantID      string    `bun:"tenant_id" json:"tenantId"`
+	CreationDate  time.Time `bun:"creation_date" json:"creationDate"`
+	UpdateDate    time.Time `bun:"update_date" json:"updateDate"`
+}
+
+// FromUserToUserModel convert from User Request to User Database Model
+func FromUserToUserModel(request *User, passwordHash string) *UserModel {
+	currentTime := time.Now()
+	tenantID := request.TenantID
+	if tenantID == "" {
+		tenantID = DefaultTenantID
+	}
+	return &UserModel{
+		ID:           uuid.NewString(),
+		Username:     request.Username,
+		PasswordHash: passwordHash,
+		Roles:        request.Roles,
+		TenantID:     tenantID,
+		CreationDate: currentTime,
+		UpdateDate:   currentTime,
+	}
+}
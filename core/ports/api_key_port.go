@@ -0,0 +1,27 @@
+package ports
+
+import (
+	"context"
+	"golang-api-hexagonal/core/domain"
+)
+
+// IApiKeyRepository api key repository interface
+type IApiKeyRepository interface {
+	Create(ctx context.Context, model *domain.ApiKeyModel) (*domain.ApiKeyModel, error)
+	GetApiKeyByPrefix(ctx context.Context, prefix string) (*domain.ApiKeyModel, error)
+	ListApiKeys(ctx context.Context) ([]*domain.ApiKeyModel, error)
+	Revoke(ctx context.Context, apiKeyID string) (bool, error)
+}
+
+// IApiKeyVerifier api key verifier interface
+type IApiKeyVerifier interface {
+	ParseApiKey(ctx context.Context, key string) (*domain.AuthClaims, error)
+}
+
+// IApiKeyService api key service interface
+type IApiKeyService interface {
+	IApiKeyVerifier
+	CreateApiKey(ctx context.Context, request *domain.ApiKey, username, traceID string) (*domain.ApiKeyResponse, error)
+	ListApiKeys(ctx context.Context, traceID string) ([]*domain.ApiKeyResponse, error)
+	RevokeApiKey(ctx context.Context, apiKeyID, traceID string) error
+}
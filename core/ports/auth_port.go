@@ -1,11 +1,20 @@
 package ports
 
 import (
+	"context"
 	"golang-api-hexagonal/core/domain"
 )
 
+// ITokenVerifier token verifier interface
+type ITokenVerifier interface {
+	ParseOauthToken(ctx context.Context, token string) (*domain.AuthClaims, error)
+}
+
 // IAuthService auth service interface
 type IAuthService interface {
-	CreateOauthToken(request *domain.Auth, traceID string) (string, error)
-	ParseOauthToken(token string) (*domain.AuthClaims, error)
+	ITokenVerifier
+	CreateOauthToken(ctx context.Context, request *domain.Auth, traceID string) (*domain.TokenResponse, error)
+	RefreshOauthToken(ctx context.Context, request *domain.RefreshTokenRequest, traceID string) (*domain.TokenResponse, error)
+	RevokeOauthToken(ctx context.Context, request *domain.RevokeTokenRequest, traceID string) error
+	GetJWKS() *domain.JSONWebKeySet
 }
@@ -3,6 +3,7 @@ package ports
 import (
 	"context"
 	"github.com/go-redis/redis/v8"
+	"golang-api-hexagonal/core/domain"
 	"time"
 )
 
@@ -10,4 +11,15 @@ import (
 type IRedis interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
 	Get(ctx context.Context, key string) *redis.StringCmd
+	MGet(ctx context.Context, keys ...string) *redis.SliceCmd
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	Exists(ctx context.Context, keys ...string) *redis.IntCmd
+	Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+}
+
+// ICacheWarmService cache warm up service interface
+type ICacheWarmService interface {
+	Warm(ctx context.Context, request *domain.CacheWarmRequest) (*domain.CacheWarmResult, error)
 }
@@ -2,5 +2,5 @@ package ports
 
 // IMessage kafka message interface
 type IMessage interface {
-	ProduceMessage(topicName, value, eventName, traceID string)
+	ProduceMessage(topicName, value, eventName, tenantID, traceID string)
 }
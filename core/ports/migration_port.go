@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+	"golang-api-hexagonal/core/domain"
+)
+
+// IMigrator database migrations runner, the migrations of parallel instances are serialized
+type IMigrator interface {
+	Up(ctx context.Context) ([]domain.MigrationStatus, error)
+	Down(ctx context.Context, steps int) ([]domain.MigrationStatus, error)
+	Status(ctx context.Context) ([]domain.MigrationStatus, error)
+}
@@ -3,10 +3,31 @@ package ports
 import (
 	"context"
 	"golang-api-hexagonal/core/domain"
+	"io"
 )
 
 // IProductService product service interface
 type IProductService interface {
 	CreateProduct(ctx context.Context, request *domain.Product, username, traceID string) (*domain.ProductResponse, error)
 	GetProduct(ctx context.Context, productID, traceID string) (*domain.ProductResponse, error)
+	GetProducts(ctx context.Context, productIDs []string, traceID string) (*domain.ProductBatchGetResponse, error)
+	ReloadProduct(ctx context.Context, productID, traceID string) (*domain.ProductResponse, error)
+	UpdateProduct(ctx context.Context, product *domain.ProductResponse, request *domain.Product, username, traceID string) (*domain.ProductResponse, error)
+	ChangeProductStatus(ctx context.Context, product *domain.ProductResponse, request *domain.ProductStatusChange, username, traceID string) (*domain.ProductResponse, error)
+	GetProductHistory(ctx context.Context, productID string, cursor int64, limit int, traceID string) (*domain.ProductHistoryResponse, error)
+}
+
+// IProductImportService product import service interface
+type IProductImportService interface {
+	StartImport(ctx context.Context, rows []domain.ProductImportRow, username, traceID string) (*domain.ProductImportJob, error)
+	GetImportJob(ctx context.Context, jobID, traceID string) (*domain.ProductImportJob, error)
+}
+
+// IProductExportService product export service interface
+type IProductExportService interface {
+	CountProducts(ctx context.Context, filter domain.ProductFilter, traceID string) (int, error)
+	WriteProducts(ctx context.Context, query domain.ProductExportQuery, writer io.Writer, traceID string) (int, error)
+	StartExport(ctx context.Context, query domain.ProductExportQuery, total int, username, traceID string) (*domain.ProductExportJob, error)
+	GetExportJob(ctx context.Context, jobID, traceID string) (*domain.ProductExportJob, error)
+	OpenExportFile(ctx context.Context, job *domain.ProductExportJob, traceID string) (io.Reader, error)
 }
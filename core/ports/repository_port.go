@@ -7,7 +7,14 @@ import (
 
 // IRepository repository interface
 type IRepository interface {
-	Create(ctx context.Context, model *domain.ProductModel) (*domain.ProductModel, error)
+	Create(ctx context.Context, model *domain.ProductModel, audit *domain.ProductAuditModel) (*domain.ProductModel, error)
+	CreateBatch(ctx context.Context, models []*domain.ProductModel, audits []*domain.ProductAuditModel) error
 	ProductAlreadyExist(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error)
 	GetProductById(ctx context.Context, productID string) (*domain.ProductModel, error)
+	GetProductsByIds(ctx context.Context, productIDs []string) ([]*domain.ProductModel, error)
+	Update(ctx context.Context, model *domain.ProductModel, expectedVersion int64, audit *domain.ProductAuditModel) (bool, error)
+	GetProductHistory(ctx context.Context, productID string, beforeID int64, limit int) ([]*domain.ProductAuditModel, error)
+	ListProducts(ctx context.Context, filter domain.ProductFilter, afterID string, limit int) ([]*domain.ProductModel, error)
+	CountProducts(ctx context.Context, filter domain.ProductFilter) (int, error)
+	ExportProducts(ctx context.Context, filter domain.ProductFilter, fn func(product *domain.ProductModel) error) error
 }
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+	"golang-api-hexagonal/core/domain"
+)
+
+// IProductSearch product search engine, the products of other tenants are never found.
+// The cursor is specific to the engine and returns domain.ErrInvalidSearchCursor when it is not valid
+type IProductSearch interface {
+	SearchProducts(ctx context.Context, query domain.ProductSearchQuery) (*domain.ProductSearchResult, error)
+}
+
+// IProductSearchService product search service interface
+type IProductSearchService interface {
+	SearchProducts(ctx context.Context, query domain.ProductSearchQuery, traceID string) (*domain.ProductSearchResponse, error)
+}
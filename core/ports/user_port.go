@@ -0,0 +1,19 @@
+package ports
+
+import (
+	"context"
+	"golang-api-hexagonal/core/domain"
+)
+
+// IUserRepository user repository interface
+type IUserRepository interface {
+	Create(ctx context.Context, model *domain.UserModel) (*domain.UserModel, error)
+	GetUserByUsername(ctx context.Context, username string) (*domain.UserModel, error)
+	UpdateRoles(ctx context.Context, username string, roles []string) (bool, error)
+}
+
+// IUserService user service interface
+type IUserService interface {
+	CreateUser(ctx context.Context, request *domain.User, traceID string) (*domain.UserModel, error)
+	AssignRoles(ctx context.Context, request *domain.UserRoles, traceID string) error
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ApiKeyService api key service
+type ApiKeyService struct {
+	log              *zap.SugaredLogger
+	apiKeyRepository ports.IApiKeyRepository
+}
+
+// NewApiKeyService create new api key service
+func NewApiKeyService(log *zap.SugaredLogger, apiKeyRepository ports.IApiKeyRepository) *ApiKeyService {
+	return &ApiKeyService{
+		log:              log,
+		apiKeyRepository: apiKeyRepository,
+	}
+}
+
+// CreateApiKey create the api key, the key is only returned in this response
+func (aks *ApiKeyService) CreateApiKey(ctx context.Context, request *domain.ApiKey, username, traceID string) (*domain.ApiKeyResponse, error) {
+	prefixBytes := make([]byte, 6)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		aks.log.With("traceId", traceID).Errorf("Internal error to generate the api key: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		aks.log.With("traceId", traceID).Errorf("Internal error to generate the api key: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+
+	apiKeyModel, err := aks.apiKeyRepository.Create(ctx, domain.FromApiKeyToApiKeyModel(request, prefix, hashToken(secret), username, domain.TenantFromContext(ctx)))
+	if err != nil {
+		aks.log.With("traceId", traceID).Errorf("Internal server error: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	response := domain.FromApiKeyModelToApiKeyResponse(apiKeyModel)
+	response.Key = strings.Join([]string{domain.ApiKeyPrefix, prefix, secret}, "_")
+
+	aks.log.With("traceId", traceID).Infof("The api key %s with prefix %s was created with success", apiKeyModel.ID, prefix)
+	return response, nil
+}
+
+// ListApiKeys list the api keys without their secrets
+func (aks *ApiKeyService) ListApiKeys(ctx context.Context, traceID string) ([]*domain.ApiKeyResponse, error) {
+	apiKeys, err := aks.apiKeyRepository.ListApiKeys(ctx)
+	if err != nil {
+		aks.log.With("traceId", traceID).Errorf("Internal server error to list the api keys: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	response := make([]*domain.ApiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		response = append(response, domain.FromApiKeyModelToApiKeyResponse(apiKey))
+	}
+	return response, nil
+}
+
+// RevokeApiKey revoke the api key
+func (aks *ApiKeyService) RevokeApiKey(ctx context.Context, apiKeyID, traceID string) error {
+	if _, err := uuid.Parse(apiKeyID); err != nil {
+		aks.log.With("traceId", traceID).Errorf("Invalid api key id: %s", apiKeyID)
+		return custom_error.New(http.StatusNotFound, "not found")
+	}
+
+	revoked, err := aks.apiKeyRepository.Revoke(ctx, apiKeyID)
+	if err != nil {
+		aks.log.With("traceId", traceID).Errorf("Internal server error to revoke the api key: %v", err)
+		return custom_error.New(http.StatusInternalServerError, "internal server error")
+	} else if !revoked {
+		aks.log.With("traceId", traceID).Errorf("Api key not found or already revoked")
+		return custom_error.New(http.StatusNotFound, "not found")
+	}
+
+	aks.log.With("traceId", traceID).Infof("The api key %s was revoked", apiKeyID)
+	return nil
+}
+
+// ParseApiKey resolve the api key to the auth claims
+func (aks *ApiKeyService) ParseApiKey(ctx context.Context, key string) (*domain.AuthClaims, error) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != domain.ApiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		aks.log.Errorf("malformed api key")
+		return nil, domain.ErrInvalidToken
+	}
+
+	apiKey, err := aks.apiKeyRepository.GetApiKeyByPrefix(ctx, parts[1])
+	if err != nil {
+		aks.log.Errorf("failed to get the api key: %v", err)
+		return nil, domain.ErrInvalidToken
+	} else if apiKey == nil {
+		aks.log.Errorf("api key with prefix %s not found", parts[1])
+		return nil, domain.ErrInvalidToken
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(parts[2])), []byte(apiKey.KeyHash)) != 1 {
+		aks.log.Errorf("invalid secret for the api key %s", apiKey.ID)
+		return nil, domain.ErrInvalidToken
+	}
+	if apiKey.RevokedAt != nil {
+		aks.log.Errorf("api key %s revoked", apiKey.ID)
+		return nil, domain.ErrTokenRevoked
+	}
+	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(time.Now()) {
+		aks.log.Errorf("api key %s expired", apiKey.ID)
+		return nil, domain.ErrTokenExpired
+	}
+
+	// the name is free text, so the principal is the prefix: it can not be the username of an owner and fits the audit user
+	return &domain.AuthClaims{
+		Subject:  domain.ApiKeySubjectPrefix + apiKey.ID,
+		Username: domain.ApiKeySubjectPrefix + apiKey.Prefix,
+		Roles:    apiKey.Roles,
+		TenantID: apiKey.TenantID,
+		Scopes:   []string{},
+		Audience: []string{},
+	}, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/adapters/repository/apikeys"
+	"golang-api-hexagonal/core/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockApiKeyStore mock the api key repository with an in memory store by prefix
+func mockApiKeyStore(apiKeyRepository *apikeys.ApiKeyRepositoryMock) map[string]*domain.ApiKeyModel {
+	store := map[string]*domain.ApiKeyModel{}
+	apiKeyRepository.CreateFunc = func(ctx context.Context, model *domain.ApiKeyModel) (*domain.ApiKeyModel, error) {
+		store[model.Prefix] = model
+		return model, nil
+	}
+	apiKeyRepository.GetApiKeyByPrefixFunc = func(ctx context.Context, prefix string) (*domain.ApiKeyModel, error) {
+		return store[prefix], nil
+	}
+	return store
+}
+
+// TestParseApiKey for test CreateApiKey and ParseApiKey
+func TestParseApiKey(t *testing.T) {
+	apiKeyRepository := &apikeys.ApiKeyRepositoryMock{}
+	service := NewApiKeyService(log, apiKeyRepository)
+	store := mockApiKeyStore(apiKeyRepository)
+
+	apiKey, err := service.CreateApiKey(defaultContext, &domain.ApiKey{Name: "batch-job", Roles: []string{"business"}}, username, traceID)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(apiKey.Key, domain.ApiKeyPrefix+"_"+apiKey.Prefix+"_"))
+	assert.NotContains(t, store[apiKey.Prefix].KeyHash, strings.Split(apiKey.Key, "_")[2])
+
+	claims, err := service.ParseApiKey(defaultContext, apiKey.Key)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.ApiKeySubjectPrefix+apiKey.ID, claims.Subject)
+	assert.Equal(t, domain.ApiKeySubjectPrefix+apiKey.Prefix, claims.Username)
+	assert.Equal(t, []string{"business"}, claims.Roles)
+
+	_, err = service.ParseApiKey(defaultContext, apiKey.Key+"x")
+	assert.True(t, errors.Is(err, domain.ErrInvalidToken))
+
+	_, err = service.ParseApiKey(defaultContext, "not-an-api-key")
+	assert.True(t, errors.Is(err, domain.ErrInvalidToken))
+
+	expired := time.Now().Add(-time.Minute)
+	store[apiKey.Prefix].ExpiresAt = &expired
+	_, err = service.ParseApiKey(defaultContext, apiKey.Key)
+	assert.True(t, errors.Is(err, domain.ErrTokenExpired))
+
+	revoked := time.Now()
+	store[apiKey.Prefix].RevokedAt = &revoked
+	_, err = service.ParseApiKey(defaultContext, apiKey.Key)
+	assert.True(t, errors.Is(err, domain.ErrTokenRevoked))
+}
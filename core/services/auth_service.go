@@ -1,75 +1,339 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"github.com/go-redis/redis/v8"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/custom_error"
 	"golang-api-hexagonal/config"
 	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
 	"time"
 )
 
+const (
+	defaultAccessTokenDuration  = 15 * time.Minute
+	defaultRefreshTokenDuration = 24 * time.Hour
+	refreshTokenKeyPrefix       = "sts:refresh:"
+	refreshTokenUsedKeyPrefix   = "sts:refresh-used:"
+	tokenFamilyKeyPrefix        = "sts:family:"
+	revokedTokenKeyPrefix       = "sts:revoked:"
+)
+
+// dummyPasswordHash compared when the user does not exist, so unknown users take as long as wrong passwords
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
 // AuthService service to authenticate
 type AuthService struct {
-	log  *zap.SugaredLogger
-	conf config.Oauth
+	log                  *zap.SugaredLogger
+	conf                 config.Oauth
+	keys                 *config.OauthKeys
+	userRepository       ports.IUserRepository
+	redis                ports.IRedis
+	accessTokenDuration  time.Duration
+	refreshTokenDuration time.Duration
 }
 
 // NewAuthService create new auth service
-func NewAuthService(log *zap.SugaredLogger, conf config.Oauth) *AuthService {
+func NewAuthService(log *zap.SugaredLogger, conf config.Oauth, keys *config.OauthKeys, userRepository ports.IUserRepository, redis ports.IRedis) *AuthService {
+	accessTokenDuration := time.Duration(conf.AccessTokenMinutes) * time.Minute
+	if accessTokenDuration <= 0 {
+		accessTokenDuration = defaultAccessTokenDuration
+	}
+	refreshTokenDuration := time.Duration(conf.RefreshTokenHours) * time.Hour
+	if refreshTokenDuration <= 0 {
+		refreshTokenDuration = defaultRefreshTokenDuration
+	}
+
 	return &AuthService{
-		log:  log,
-		conf: conf,
+		log:                  log,
+		conf:                 conf,
+		keys:                 keys,
+		userRepository:       userRepository,
+		redis:                redis,
+		accessTokenDuration:  accessTokenDuration,
+		refreshTokenDuration: refreshTokenDuration,
 	}
 }
 
-// CreateOauthToken create the oauth token
-func (as *AuthService) CreateOauthToken(request *domain.Auth, traceID string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256,
-		jwt.MapClaims{"username": request.Username, "role": domain.SuperRole, "iss": domain.Issuer, "exp": time.Now().Add(time.Hour * 1).Unix()})
+// CreateOauthToken verify the user credentials and create the access and refresh tokens with the user roles
+func (as *AuthService) CreateOauthToken(ctx context.Context, request *domain.Auth, traceID string) (*domain.TokenResponse, error) {
+	user, err := as.userRepository.GetUserByUsername(ctx, request.Username)
+	if err != nil {
+		as.log.With("traceId", traceID).Errorf("Internal server error to get the user: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	passwordHash := dummyPasswordHash
+	if user != nil {
+		passwordHash = []byte(user.PasswordHash)
+	}
+	err = bcrypt.CompareHashAndPassword(passwordHash, []byte(request.Password))
+	if user == nil || err != nil {
+		as.log.With("traceId", traceID).Errorf("Invalid credentials for user %s", request.Username)
+		return nil, custom_error.New(http.StatusUnauthorized, "invalid credentials")
+	}
 
-	tokenString, err := token.SignedString([]byte(as.conf.Secret))
+	return as.issueTokens(ctx, user, uuid.NewString(), traceID)
+}
+
+// RefreshOauthToken rotate the refresh token, a reused refresh token revokes the whole token family
+func (as *AuthService) RefreshOauthToken(ctx context.Context, request *domain.RefreshTokenRequest, traceID string) (*domain.TokenResponse, error) {
+	tokenHash := hashToken(request.RefreshToken)
+
+	payload, err := as.redis.Get(ctx, refreshTokenKeyPrefix+tokenHash).Bytes()
+	if errors.Is(err, redis.Nil) {
+		as.log.With("traceId", traceID).Errorf("Refresh token not found")
+		return nil, custom_error.New(http.StatusUnauthorized, "invalid refresh token")
+	} else if err != nil {
+		as.log.With("traceId", traceID).Errorf("Internal error to get the refresh token: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	var refreshData domain.RefreshTokenData
+	if err = json.Unmarshal(payload, &refreshData); err != nil {
+		as.log.With("traceId", traceID).Errorf("Internal error unmarshal the refresh token: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	familyExist, err := as.redis.Exists(ctx, tokenFamilyKeyPrefix+refreshData.FamilyID).Result()
 	if err != nil {
-		as.log.With("traceId", traceID).Errorf("Error to sign token: %v", err)
-		return "", err
+		as.log.With("traceId", traceID).Errorf("Internal error to get the token family: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	} else if familyExist == 0 {
+		as.log.With("traceId", traceID).Errorf("Refresh token family %s was revoked", refreshData.FamilyID)
+		return nil, custom_error.New(http.StatusUnauthorized, "invalid refresh token")
+	}
+
+	firstUse, err := as.redis.SetNX(ctx, refreshTokenUsedKeyPrefix+tokenHash, 1, as.refreshTokenDuration).Result()
+	if err != nil {
+		as.log.With("traceId", traceID).Errorf("Internal error to mark the refresh token as used: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	} else if !firstUse {
+		as.log.With("traceId", traceID).Warnf("Refresh token reuse detected, revoking the token family %s of user %s",
+			refreshData.FamilyID, refreshData.Username)
+		as.revokeTokenFamily(ctx, refreshData.FamilyID, traceID)
+		return nil, custom_error.New(http.StatusUnauthorized, "invalid refresh token")
 	}
 
-	return tokenString, nil
+	user, err := as.userRepository.GetUserByUsername(ctx, refreshData.Username)
+	if err != nil {
+		as.log.With("traceId", traceID).Errorf("Internal server error to get the user: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	} else if user == nil {
+		as.log.With("traceId", traceID).Errorf("User %s of the refresh token not found", refreshData.Username)
+		as.revokeTokenFamily(ctx, refreshData.FamilyID, traceID)
+		return nil, custom_error.New(http.StatusUnauthorized, "invalid refresh token")
+	}
+
+	return as.issueTokens(ctx, user, refreshData.FamilyID, traceID)
+}
+
+// RevokeOauthToken revoke a refresh token with its family or an access token. Unknown tokens are ignored
+func (as *AuthService) RevokeOauthToken(ctx context.Context, request *domain.RevokeTokenRequest, traceID string) error {
+	if request.TokenTypeHint != "access_token" {
+		payload, err := as.redis.Get(ctx, refreshTokenKeyPrefix+hashToken(request.Token)).Bytes()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			as.log.With("traceId", traceID).Errorf("Internal error to get the refresh token: %v", err)
+			return custom_error.New(http.StatusInternalServerError, "internal server error")
+		}
+		if err == nil {
+			var refreshData domain.RefreshTokenData
+			if err = json.Unmarshal(payload, &refreshData); err != nil {
+				as.log.With("traceId", traceID).Errorf("Internal error unmarshal the refresh token: %v", err)
+				return custom_error.New(http.StatusInternalServerError, "internal server error")
+			}
+			as.revokeTokenFamily(ctx, refreshData.FamilyID, traceID)
+			return nil
+		}
+	}
+
+	token, err := as.verifyToken(request.Token)
+	if err != nil {
+		as.log.With("traceId", traceID).Infof("Revoke ignored for an unknown token: %v", err)
+		return nil
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	tokenID, _ := claims["jti"].(string)
+	expiration, err := token.Claims.GetExpirationTime()
+	if tokenID == "" || err != nil || expiration == nil {
+		as.log.With("traceId", traceID).Infof("Revoke ignored for a token without id or expiration")
+		return nil
+	}
+
+	if err = as.denyAccessToken(ctx, tokenID, expiration.Time); err != nil {
+		as.log.With("traceId", traceID).Errorf("Internal error to revoke the access token: %v", err)
+		return custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+	as.log.With("traceId", traceID).Infof("The access token %s was revoked", tokenID)
+	return nil
+}
+
+// GetJWKS returns the public keys to verify the tokens
+func (as *AuthService) GetJWKS() *domain.JSONWebKeySet {
+	return as.keys.JWKS()
 }
 
 // ParseOauthToken parse the oauth token to claims
-func (as *AuthService) ParseOauthToken(tokenString string) (*domain.AuthClaims, error) {
+func (as *AuthService) ParseOauthToken(ctx context.Context, tokenString string) (*domain.AuthClaims, error) {
+	token, err := as.verifyToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		as.log.Errorf("claims not found")
+		return nil, domain.ErrInvalidToken
+	}
+
+	tokenID, err := domain.StringClaim(claims, "jti", true)
+	if err != nil {
+		as.log.Errorf("token without id: %v", err)
+		return nil, err
+	}
+
+	revoked, err := as.redis.Exists(ctx, revokedTokenKeyPrefix+tokenID).Result()
+	if err != nil {
+		as.log.Errorf("failed to check the token revocation: %v", err)
+		return nil, domain.ErrInvalidToken
+	} else if revoked > 0 {
+		as.log.Errorf("token %s revoked", tokenID)
+		return nil, domain.ErrTokenRevoked
+	}
+
+	authClaims, err := domain.NewAuthClaims(claims, domain.DefaultClaimPaths)
+	if err != nil {
+		as.log.Errorf("invalid token claims: %v", err)
+		return nil, err
+	}
+	return authClaims, nil
+}
+
+// verifyToken verify the token signature, issuer and expiration
+func (as *AuthService) verifyToken(tokenString string) (*jwt.Token, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if token.Method.Alg() != as.keys.Method.Alg() {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(as.conf.Secret), nil
-	})
+		keyID, _ := token.Header["kid"].(string)
+		if key, ok := as.keys.VerificationKeys[keyID]; ok {
+			return key, nil
+		}
+		return nil, errors.New("unknown signing key")
+	}, jwt.WithValidMethods([]string{as.keys.Method.Alg()}), jwt.WithExpirationRequired())
 
-	if err != nil || !token.Valid {
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		as.log.Errorf("token expired: %v", err)
+		return nil, domain.ErrTokenExpired
+	} else if err != nil || !token.Valid {
 		as.log.Errorf("invalid token: %v", err)
-		return nil, errors.New("invalid token")
+		return nil, domain.ErrInvalidToken
 	}
 
 	issuer, err := token.Claims.GetIssuer()
 	if err != nil || issuer != domain.Issuer {
 		as.log.Errorf("invalid token issuer: %v", err)
-		return nil, errors.New("invalid token issuer")
+		return nil, domain.ErrInvalidTokenIssuer
 	}
 
-	expiration, err := token.Claims.GetExpirationTime()
-	if err != nil || expiration.Time.Before(time.Now()) {
-		as.log.Errorf("token expired: %v", err)
-		return nil, errors.New("token expired")
+	return token, nil
+}
+
+// issueTokens sign a new access token and store a new refresh token in the token family
+func (as *AuthService) issueTokens(ctx context.Context, user *domain.UserModel, familyID, traceID string) (*domain.TokenResponse, error) {
+	now := time.Now()
+	tokenID := uuid.NewString()
+	expiration := now.Add(as.accessTokenDuration)
+
+	token := jwt.NewWithClaims(as.keys.Method,
+		jwt.MapClaims{domain.SubjectClaim: user.ID, domain.UsernameClaim: user.Username, domain.RolesClaim: user.Roles,
+			domain.TenantClaim: user.TenantID, "iss": domain.Issuer, "jti": tokenID, "iat": now.Unix(), "exp": expiration.Unix()})
+	if as.keys.SigningKeyID != "" {
+		token.Header["kid"] = as.keys.SigningKeyID
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		return &domain.AuthClaims{
-			Username: claims["username"].(string),
-			Role:     claims["role"].(string),
-		}, nil
-	} else {
-		as.log.Errorf("claims not found")
-		return nil, errors.New("claims not found")
+	tokenString, err := token.SignedString(as.keys.SigningKey)
+	if err != nil {
+		as.log.With("traceId", traceID).Errorf("Error to sign token: %v", err)
+		return nil, err
 	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		as.log.With("traceId", traceID).Errorf("Error to generate the refresh token: %v", err)
+		return nil, err
+	}
+
+	refreshData, _ := json.Marshal(domain.RefreshTokenData{Username: user.Username, FamilyID: familyID})
+	familyData, _ := json.Marshal(domain.TokenFamilyData{Username: user.Username, AccessTokenID: tokenID, AccessExpiresAt: expiration.Unix()})
+	_, err = as.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, refreshTokenKeyPrefix+hashToken(refreshToken), refreshData, as.refreshTokenDuration)
+		pipe.Set(ctx, tokenFamilyKeyPrefix+familyID, familyData, as.refreshTokenDuration)
+		return nil
+	})
+	if err != nil {
+		as.log.With("traceId", traceID).Errorf("Internal error to save the refresh token: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	return &domain.TokenResponse{
+		AccessToken:  tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(as.accessTokenDuration.Seconds()),
+		TokenType:    domain.TokenType,
+	}, nil
+}
+
+// revokeTokenFamily remove the token family, so all its refresh tokens become invalid, and deny its last access token
+func (as *AuthService) revokeTokenFamily(ctx context.Context, familyID, traceID string) {
+	payload, err := as.redis.Get(ctx, tokenFamilyKeyPrefix+familyID).Bytes()
+	if err == nil {
+		var familyData domain.TokenFamilyData
+		if json.Unmarshal(payload, &familyData) == nil && familyData.AccessTokenID != "" {
+			if errDeny := as.denyAccessToken(ctx, familyData.AccessTokenID, time.Unix(familyData.AccessExpiresAt, 0)); errDeny != nil {
+				as.log.With("traceId", traceID).Errorf("Internal error to revoke the access token: %v", errDeny)
+			}
+		}
+	}
+
+	if err = as.redis.Del(ctx, tokenFamilyKeyPrefix+familyID).Err(); err != nil {
+		as.log.With("traceId", traceID).Errorf("Internal error to revoke the token family %s: %v", familyID, err)
+		return
+	}
+	as.log.With("traceId", traceID).Infof("The token family %s was revoked", familyID)
+}
+
+// denyAccessToken add the access token id in the denylist until the token expires
+func (as *AuthService) denyAccessToken(ctx context.Context, tokenID string, expiration time.Time) error {
+	ttl := time.Until(expiration)
+	if ttl <= 0 {
+		return nil
+	}
+	return as.redis.Set(ctx, revokedTokenKeyPrefix+tokenID, 1, ttl).Err()
+}
+
+// newRefreshToken generate a random opaque refresh token
+func newRefreshToken() (string, error) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
+
+// hashToken hash the refresh token, so only hashes are stored in cache
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/adapters/repository/users"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var oauthConfig = config.Oauth{Secret: "test-secret"}
+var oauthKeys, _ = config.LoadOauthKeys(oauthConfig)
+
+// fakePipeline pipeline that writes directly in the fake cache store
+type fakePipeline struct {
+	redis.Pipeliner
+	store map[string]string
+}
+
+// Set write the value in the fake cache store
+func (fp *fakePipeline) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	fp.store[key] = toString(value)
+	return &redis.StatusCmd{}
+}
+
+func toString(value interface{}) string {
+	if bytes, ok := value.([]byte); ok {
+		return string(bytes)
+	}
+	return "1"
+}
+
+// mockCacheStore mock the redis cache with an in memory map
+func mockCacheStore(redisCache *cache.RedisCacheMock) map[string]string {
+	store := map[string]string{}
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+		store[key] = toString(value)
+		return &redis.StatusCmd{}
+	}
+	redisCache.GetFunc = func(ctx context.Context, key string) *redis.StringCmd {
+		cmd := &redis.StringCmd{}
+		if value, ok := store[key]; ok {
+			cmd.SetVal(value)
+		} else {
+			cmd.SetErr(redis.Nil)
+		}
+		return cmd
+	}
+	redisCache.SetNXFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+		cmd := &redis.BoolCmd{}
+		_, exist := store[key]
+		if !exist {
+			store[key] = toString(value)
+		}
+		cmd.SetVal(!exist)
+		return cmd
+	}
+	redisCache.DelFunc = func(ctx context.Context, keys ...string) *redis.IntCmd {
+		for _, key := range keys {
+			delete(store, key)
+		}
+		return &redis.IntCmd{}
+	}
+	redisCache.ExistsFunc = func(ctx context.Context, keys ...string) *redis.IntCmd {
+		cmd := &redis.IntCmd{}
+		var count int64
+		for _, key := range keys {
+			if _, ok := store[key]; ok {
+				count++
+			}
+		}
+		cmd.SetVal(count)
+		return cmd
+	}
+	redisCache.PipelinedFunc = func(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+		return nil, fn(&fakePipeline{store: store})
+	}
+	return store
+}
+
+func mockUser(t *testing.T, userRepository *users.UserRepositoryMock, password string, roles []string) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	assert.Nil(t, err)
+	userRepository.GetUserByUsernameFunc = func(ctx context.Context, username string) (*domain.UserModel, error) {
+		return &domain.UserModel{Username: username, PasswordHash: string(hash), Roles: roles}, nil
+	}
+}
+
+// TestCreateOauthTokenWithUserRoles for test CreateOauthToken
+func TestCreateOauthTokenWithUserRoles(t *testing.T) {
+	userRepository := &users.UserRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	service := NewAuthService(log, oauthConfig, oauthKeys, userRepository, redisCache)
+	mockCacheStore(redisCache)
+	mockUser(t, userRepository, "password123", []string{"business", "user"})
+
+	token, err := service.CreateOauthToken(defaultContext, &domain.Auth{Username: username, Password: "password123"}, traceID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.TokenType, token.TokenType)
+	assert.Equal(t, int64(900), token.ExpiresIn)
+	assert.NotEmpty(t, token.RefreshToken)
+
+	claims, err := service.ParseOauthToken(defaultContext, token.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, username, claims.Username)
+	assert.Equal(t, []string{"business", "user"}, claims.Roles)
+}
+
+// TestCreateOauthTokenWithWrongPassword for test CreateOauthToken
+func TestCreateOauthTokenWithWrongPassword(t *testing.T) {
+	userRepository := &users.UserRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	service := NewAuthService(log, oauthConfig, oauthKeys, userRepository, redisCache)
+	mockUser(t, userRepository, "password123", []string{"admin"})
+
+	_, err := service.CreateOauthToken(defaultContext, &domain.Auth{Username: username, Password: "wrong-password"}, traceID)
+	var statusError *custom_error.StatusError
+	assert.True(t, errors.As(err, &statusError))
+	assert.Equal(t, http.StatusUnauthorized, statusError.ErrorCode())
+}
+
+// TestCreateOauthTokenWithUnknownUser for test CreateOauthToken
+func TestCreateOauthTokenWithUnknownUser(t *testing.T) {
+	userRepository := &users.UserRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	service := NewAuthService(log, oauthConfig, oauthKeys, userRepository, redisCache)
+	userRepository.GetUserByUsernameFunc = func(ctx context.Context, username string) (*domain.UserModel, error) {
+		return nil, nil
+	}
+
+	_, err := service.CreateOauthToken(defaultContext, &domain.Auth{Username: username, Password: "password123"}, traceID)
+	assert.Equal(t, "invalid credentials", err.Error())
+}
+
+// TestRefreshOauthTokenReuseRevokesTheFamily for test RefreshOauthToken
+func TestRefreshOauthTokenReuseRevokesTheFamily(t *testing.T) {
+	userRepository := &users.UserRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	service := NewAuthService(log, oauthConfig, oauthKeys, userRepository, redisCache)
+	mockCacheStore(redisCache)
+	mockUser(t, userRepository, "password123", []string{"user"})
+
+	first, err := service.CreateOauthToken(defaultContext, &domain.Auth{Username: username, Password: "password123"}, traceID)
+	assert.Nil(t, err)
+
+	second, err := service.RefreshOauthToken(defaultContext, &domain.RefreshTokenRequest{RefreshToken: first.RefreshToken}, traceID)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	_, err = service.RefreshOauthToken(defaultContext, &domain.RefreshTokenRequest{RefreshToken: first.RefreshToken}, traceID)
+	assert.Equal(t, "invalid refresh token", err.Error())
+
+	_, err = service.RefreshOauthToken(defaultContext, &domain.RefreshTokenRequest{RefreshToken: second.RefreshToken}, traceID)
+	assert.Equal(t, "invalid refresh token", err.Error())
+
+	_, err = service.ParseOauthToken(defaultContext, second.AccessToken)
+	assert.Equal(t, "token revoked", err.Error())
+}
+
+// TestRevokeOauthAccessToken for test RevokeOauthToken
+func TestRevokeOauthAccessToken(t *testing.T) {
+	userRepository := &users.UserRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	service := NewAuthService(log, oauthConfig, oauthKeys, userRepository, redisCache)
+	mockCacheStore(redisCache)
+	mockUser(t, userRepository, "password123", []string{"user"})
+
+	token, err := service.CreateOauthToken(defaultContext, &domain.Auth{Username: username, Password: "password123"}, traceID)
+	assert.Nil(t, err)
+
+	err = service.RevokeOauthToken(defaultContext, &domain.RevokeTokenRequest{Token: token.AccessToken, TokenTypeHint: "access_token"}, traceID)
+	assert.Nil(t, err)
+
+	_, err = service.ParseOauthToken(defaultContext, token.AccessToken)
+	assert.Equal(t, "token revoked", err.Error())
+}
+
+// writeECKey write a new P-256 private key as PEM in the directory
+func writeECKey(t *testing.T, dir, name string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.Nil(t, err)
+	file := filepath.Join(dir, name+".pem")
+	assert.Nil(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return file
+}
+
+// TestAsymmetricOauthTokenWithKeyRotation for test CreateOauthToken and ParseOauthToken with ES256 keys
+func TestAsymmetricOauthTokenWithKeyRotation(t *testing.T) {
+	userRepository := &users.UserRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	dir := t.TempDir()
+	oldKey := config.OauthKey{ID: "old", PrivateKeyFile: writeECKey(t, dir, "old")}
+	newKey := config.OauthKey{ID: "new", PrivateKeyFile: writeECKey(t, dir, "new")}
+	mockCacheStore(redisCache)
+	mockUser(t, userRepository, "password123", []string{"user"})
+
+	oldConfig := config.Oauth{Algorithm: "ES256", SigningKeyID: "old", Keys: []config.OauthKey{oldKey}}
+	oldKeys, err := config.LoadOauthKeys(oldConfig)
+	assert.Nil(t, err)
+	oldService := NewAuthService(log, oldConfig, oldKeys, userRepository, redisCache)
+	oldToken, err := oldService.CreateOauthToken(defaultContext, &domain.Auth{Username: username, Password: "password123"}, traceID)
+	assert.Nil(t, err)
+
+	rotatedConfig := config.Oauth{Algorithm: "ES256", SigningKeyID: "new", Keys: []config.OauthKey{newKey, oldKey}}
+	rotatedKeys, err := config.LoadOauthKeys(rotatedConfig)
+	assert.Nil(t, err)
+	rotatedService := NewAuthService(log, rotatedConfig, rotatedKeys, userRepository, redisCache)
+
+	claims, err := rotatedService.ParseOauthToken(defaultContext, oldToken.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, username, claims.Username)
+
+	newToken, err := rotatedService.CreateOauthToken(defaultContext, &domain.Auth{Username: username, Password: "password123"}, traceID)
+	assert.Nil(t, err)
+	_, err = oldService.ParseOauthToken(defaultContext, newToken.AccessToken)
+	assert.Equal(t, "invalid token", err.Error())
+
+	hmacService := NewAuthService(log, oauthConfig, oauthKeys, userRepository, redisCache)
+	_, err = hmacService.ParseOauthToken(defaultContext, newToken.AccessToken)
+	assert.Equal(t, "invalid token", err.Error())
+
+	jwks := rotatedService.GetJWKS()
+	assert.Len(t, jwks.Keys, 2)
+	for _, key := range jwks.Keys {
+		assert.Equal(t, "EC", key.Kty)
+		assert.Equal(t, "P-256", key.Crv)
+		assert.Equal(t, "ES256", key.Alg)
+	}
+	assert.Empty(t, hmacService.GetJWKS().Keys)
+}
+
+// TestParseOauthTokenWithMalformedClaims for test ParseOauthToken
+func TestParseOauthTokenWithMalformedClaims(t *testing.T) {
+	userRepository := &users.UserRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	service := NewAuthService(log, oauthConfig, oauthKeys, userRepository, redisCache)
+	mockCacheStore(redisCache)
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{"iss": domain.Issuer, "jti": "token-id", "exp": time.Now().Add(time.Minute).Unix(),
+			"sub": "user-id", "username": username, "roles": []string{"user"}, "scope": "products:read products:write",
+			"tenant_id": "tenant-a", "aud": "golang-api-hexagonal"}
+	}
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(oauthConfig.Secret))
+		assert.Nil(t, err)
+		return token
+	}
+
+	claims, err := service.ParseOauthToken(defaultContext, sign(validClaims()))
+	assert.Nil(t, err)
+	assert.Equal(t, domain.AuthClaims{Subject: "user-id", Username: username, Roles: []string{"user"},
+		Scopes: []string{"products:read", "products:write"}, TenantID: "tenant-a", Audience: []string{"golang-api-hexagonal"}}, *claims)
+
+	tests := []struct {
+		name     string
+		change   func(claims jwt.MapClaims)
+		expected error
+	}{
+		{"without username and subject", func(claims jwt.MapClaims) { delete(claims, "username"); delete(claims, "sub") }, domain.ErrInvalidToken},
+		{"username is not a string", func(claims jwt.MapClaims) { claims["username"] = 42 }, domain.ErrInvalidToken},
+		{"roles is not a list of strings", func(claims jwt.MapClaims) { claims["roles"] = []interface{}{"user", 1} }, domain.ErrInvalidToken},
+		{"tenant is not a string", func(claims jwt.MapClaims) { claims["tenant_id"] = true }, domain.ErrInvalidToken},
+		{"without token id", func(claims jwt.MapClaims) { delete(claims, "jti") }, domain.ErrInvalidToken},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }, domain.ErrTokenExpired},
+		{"other issuer", func(claims jwt.MapClaims) { claims["iss"] = "other" }, domain.ErrInvalidTokenIssuer},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := validClaims()
+			test.change(claims)
+			_, err := service.ParseOauthToken(defaultContext, sign(claims))
+			assert.ErrorIs(t, err, test.expected)
+		})
+	}
+
+	_, err = service.ParseOauthToken(defaultContext, sign(jwt.MapClaims{"iss": domain.Issuer, "jti": "id", "exp": time.Now().Add(time.Minute).Unix(), "roles": "admin"}))
+	var claimError *domain.ClaimError
+	assert.True(t, errors.As(err, &claimError))
+	assert.Equal(t, domain.UsernameClaim, claimError.Claim)
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"time"
+)
+
+// CacheWarmService service to preload the products in cache
+type CacheWarmService struct {
+	log               *zap.SugaredLogger
+	productRepository ports.IRepository
+	redis             ports.IRedis
+}
+
+// NewCacheWarmService create new cache warm up service
+func NewCacheWarmService(log *zap.SugaredLogger, productRepository ports.IRepository, redis ports.IRedis) *CacheWarmService {
+	return &CacheWarmService{
+		log:               log,
+		productRepository: productRepository,
+		redis:             redis,
+	}
+}
+
+// NewCacheWarmRequest create the cache warm up request from the configuration
+func NewCacheWarmRequest(conf config.CacheWarmUpConfiguration) (*domain.CacheWarmRequest, error) {
+	request := &domain.CacheWarmRequest{
+		TenantID:      conf.Tenant,
+		Filter:        domain.ProductFilter{Status: conf.Status},
+		PageSize:      conf.PageSize,
+		RatePerSecond: conf.RatePerSecond,
+	}
+	if conf.CreatedSince != "" {
+		createdSince, err := time.Parse(time.RFC3339, conf.CreatedSince)
+		if err != nil {
+			return nil, err
+		}
+		request.Filter.CreatedSince = createdSince
+	}
+	return request, nil
+}
+
+// Warm streams the products from the repository in pages and writes them in cache
+func (cs *CacheWarmService) Warm(ctx context.Context, request *domain.CacheWarmRequest) (*domain.CacheWarmResult, error) {
+	pageSize := request.PageSize
+	if pageSize <= 0 {
+		pageSize = domain.DefaultCacheWarmPageSize
+	}
+	// Keep each pipeline inside the rate limit
+	if request.RatePerSecond > 0 && pageSize > request.RatePerSecond {
+		pageSize = request.RatePerSecond
+	}
+
+	result := &domain.CacheWarmResult{}
+	startTime := time.Now()
+	afterID := ""
+	ctx = domain.WithTenant(ctx, request.TenantID)
+
+	cs.log.Infof("Cache warm up started for tenant %s with status: %q, created since: %v, page size: %d, rate: %d/s",
+		domain.TenantFromContext(ctx), request.Filter.Status, request.Filter.CreatedSince, pageSize, request.RatePerSecond)
+
+	for {
+		page, err := cs.productRepository.ListProducts(ctx, request.Filter, afterID, pageSize)
+		if err != nil {
+			cs.log.Errorf("Internal error to load the products page: %v", err)
+			result.Duration = time.Since(startTime)
+			return result, err
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		result.Pages++
+		result.Loaded += len(page)
+		cached, failed := cs.cachePage(ctx, page)
+		result.Cached += cached
+		result.Failed += failed
+		afterID = page[len(page)-1].ID
+
+		cs.log.Infof("Cache warm up progress: %d pages, %d products loaded, %d cached, %d failed",
+			result.Pages, result.Loaded, result.Cached, result.Failed)
+
+		if len(page) < pageSize {
+			break
+		}
+
+		if err = waitForRate(ctx, startTime, result.Loaded, request.RatePerSecond); err != nil {
+			result.Duration = time.Since(startTime)
+			return result, err
+		}
+	}
+
+	result.Duration = time.Since(startTime)
+	cs.log.Infof("Cache warm up finished: %d products cached, %d failed in %v", result.Cached, result.Failed, result.Duration)
+	return result, nil
+}
+
+// cachePage writes a page of products in cache with a single pipeline
+func (cs *CacheWarmService) cachePage(ctx context.Context, page []*domain.ProductModel) (int, int) {
+	failed := 0
+	cmds, err := cs.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, product := range page {
+			data, errMarshall := json.Marshal(product)
+			if errMarshall != nil {
+				cs.log.Errorf("Internal error to marshal the productID %s: %v", product.ID, errMarshall)
+				failed++
+				continue
+			}
+			pipe.Set(ctx, cache.ProductKey(product.TenantID, product.ID), data, cache.KeyCacheDuration)
+		}
+		return nil
+	})
+	if err != nil {
+		cs.log.Errorf("Internal error to save the products page in cache: %v", err)
+	}
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			failed++
+		}
+	}
+	return len(page) - failed, failed
+}
+
+// waitForRate sleeps until the loaded products fit in the rate per second
+func waitForRate(ctx context.Context, startTime time.Time, loaded, ratePerSecond int) error {
+	if ratePerSecond <= 0 {
+		return nil
+	}
+
+	expected := time.Duration(loaded) * time.Second / time.Duration(ratePerSecond)
+	wait := expected - time.Since(startTime)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
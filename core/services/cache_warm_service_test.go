@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/adapters/repository/products"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"testing"
+)
+
+// TestWarmCacheInPages for test Warm
+func TestWarmCacheInPages(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	service := NewCacheWarmService(log, repository, redisCache)
+
+	pages := map[string][]*domain.ProductModel{
+		"":   {{ID: "1"}, {ID: "2"}},
+		"2":  {{ID: "3"}, {ID: "4"}},
+		"4":  {{ID: "5"}},
+		"99": nil,
+	}
+	var afterIDs []string
+	repository.ListProductsFunc = func(ctx context.Context, filter domain.ProductFilter, afterID string, limit int) ([]*domain.ProductModel, error) {
+		assert.Equal(t, "available", filter.Status)
+		assert.Equal(t, 2, limit)
+		afterIDs = append(afterIDs, afterID)
+		return pages[afterID], nil
+	}
+	redisCache.PipelinedFunc = func(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+		return nil, nil
+	}
+
+	result, err := service.Warm(defaultContext, &domain.CacheWarmRequest{Filter: domain.ProductFilter{Status: "available"}, PageSize: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"", "2", "4"}, afterIDs)
+	assert.Equal(t, 5, result.Loaded)
+	assert.Equal(t, 5, result.Cached)
+	assert.Equal(t, 3, result.Pages)
+}
+
+// TestWarmCacheWithRepositoryError for test Warm
+func TestWarmCacheWithRepositoryError(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	service := NewCacheWarmService(log, repository, redisCache)
+
+	repository.ListProductsFunc = func(ctx context.Context, filter domain.ProductFilter, afterID string, limit int) ([]*domain.ProductModel, error) {
+		return nil, errors.New("internal query error")
+	}
+
+	_, err := service.Warm(defaultContext, &domain.CacheWarmRequest{})
+	assert.Equal(t, "internal query error", err.Error())
+}
+
+// TestNewCacheWarmRequestWithInvalidDate for test NewCacheWarmRequest
+func TestNewCacheWarmRequestWithInvalidDate(t *testing.T) {
+	_, err := NewCacheWarmRequest(config.CacheWarmUpConfiguration{CreatedSince: "yesterday"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"io"
+)
+
+// exportFileWriter write the export file in cache keys of at most chunkBytes, only one chunk is kept in memory
+type exportFileWriter struct {
+	ctx        context.Context
+	redis      ports.IRedis
+	tenantID   string
+	jobID      string
+	chunkBytes int
+	chunk      []byte
+	chunks     int
+}
+
+// newExportFileWriter create new writer of the file of the export job
+func newExportFileWriter(ctx context.Context, redis ports.IRedis, job *domain.ProductExportJob, chunkBytes int) *exportFileWriter {
+	return &exportFileWriter{
+		ctx:        ctx,
+		redis:      redis,
+		tenantID:   job.TenantID,
+		jobID:      job.ID,
+		chunkBytes: chunkBytes,
+		chunk:      make([]byte, 0, chunkBytes),
+	}
+}
+
+// Write add the data to the chunk, the full chunks are saved in cache
+func (fw *exportFileWriter) Write(data []byte) (int, error) {
+	written := 0
+	for len(data) > 0 {
+		size := fw.chunkBytes - len(fw.chunk)
+		if size > len(data) {
+			size = len(data)
+		}
+		fw.chunk = append(fw.chunk, data[:size]...)
+		data = data[size:]
+		written += size
+		if len(fw.chunk) == fw.chunkBytes {
+			if err := fw.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close save the last chunk
+func (fw *exportFileWriter) Close() error {
+	if len(fw.chunk) == 0 {
+		return nil
+	}
+	return fw.flush()
+}
+
+// flush save the chunk in cache until the job expires
+func (fw *exportFileWriter) flush() error {
+	err := fw.redis.Set(fw.ctx, cache.ExportFileKey(fw.tenantID, fw.jobID, fw.chunks), fw.chunk, domain.ExportJobDuration).Err()
+	if err != nil {
+		return err
+	}
+	fw.chunks++
+	fw.chunk = make([]byte, 0, fw.chunkBytes)
+	return nil
+}
+
+// keys cache keys of the saved chunks
+func (fw *exportFileWriter) keys() []string {
+	keys := make([]string, 0, fw.chunks)
+	for chunk := 0; chunk < fw.chunks; chunk++ {
+		keys = append(keys, cache.ExportFileKey(fw.tenantID, fw.jobID, chunk))
+	}
+	return keys
+}
+
+// exportFileReader read the chunks of the export file in order, each chunk is loaded from cache when the previous one is read.
+// An expired chunk is the redis.Nil error
+type exportFileReader struct {
+	ctx   context.Context
+	redis ports.IRedis
+	job   *domain.ProductExportJob
+	next  int
+	chunk []byte
+}
+
+// Read read the loaded chunk, then the next one
+func (fr *exportFileReader) Read(data []byte) (int, error) {
+	for len(fr.chunk) == 0 {
+		if fr.next == fr.job.Chunks {
+			return 0, io.EOF
+		}
+		chunk, err := fr.redis.Get(fr.ctx, cache.ExportFileKey(fr.job.TenantID, fr.job.ID, fr.next)).Bytes()
+		if err != nil {
+			return 0, err
+		}
+		fr.chunk = chunk
+		fr.next++
+	}
+	read := copy(data, fr.chunk)
+	fr.chunk = fr.chunk[read:]
+	return read, nil
+}
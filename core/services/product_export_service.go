@@ -0,0 +1,192 @@
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/adapters/export"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"io"
+	"net/http"
+	"time"
+)
+
+// finishJobTimeout max duration to save the final status of an export job, the job context can be cancelled by its timeout
+const finishJobTimeout = time.Second * 10
+
+// ProductExportService product export service
+type ProductExportService struct {
+	log               *zap.SugaredLogger
+	productRepository ports.IRepository
+	redis             ports.IRedis
+	fileChunkBytes    int
+}
+
+// NewProductExportService create new product export service
+func NewProductExportService(log *zap.SugaredLogger, productRepository ports.IRepository, redis ports.IRedis) *ProductExportService {
+	return &ProductExportService{
+		log:               log,
+		productRepository: productRepository,
+		redis:             redis,
+		fileChunkBytes:    domain.ExportFileChunkBytes,
+	}
+}
+
+// CountProducts count the products of the tenant to export
+func (es *ProductExportService) CountProducts(ctx context.Context, filter domain.ProductFilter, traceID string) (int, error) {
+	count, err := es.productRepository.CountProducts(ctx, filter)
+	if err != nil {
+		es.log.With("traceId", traceID).Errorf("Internal server error to count the products: %v", err)
+		return 0, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+	return count, nil
+}
+
+// WriteProducts stream the products of the tenant to the writer in the query format, the products are read with a database cursor.
+// The errors happen while the export is written, so they can not be returned to the client
+func (es *ProductExportService) WriteProducts(ctx context.Context, query domain.ProductExportQuery, writer io.Writer, traceID string) (int, error) {
+	productWriter := export.NewProductWriter(writer, query)
+	exported := 0
+	err := es.productRepository.ExportProducts(ctx, query.Filter, func(product *domain.ProductModel) error {
+		exported++
+		return productWriter.Write(product)
+	})
+	if err == nil {
+		err = productWriter.Close()
+	}
+	if err != nil {
+		es.log.With("traceId", traceID).Errorf("Error to export the products after %d products: %v", exported, err)
+		return exported, err
+	}
+
+	es.log.With("traceId", traceID).Infof("%d products were exported with success in %s", exported, query.Format)
+	return exported, nil
+}
+
+// StartExport save the export job and write the export file in background, the request timeout does not apply to the job
+func (es *ProductExportService) StartExport(ctx context.Context, query domain.ProductExportQuery, total int, username, traceID string) (*domain.ProductExportJob, error) {
+	now := time.Now()
+	job := &domain.ProductExportJob{
+		ID:           uuid.NewString(),
+		TenantID:     domain.TenantFromContext(ctx),
+		User:         username,
+		Status:       domain.ExportRunning,
+		Format:       query.Format,
+		Total:        total,
+		CreationDate: now,
+		UpdateDate:   now,
+	}
+	if err := es.saveJob(ctx, job); err != nil {
+		es.log.With("traceId", traceID).Errorf("Internal error to save the export job: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+	response := *job
+
+	go es.runExport(domain.WithTenant(context.Background(), job.TenantID), job, query, traceID)
+
+	es.log.With("traceId", traceID).Infof("The export job %s of %d products was started by %s in tenant %s", job.ID, total, username, job.TenantID)
+	return &response, nil
+}
+
+// GetExportJob get the export job of the tenant by id
+func (es *ProductExportService) GetExportJob(ctx context.Context, jobID, traceID string) (*domain.ProductExportJob, error) {
+	payloadBytes, err := es.redis.Get(ctx, cache.ExportJobKey(domain.TenantFromContext(ctx), jobID)).Bytes()
+	if err == redis.Nil {
+		es.log.With("traceId", traceID).Errorf("Export job %s not found", jobID)
+		return nil, custom_error.New(http.StatusNotFound, "not found")
+	} else if err != nil {
+		es.log.With("traceId", traceID).Errorf("Internal error to get the export job: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	job := &domain.ProductExportJob{}
+	if err = json.Unmarshal(payloadBytes, job); err != nil {
+		es.log.With("traceId", traceID).Errorf("Internal error unmarshal the export job: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+	return job, nil
+}
+
+// OpenExportFile open the file of the completed export job, the chunks of the file are read from cache while it is read
+func (es *ProductExportService) OpenExportFile(ctx context.Context, job *domain.ProductExportJob, traceID string) (io.Reader, error) {
+	if job.Status != domain.ExportCompleted {
+		es.log.With("traceId", traceID).Errorf("The export job %s is %s", job.ID, job.Status)
+		return nil, custom_error.New(http.StatusConflict, "the export is not completed")
+	}
+
+	reader, err := gzip.NewReader(&exportFileReader{ctx: ctx, redis: es.redis, job: job})
+	if err == redis.Nil || err == io.EOF {
+		es.log.With("traceId", traceID).Errorf("The file of the export job %s expired", job.ID)
+		return nil, custom_error.New(http.StatusNotFound, "not found")
+	} else if err != nil {
+		es.log.With("traceId", traceID).Errorf("Internal error to read the export file: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+	return reader, nil
+}
+
+// runExport write the compressed export file in cache and complete the job
+func (es *ProductExportService) runExport(ctx context.Context, job *domain.ProductExportJob, query domain.ProductExportQuery, traceID string) {
+	ctx, cancel := context.WithTimeout(ctx, domain.ExportJobTimeout)
+	defer cancel()
+	file := newExportFileWriter(ctx, es.redis, job, es.fileChunkBytes)
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			es.log.With("traceId", traceID).Errorf("The export job %s failed: %v", job.ID, recovered)
+			es.finishJob(job, file, 0, custom_error.New(http.StatusInternalServerError, "internal server error"), traceID)
+		}
+	}()
+
+	compressed := gzip.NewWriter(file)
+	exported, err := es.WriteProducts(ctx, query, compressed, traceID)
+	if err == nil {
+		err = compressed.Close()
+	}
+	if err == nil {
+		err = file.Close()
+	}
+	es.finishJob(job, file, exported, err, traceID)
+}
+
+// finishJob save the final status of the export job with the chunks of its file, the chunks of a failed job are removed.
+// The job context can be cancelled by its timeout, so the status is saved with a new context
+func (es *ProductExportService) finishJob(job *domain.ProductExportJob, file *exportFileWriter, exported int, err error, traceID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), finishJobTimeout)
+	defer cancel()
+
+	job.Exported = exported
+	job.Status = domain.ExportCompleted
+	job.Chunks = file.chunks
+	if err != nil {
+		es.log.With("traceId", traceID).Errorf("The export job %s failed: %v", job.ID, err)
+		job.Status = domain.ExportFailed
+		job.Error = "internal server error"
+		job.Chunks = 0
+		if keys := file.keys(); len(keys) > 0 {
+			if errDel := es.redis.Del(ctx, keys...).Err(); errDel != nil {
+				es.log.With("traceId", traceID).Errorf("Internal error to remove the file of the export job %s: %v", job.ID, errDel)
+			}
+		}
+	}
+	job.UpdateDate = time.Now()
+	if err = es.saveJob(ctx, job); err != nil {
+		es.log.With("traceId", traceID).Errorf("Internal error to save the export job %s: %v", job.ID, err)
+		return
+	}
+	es.log.With("traceId", traceID).Infof("The export job %s is %s with %d products", job.ID, job.Status, exported)
+}
+
+// saveJob save the export job in cache until it expires
+func (es *ProductExportService) saveJob(ctx context.Context, job *domain.ProductExportJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return es.redis.Set(ctx, cache.ExportJobKey(job.TenantID, job.ID), data, domain.ExportJobDuration).Err()
+}
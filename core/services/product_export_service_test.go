@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/adapters/repository/products"
+	"golang-api-hexagonal/core/domain"
+	"io"
+	"testing"
+	"time"
+)
+
+// TestExportJobWritesTheFile for test the asynchronous export and the download of its file
+func TestExportJobWritesTheFile(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	service := NewProductExportService(log, repository, redisCache)
+	service.fileChunkBytes = 16
+
+	repository.ExportProductsFunc = func(ctx context.Context, filter domain.ProductFilter, fn func(product *domain.ProductModel) error) error {
+		assert.Equal(t, "available", filter.Status)
+		for _, id := range []string{"1", "2"} {
+			if err := fn(&domain.ProductModel{ID: id, Name: "product " + id}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	store := map[string][]byte{}
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+		store[key] = value.([]byte)
+		return redis.NewStatusCmd(ctx)
+	}
+	redisCache.GetFunc = func(ctx context.Context, key string) *redis.StringCmd {
+		cmd := redis.NewStringCmd(ctx)
+		cmd.SetVal(string(store[key]))
+		return cmd
+	}
+
+	query := domain.ProductExportQuery{Filter: domain.ProductFilter{Status: "available"}, Format: domain.ExportCSV, Columns: []string{"id", "name"}}
+	job := &domain.ProductExportJob{ID: "job-1", TenantID: "tenant-a", Status: domain.ExportRunning, Format: domain.ExportCSV, Total: 2}
+	service.runExport(domain.WithTenant(context.Background(), "tenant-a"), job, query, traceID)
+
+	assert.Equal(t, domain.ExportCompleted, job.Status)
+	assert.Equal(t, 2, job.Exported)
+	assert.Contains(t, store, "tenant-a:export:job-1")
+	assert.Greater(t, job.Chunks, 1)
+	for chunk := 0; chunk < job.Chunks; chunk++ {
+		assert.LessOrEqual(t, len(store[cache.ExportFileKey("tenant-a", "job-1", chunk)]), 16)
+	}
+
+	file, err := service.OpenExportFile(defaultContext, job, traceID)
+	assert.NoError(t, err)
+	content, err := io.ReadAll(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "id,name\n1,product 1\n2,product 2\n", string(content))
+}
+
+// TestExportJobTimeoutSavesTheFailedJob for test the final status of a job cancelled by its timeout
+func TestExportJobTimeoutSavesTheFailedJob(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	service := NewProductExportService(log, repository, redisCache)
+
+	repository.ExportProductsFunc = func(ctx context.Context, filter domain.ProductFilter, fn func(product *domain.ProductModel) error) error {
+		return ctx.Err()
+	}
+	saved := &domain.ProductExportJob{}
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+		assert.NoError(t, ctx.Err())
+		assert.Nil(t, json.Unmarshal(value.([]byte), saved))
+		return redis.NewStatusCmd(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(domain.WithTenant(context.Background(), "tenant-a"))
+	cancel()
+	job := &domain.ProductExportJob{ID: "job-1", TenantID: "tenant-a", Status: domain.ExportRunning, Format: domain.ExportCSV}
+	service.runExport(ctx, job, domain.ProductExportQuery{Format: domain.ExportCSV}, traceID)
+
+	assert.Equal(t, domain.ExportFailed, saved.Status)
+	assert.Equal(t, 0, saved.Chunks)
+}
+
+// TestOpenExportFileOfRunningJob for test OpenExportFile
+func TestOpenExportFileOfRunningJob(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	service := NewProductExportService(log, repository, redisCache)
+
+	_, err := service.OpenExportFile(defaultContext, &domain.ProductExportJob{ID: "job-1", Status: domain.ExportRunning}, traceID)
+	assert.Equal(t, "the export is not completed", err.Error())
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ProductImportService product bulk import service
+type ProductImportService struct {
+	log               *zap.SugaredLogger
+	productRepository ports.IRepository
+	redis             ports.IRedis
+	message           ports.IMessage
+	messageConfig     config.KafkaConfiguration
+}
+
+// importedProduct product of an import row ready to be inserted
+type importedProduct struct {
+	row   int
+	model *domain.ProductModel
+	audit *domain.ProductAuditModel
+}
+
+// NewProductImportService create new product import service
+func NewProductImportService(log *zap.SugaredLogger, productRepository ports.IRepository, redis ports.IRedis, message ports.IMessage,
+	messageConfig config.KafkaConfiguration) *ProductImportService {
+	return &ProductImportService{
+		log:               log,
+		productRepository: productRepository,
+		redis:             redis,
+		message:           message,
+		messageConfig:     messageConfig,
+	}
+}
+
+// StartImport save the import job and import the rows in background, the job status is updated after each batch
+func (is *ProductImportService) StartImport(ctx context.Context, rows []domain.ProductImportRow, username, traceID string) (*domain.ProductImportJob, error) {
+	tenantID := domain.TenantFromContext(ctx)
+	job := domain.NewProductImportJob(tenantID, username, len(rows))
+	if err := is.saveJob(ctx, job); err != nil {
+		is.log.With("traceId", traceID).Errorf("Internal error to save the import job: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+	response := *job
+
+	// the import outlives the request, so it only keeps the tenant of the request context
+	go is.runImport(domain.WithTenant(context.Background(), tenantID), job, rows, traceID)
+
+	is.log.With("traceId", traceID).Infof("The import job %s of %d products was started by %s in tenant %s", job.ID, len(rows), username, tenantID)
+	return &response, nil
+}
+
+// GetImportJob get the import job of the tenant by id
+func (is *ProductImportService) GetImportJob(ctx context.Context, jobID, traceID string) (*domain.ProductImportJob, error) {
+	payloadBytes, err := is.redis.Get(ctx, cache.ImportJobKey(domain.TenantFromContext(ctx), jobID)).Bytes()
+	if err == redis.Nil {
+		is.log.With("traceId", traceID).Errorf("Import job %s not found", jobID)
+		return nil, custom_error.New(http.StatusNotFound, "not found")
+	} else if err != nil {
+		is.log.With("traceId", traceID).Errorf("Internal error to get the import job: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	job := &domain.ProductImportJob{}
+	if err = json.Unmarshal(payloadBytes, job); err != nil {
+		is.log.With("traceId", traceID).Errorf("Internal error unmarshal the import job: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+	return job, nil
+}
+
+// runImport import the rows by batches, each batch is inserted in a single transaction
+func (is *ProductImportService) runImport(ctx context.Context, job *domain.ProductImportJob, rows []domain.ProductImportRow, traceID string) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			is.log.With("traceId", traceID).Errorf("The import job %s failed: %v", job.ID, recovered)
+			job.Status = domain.ImportFailed
+			is.updateJob(ctx, job, traceID)
+		}
+	}()
+
+	identities := map[string]bool{}
+	for start := 0; start < len(rows); start += domain.ImportBatchSize {
+		end := start + domain.ImportBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		batch := make([]importedProduct, 0, end-start)
+		for _, row := range rows[start:end] {
+			product, message := is.prepareRow(ctx, row, job.User, identities, traceID)
+			if message != "" {
+				job.AddError(row.Row, message)
+				continue
+			}
+			batch = append(batch, product)
+		}
+		is.insertBatch(ctx, job, batch, traceID)
+
+		job.Processed = end
+		is.updateJob(ctx, job, traceID)
+	}
+
+	job.Status = domain.ImportCompleted
+	is.updateJob(ctx, job, traceID)
+	is.log.With("traceId", traceID).Infof("The import job %s was completed: %d products created, %d failed", job.ID, job.Created, job.Failed)
+}
+
+// prepareRow check the row like the creation of a product, the message is the error of the row
+func (is *ProductImportService) prepareRow(ctx context.Context, row domain.ProductImportRow, username string, identities map[string]bool, traceID string) (importedProduct, string) {
+	if row.Error != "" {
+		return importedProduct{}, row.Error
+	}
+	request := row.Product
+	if !domain.IsProductInitialStatus(request.Status) {
+		return importedProduct{}, fmt.Sprintf("invalid initial status %s, allowed: %s",
+			request.Status, strings.Join(domain.AllowedProductStatuses(""), ", "))
+	}
+	identity := domain.ProductIdentity(request)
+	if identities[identity] {
+		return importedProduct{}, "duplicated in the import"
+	}
+	exist, err := is.productRepository.ProductAlreadyExist(ctx,
+		request.Name, request.UnitType, request.Unit, request.Brand, request.Color, request.Style)
+	if err != nil {
+		is.log.With("traceId", traceID).Errorf("Internal server error: %v", err)
+		return importedProduct{}, "internal server error"
+	} else if exist {
+		return importedProduct{}, "already exist"
+	}
+	identities[identity] = true
+
+	model := domain.FromProductToProductModel(request, username, domain.TenantFromContext(ctx))
+	return importedProduct{
+		row:   row.Row,
+		model: model,
+		audit: domain.NewProductAudit(domain.ProductCreatedAudit, nil, model, username, traceID),
+	}, ""
+}
+
+// insertBatch insert the products of the batch in a transaction, when the transaction fails the products are inserted one by one
+// so only the failing rows are reported
+func (is *ProductImportService) insertBatch(ctx context.Context, job *domain.ProductImportJob, batch []importedProduct, traceID string) {
+	if len(batch) == 0 {
+		return
+	}
+	models := make([]*domain.ProductModel, 0, len(batch))
+	audits := make([]*domain.ProductAuditModel, 0, len(batch))
+	for _, product := range batch {
+		models = append(models, product.model)
+		audits = append(audits, product.audit)
+	}
+
+	err := is.productRepository.CreateBatch(ctx, models, audits)
+	if err == nil {
+		is.created(ctx, job, models, traceID)
+		return
+	}
+	is.log.With("traceId", traceID).Errorf("Internal error to insert the import batch, retrying by product: %v", err)
+	if len(batch) == 1 {
+		job.AddError(batch[0].row, "internal server error")
+		return
+	}
+	for _, product := range batch {
+		is.insertBatch(ctx, job, []importedProduct{product}, traceID)
+	}
+}
+
+// created cache the created products and publish their creation events
+func (is *ProductImportService) created(ctx context.Context, job *domain.ProductImportJob, models []*domain.ProductModel, traceID string) {
+	job.Created += len(models)
+	payloads := make(map[string][]byte, len(models))
+	for _, model := range models {
+		data, errMarshall := json.Marshal(model)
+		if errMarshall != nil {
+			is.log.With("traceId", traceID).Errorf("Internal error to marshal the productID %s: %v", model.ID, errMarshall)
+			continue
+		}
+		payloads[model.ID] = data
+	}
+
+	_, err := is.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for productID, data := range payloads {
+			pipe.Set(ctx, cache.ProductKey(job.TenantID, productID), data, cache.KeyCacheDuration)
+		}
+		return nil
+	})
+	if err != nil {
+		is.log.With("traceId", traceID).Errorf("Internal error to save the imported products in cache: %v", err)
+	}
+
+	for _, model := range models {
+		payload, ok := payloads[model.ID]
+		if !ok {
+			continue
+		}
+		is.message.ProduceMessage(is.messageConfig.Producer.ProductTopic, string(payload), domain.ProductEventName, job.TenantID, traceID)
+	}
+}
+
+// updateJob save the progress of the import job, the errors are only logged so the import continues
+func (is *ProductImportService) updateJob(ctx context.Context, job *domain.ProductImportJob, traceID string) {
+	job.UpdateDate = time.Now()
+	if err := is.saveJob(ctx, job); err != nil {
+		is.log.With("traceId", traceID).Errorf("Internal error to save the import job %s: %v", job.ID, err)
+	}
+}
+
+// saveJob save the import job in cache until it expires
+func (is *ProductImportService) saveJob(ctx context.Context, job *domain.ProductImportJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return is.redis.Set(ctx, cache.ImportJobKey(job.TenantID, job.ID), data, domain.ImportJobDuration).Err()
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/adapters/kafka"
+	"golang-api-hexagonal/adapters/repository/products"
+	"golang-api-hexagonal/config"
+	"golang-api-hexagonal/core/domain"
+	"testing"
+	"time"
+)
+
+// TestRunImportWithRowErrors for test the per row error report and the retry by product of a failed batch
+func TestRunImportWithRowErrors(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductImportService(log, repository, redisCache, producer, config.KafkaConfiguration{})
+
+	repository.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
+		return name == "existing", nil
+	}
+	batches := 0
+	repository.CreateBatchFunc = func(ctx context.Context, models []*domain.ProductModel, audits []*domain.ProductAuditModel) error {
+		batches++
+		for _, model := range models {
+			if model.Name == "conflict" {
+				return errors.New("duplicate key value")
+			}
+		}
+		return nil
+	}
+	redisCache.PipelinedFunc = func(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+		return nil, nil
+	}
+	saved := &domain.ProductImportJob{}
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+		assert.Equal(t, "tenant-a:import:job-1", key)
+		_ = json.Unmarshal(value.([]byte), saved)
+		return redis.NewStatusCmd(ctx)
+	}
+	var events []string
+	producer.ProduceMessageFunc = func(topicName, value, eventName, tenantID, traceID string) {
+		events = append(events, eventName)
+	}
+
+	rows := []domain.ProductImportRow{
+		{Row: 2, Product: &domain.Product{Name: "first", Status: domain.ProductPending}},
+		{Row: 3, Error: "validation errors: Unit is required"},
+		{Row: 4, Product: &domain.Product{Name: "existing", Status: domain.ProductPending}},
+		{Row: 5, Product: &domain.Product{Name: "first", Status: domain.ProductPending}},
+		{Row: 6, Product: &domain.Product{Name: "inactive", Status: domain.ProductInactive}},
+		{Row: 7, Product: &domain.Product{Name: "conflict", Status: domain.ProductAvailable}},
+		{Row: 8, Product: &domain.Product{Name: "second", Status: domain.ProductAvailable}},
+	}
+	job := &domain.ProductImportJob{ID: "job-1", TenantID: "tenant-a", User: username, Status: domain.ImportRunning, Total: len(rows)}
+	service.runImport(domain.WithTenant(context.Background(), "tenant-a"), job, rows, traceID)
+
+	assert.Equal(t, domain.ImportCompleted, saved.Status)
+	assert.Equal(t, 7, saved.Processed)
+	assert.Equal(t, 2, saved.Created)
+	assert.Equal(t, 5, saved.Failed)
+	assert.Equal(t, []domain.ProductImportError{
+		{Row: 3, Message: "validation errors: Unit is required"},
+		{Row: 4, Message: "already exist"},
+		{Row: 5, Message: "duplicated in the import"},
+		{Row: 6, Message: "invalid initial status inactive, allowed: pending, available"},
+		{Row: 7, Message: "internal server error"},
+	}, saved.Errors)
+	assert.Equal(t, 4, batches)
+	assert.Equal(t, []string{domain.ProductEventName, domain.ProductEventName}, events)
+}
+
+// TestGetImportJobNotFound for test GetImportJob
+func TestGetImportJobNotFound(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductImportService(log, repository, redisCache, producer, config.KafkaConfiguration{})
+
+	redisCache.GetFunc = func(ctx context.Context, key string) *redis.StringCmd {
+		cmd := redis.NewStringCmd(ctx)
+		cmd.SetErr(redis.Nil)
+		return cmd
+	}
+
+	_, err := service.GetImportJob(defaultContext, "job-1", traceID)
+	assert.Equal(t, "not found", err.Error())
+}
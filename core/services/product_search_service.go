@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"net/http"
+)
+
+// ProductSearchService product search service
+type ProductSearchService struct {
+	log    *zap.SugaredLogger
+	search ports.IProductSearch
+}
+
+// NewProductSearchService create new product search service
+func NewProductSearchService(log *zap.SugaredLogger, search ports.IProductSearch) *ProductSearchService {
+	return &ProductSearchService{
+		log:    log,
+		search: search,
+	}
+}
+
+// SearchProducts search a page of products of the tenant with the facet counts
+func (pss *ProductSearchService) SearchProducts(ctx context.Context, query domain.ProductSearchQuery, traceID string) (*domain.ProductSearchResponse, error) {
+	result, err := pss.search.SearchProducts(ctx, query)
+	if errors.Is(err, domain.ErrInvalidSearchCursor) {
+		pss.log.With("traceId", traceID).Errorf("Invalid search cursor: %s", query.Cursor)
+		return nil, custom_error.New(http.StatusBadRequest, "invalid cursor")
+	} else if err != nil {
+		pss.log.With("traceId", traceID).Errorf("Internal server error to search the products: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	pss.log.With("traceId", traceID).Infof("%d products were found with success", len(result.Products))
+	return domain.FromProductSearchResultToProductSearchResponse(result), nil
+}
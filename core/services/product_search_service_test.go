@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang-api-hexagonal/adapters/search"
+	"golang-api-hexagonal/core/domain"
+	"testing"
+)
+
+// TestSearchProductsWithInvalidCursor for test SearchProducts
+func TestSearchProductsWithInvalidCursor(t *testing.T) {
+	productSearch := &search.ProductSearchMock{}
+	service := NewProductSearchService(log, productSearch)
+
+	productSearch.SearchProductsFunc = func(ctx context.Context, query domain.ProductSearchQuery) (*domain.ProductSearchResult, error) {
+		return nil, domain.ErrInvalidSearchCursor
+	}
+
+	_, err := service.SearchProducts(defaultContext, domain.ProductSearchQuery{Cursor: "abc"}, traceID)
+	assert.Equal(t, "invalid cursor", err.Error())
+}
+
+// TestSearchProductsWithSuccess for test SearchProducts
+func TestSearchProductsWithSuccess(t *testing.T) {
+	productSearch := &search.ProductSearchMock{}
+	service := NewProductSearchService(log, productSearch)
+
+	productSearch.SearchProductsFunc = func(ctx context.Context, query domain.ProductSearchQuery) (*domain.ProductSearchResult, error) {
+		return &domain.ProductSearchResult{
+			Products:   []*domain.ProductModel{{ID: "1", Name: "red shoes"}},
+			Facets:     map[string][]domain.FacetCount{"brand": {{Value: "acme", Count: 1}}},
+			NextCursor: "next",
+		}, nil
+	}
+
+	response, err := service.SearchProducts(defaultContext, domain.ProductSearchQuery{Text: "shoes"}, traceID)
+	assert.Nil(t, err)
+	assert.Equal(t, "red shoes", response.Items[0].Name)
+	assert.Equal(t, int64(1), response.Facets["brand"][0].Count)
+	assert.Equal(t, "next", response.NextCursor)
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
 	"golang-api-hexagonal/adapters/cache"
 	"golang-api-hexagonal/adapters/custom_error"
@@ -35,6 +36,11 @@ func NewProductService(log *zap.SugaredLogger, productRepository ports.IReposito
 
 // CreateProduct service to create the product
 func (ps *ProductService) CreateProduct(ctx context.Context, request *domain.Product, username, traceID string) (*domain.ProductResponse, error) {
+	tenantID := domain.TenantFromContext(ctx)
+	if !domain.IsProductInitialStatus(request.Status) {
+		ps.log.With("traceId", traceID).Errorf("Invalid initial product status %s", request.Status)
+		return nil, custom_error.NewTransitionError("", request.Status, domain.AllowedProductStatuses(""))
+	}
 	exist, err := ps.productRepository.ProductAlreadyExist(ctx,
 		request.Name, request.UnitType, request.Unit, request.Brand, request.Color, request.Style)
 	if err != nil {
@@ -45,9 +51,10 @@ func (ps *ProductService) CreateProduct(ctx context.Context, request *domain.Pro
 		return nil, custom_error.New(http.StatusConflict, "already exist")
 	}
 
-	productModel := domain.FromProductToProductModel(request, username)
+	productModel := domain.FromProductToProductModel(request, username, tenantID)
 
-	_, err = ps.productRepository.Create(ctx, productModel)
+	audit := domain.NewProductAudit(domain.ProductCreatedAudit, nil, productModel, username, traceID)
+	_, err = ps.productRepository.Create(ctx, productModel, audit)
 	if err != nil {
 		ps.log.With("traceId", traceID).Errorf("Internal server error: %v", err)
 		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
@@ -57,15 +64,15 @@ func (ps *ProductService) CreateProduct(ctx context.Context, request *domain.Pro
 	if errMarshall != nil {
 		ps.log.With("traceId", traceID).Errorf("Internal error to marshal the payload: %v", errMarshall)
 	} else {
-		errCache := ps.redis.Set(ctx, productModel.ID, data, cache.KeyCacheDuration).Err()
+		errCache := ps.redis.Set(ctx, cache.ProductKey(tenantID, productModel.ID), data, cache.KeyCacheDuration).Err()
 		if errCache != nil {
 			ps.log.With("traceId", traceID).Errorf("Internal error to save in cache: %v", errCache)
 		}
 	}
 
-	ps.message.ProduceMessage(ps.messageConfig.Producer.ProductTopic, string(data), domain.ProductEventName, traceID)
+	ps.message.ProduceMessage(ps.messageConfig.Producer.ProductTopic, string(data), domain.ProductEventName, tenantID, traceID)
 
-	ps.log.With("traceId", traceID).Infof("The productID %s was created with success", productModel.ID)
+	ps.log.With("traceId", traceID).Infof("The productID %s was created with success in tenant %s", productModel.ID, tenantID)
 	return &domain.ProductResponse{ID: productModel.ID}, nil
 }
 
@@ -73,19 +80,43 @@ func (ps *ProductService) CreateProduct(ctx context.Context, request *domain.Pro
 func (ps *ProductService) GetProduct(ctx context.Context, productID, traceID string) (*domain.ProductResponse, error) {
 	var product *domain.ProductModel
 
-	payloadBytes, errCache := ps.redis.Get(ctx, productID).Bytes()
+	payloadBytes, errCache := ps.redis.Get(ctx, cache.ProductKey(domain.TenantFromContext(ctx), productID)).Bytes()
 	if errCache != nil {
 		ps.log.With("traceId", traceID).Infof("productID not found in cache: %v", errCache)
 	} else {
 		errMar := json.Unmarshal(payloadBytes, &product)
 		if errMar != nil {
 			ps.log.With("traceId", traceID).Errorf("Internal error unmarshal the payload: %v", errMar)
+		} else if product.Version == 0 {
+			// cached before the product versions, the version is required for the ETag
+			ps.log.With("traceId", traceID).Infof("The productID %s was found in cache without version", product.ID)
 		} else {
 			ps.log.With("traceId", traceID).Infof("The productID %s was found with success in cache", product.ID)
 			return domain.FromProductModelToProductResponse(product), nil
 		}
 	}
 
+	productModel, err := ps.loadProduct(ctx, productID, traceID)
+	if err != nil {
+		return nil, err
+	}
+	return domain.FromProductModelToProductResponse(productModel), nil
+}
+
+// ReloadProduct get the product by id from the database and replace it in cache, the mutations load their product from the
+// database so the policy and the update use the current version, not a stale cache entry
+func (ps *ProductService) ReloadProduct(ctx context.Context, productID, traceID string) (*domain.ProductResponse, error) {
+	productModel, err := ps.loadProduct(ctx, productID, traceID)
+	if err != nil {
+		ps.evict(ctx, productID, traceID)
+		return nil, err
+	}
+	ps.backfill(ctx, []*domain.ProductModel{productModel}, traceID)
+	return domain.FromProductModelToProductResponse(productModel), nil
+}
+
+// loadProduct get the product by id from the database
+func (ps *ProductService) loadProduct(ctx context.Context, productID, traceID string) (*domain.ProductModel, error) {
 	productModel, err := ps.productRepository.GetProductById(ctx, productID)
 	if err != nil {
 		ps.log.With("traceId", traceID).Errorf("Internal server error to get the product: %v", err)
@@ -97,5 +128,218 @@ func (ps *ProductService) GetProduct(ctx context.Context, productID, traceID str
 	}
 
 	ps.log.With("traceId", traceID).Infof("The productID %s was found with success", productModel.ID)
+	return productModel, nil
+}
+
+// evict remove the product from cache, so the next read loads it from the database
+func (ps *ProductService) evict(ctx context.Context, productID, traceID string) {
+	if err := ps.redis.Del(ctx, cache.ProductKey(domain.TenantFromContext(ctx), productID)).Err(); err != nil {
+		ps.log.With("traceId", traceID).Errorf("Internal error to remove the productID %s from cache: %v", productID, err)
+	}
+}
+
+// GetProducts get the products by ids in the order of the ids, the cache hits are read with a single MGET,
+// the misses with a single query and then written in cache with a pipeline
+func (ps *ProductService) GetProducts(ctx context.Context, productIDs []string, traceID string) (*domain.ProductBatchGetResponse, error) {
+	tenantID := domain.TenantFromContext(ctx)
+	products := make(map[string]*domain.ProductModel, len(productIDs))
+	uniqueIDs := make([]string, 0, len(productIDs))
+	keys := make([]string, 0, len(productIDs))
+	for _, productID := range productIDs {
+		if _, ok := products[productID]; !ok {
+			products[productID] = nil
+			uniqueIDs = append(uniqueIDs, productID)
+			keys = append(keys, cache.ProductKey(tenantID, productID))
+		}
+	}
+
+	values, errCache := ps.redis.MGet(ctx, keys...).Result()
+	if errCache != nil {
+		ps.log.With("traceId", traceID).Errorf("Internal error to get the products in cache: %v", errCache)
+	}
+	misses := make([]string, 0, len(keys))
+	for index, productID := range uniqueIDs {
+		if payload, ok := cacheValue(values, index); ok {
+			product := &domain.ProductModel{}
+			errMar := json.Unmarshal([]byte(payload), product)
+			if errMar == nil && product.Version != 0 {
+				products[productID] = product
+				continue
+			} else if errMar != nil {
+				ps.log.With("traceId", traceID).Errorf("Internal error unmarshal the payload: %v", errMar)
+			}
+		}
+		misses = append(misses, productID)
+	}
+
+	if len(misses) > 0 {
+		models, err := ps.productRepository.GetProductsByIds(ctx, misses)
+		if err != nil {
+			ps.log.With("traceId", traceID).Errorf("Internal server error to get the products: %v", err)
+			return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+		}
+		for _, model := range models {
+			products[model.ID] = model
+		}
+		ps.backfill(ctx, models, traceID)
+	}
+
+	response := &domain.ProductBatchGetResponse{Items: make([]*domain.ProductBatchItem, 0, len(productIDs))}
+	for _, productID := range productIDs {
+		item := &domain.ProductBatchItem{ID: productID, Error: "not found"}
+		if product := products[productID]; product != nil {
+			item = &domain.ProductBatchItem{ID: productID, Found: true, Product: domain.FromProductModelToProductResponse(product)}
+		}
+		response.Items = append(response.Items, item)
+	}
+
+	ps.log.With("traceId", traceID).Infof("%d products were requested, %d found in cache and %d in the database",
+		len(productIDs), len(keys)-len(misses), len(misses))
+	return response, nil
+}
+
+// backfill write the products loaded from the database in cache with a single pipeline
+func (ps *ProductService) backfill(ctx context.Context, models []*domain.ProductModel, traceID string) {
+	if len(models) == 0 {
+		return
+	}
+	_, err := ps.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, model := range models {
+			data, errMarshall := json.Marshal(model)
+			if errMarshall != nil {
+				ps.log.With("traceId", traceID).Errorf("Internal error to marshal the productID %s: %v", model.ID, errMarshall)
+				continue
+			}
+			pipe.Set(ctx, cache.ProductKey(model.TenantID, model.ID), data, cache.KeyCacheDuration)
+		}
+		return nil
+	})
+	if err != nil {
+		ps.log.With("traceId", traceID).Errorf("Internal error to save the products in cache: %v", err)
+	}
+}
+
+// cacheValue the MGET value of the key index, the missing keys are nil
+func cacheValue(values []interface{}, index int) (string, bool) {
+	if index >= len(values) {
+		return "", false
+	}
+	value, ok := values[index].(string)
+	return value, ok
+}
+
+// UpdateProduct replace the loaded product by the request, the product is only updated when it was not modified since it was loaded
+func (ps *ProductService) UpdateProduct(ctx context.Context, product *domain.ProductResponse, request *domain.Product, username, traceID string) (*domain.ProductResponse, error) {
+	tenantID := domain.TenantFromContext(ctx)
+	if request.Status != product.Status {
+		ps.log.With("traceId", traceID).Errorf("The product status can not be updated from %s to %s", product.Status, request.Status)
+		return nil, custom_error.New(http.StatusConflict, domain.StatusNotUpdatedMessage)
+	}
+	if request.Name != product.Name || request.UnitType != product.UnitType || request.Unit != product.Unit ||
+		request.Brand != product.Brand || request.Color != product.Color || request.Style != product.Style {
+		exist, err := ps.productRepository.ProductAlreadyExist(ctx,
+			request.Name, request.UnitType, request.Unit, request.Brand, request.Color, request.Style)
+		if err != nil {
+			ps.log.With("traceId", traceID).Errorf("Internal server error: %v", err)
+			return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+		} else if exist {
+			ps.log.With("traceId", traceID).Errorf("Product already exist")
+			return nil, custom_error.New(http.StatusConflict, "already exist")
+		}
+	}
+
+	productModel := domain.FromProductToUpdatedProductModel(request, product)
+	audit := domain.NewProductAudit(domain.ProductUpdatedAudit, product, productModel, username, traceID)
+	updated, err := ps.productRepository.Update(ctx, productModel, product.Version, audit)
+	if err != nil {
+		ps.log.With("traceId", traceID).Errorf("Internal server error to update the product: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	} else if !updated {
+		// the loaded product can be a stale cache entry, it is removed so the retries load the current version
+		ps.log.With("traceId", traceID).Errorf("The productID %s version %d was modified", product.ID, product.Version)
+		ps.evict(ctx, product.ID, traceID)
+		return nil, custom_error.New(http.StatusPreconditionFailed, "the product was modified")
+	}
+
+	data, errMarshall := json.Marshal(productModel)
+	if errMarshall != nil {
+		ps.log.With("traceId", traceID).Errorf("Internal error to marshal the payload: %v", errMarshall)
+	} else {
+		errCache := ps.redis.Set(ctx, cache.ProductKey(tenantID, productModel.ID), data, cache.KeyCacheDuration).Err()
+		if errCache != nil {
+			ps.log.With("traceId", traceID).Errorf("Internal error to save in cache: %v", errCache)
+		}
+	}
+
+	ps.message.ProduceMessage(ps.messageConfig.Producer.ProductTopic, string(data), domain.ProductUpdatedEventName, tenantID, traceID)
+
+	ps.log.With("traceId", traceID).Infof("The productID %s was updated to version %d by %s", productModel.ID, productModel.Version, username)
+	return domain.FromProductModelToProductResponse(productModel), nil
+}
+
+// GetProductHistory get a page of the product audit trail, newest first, the cursor is the next cursor of the previous page
+func (ps *ProductService) GetProductHistory(ctx context.Context, productID string, cursor int64, limit int, traceID string) (*domain.ProductHistoryResponse, error) {
+	history, err := ps.productRepository.GetProductHistory(ctx, productID, cursor, limit+1)
+	if err != nil {
+		ps.log.With("traceId", traceID).Errorf("Internal server error to get the product history: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	ps.log.With("traceId", traceID).Infof("The history of the productID %s was found with success", productID)
+	return domain.FromProductAuditModelsToProductHistoryResponse(history, limit), nil
+}
+
+// ChangeProductStatus change the status of the loaded product when the transition is allowed, the transition emits its kafka event
+func (ps *ProductService) ChangeProductStatus(ctx context.Context, product *domain.ProductResponse, request *domain.ProductStatusChange, username, traceID string) (*domain.ProductResponse, error) {
+	tenantID := domain.TenantFromContext(ctx)
+	transition, ok := domain.FindProductStatusTransition(product.Status, request.Status)
+	if !ok {
+		ps.log.With("traceId", traceID).Errorf("Invalid status transition of the productID %s from %s to %s", product.ID, product.Status, request.Status)
+		return nil, custom_error.NewTransitionError(product.Status, request.Status, domain.AllowedProductStatuses(product.Status))
+	}
+
+	productRequest := domain.FromProductResponseToProduct(product)
+	productRequest.Status = request.Status
+	productModel := domain.FromProductToUpdatedProductModel(productRequest, product)
+	audit := domain.NewProductAudit(domain.ProductStatusChangedAudit, product, productModel, username, traceID)
+	audit.Reason = request.Reason
+	updated, err := ps.productRepository.Update(ctx, productModel, product.Version, audit)
+	if err != nil {
+		ps.log.With("traceId", traceID).Errorf("Internal server error to change the product status: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	} else if !updated {
+		// the loaded product can be a stale cache entry, it is removed so the retries load the current version
+		ps.log.With("traceId", traceID).Errorf("The productID %s version %d was modified", product.ID, product.Version)
+		ps.evict(ctx, product.ID, traceID)
+		return nil, custom_error.New(http.StatusPreconditionFailed, "the product was modified")
+	}
+
+	data, errMarshall := json.Marshal(productModel)
+	if errMarshall != nil {
+		ps.log.With("traceId", traceID).Errorf("Internal error to marshal the payload: %v", errMarshall)
+	} else {
+		errCache := ps.redis.Set(ctx, cache.ProductKey(tenantID, productModel.ID), data, cache.KeyCacheDuration).Err()
+		if errCache != nil {
+			ps.log.With("traceId", traceID).Errorf("Internal error to save in cache: %v", errCache)
+		}
+	}
+
+	event, errMarshall := json.Marshal(&domain.ProductStatusEvent{
+		ProductID: productModel.ID,
+		TenantID:  tenantID,
+		From:      transition.From,
+		To:        transition.To,
+		Reason:    request.Reason,
+		User:      username,
+		Version:   productModel.Version,
+		Date:      productModel.UpdateDate,
+	})
+	if errMarshall != nil {
+		ps.log.With("traceId", traceID).Errorf("Internal error to marshal the status event: %v", errMarshall)
+	} else {
+		ps.message.ProduceMessage(ps.messageConfig.Producer.ProductTopic, string(event), transition.EventName, tenantID, traceID)
+	}
+
+	ps.log.With("traceId", traceID).Infof("The productID %s status was changed from %s to %s by %s", productModel.ID, transition.From, transition.To, username)
 	return domain.FromProductModelToProductResponse(productModel), nil
 }
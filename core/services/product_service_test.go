@@ -7,10 +7,12 @@ import (
 	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
 	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/adapters/custom_error"
 	"golang-api-hexagonal/adapters/kafka"
 	"golang-api-hexagonal/adapters/repository/products"
 	"golang-api-hexagonal/config"
 	"golang-api-hexagonal/core/domain"
+	"net/http"
 	"testing"
 	"time"
 )
@@ -19,13 +21,16 @@ var log = config.NewLogger()
 var defaultContext = context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
 var username = "user_test"
 var traceID = "64675856476878"
-var product = &domain.Product{Name: "product_test"}
+var product = &domain.Product{Name: "product_test", Status: domain.ProductPending}
 
 // TestCreateProductThatAlreadyExistError for test CreateProduct
 func TestCreateProductThatAlreadyExistError(t *testing.T) {
-	service := NewProductService(log, &products.ProductRepositoryMock{}, &cache.RedisCacheMock{}, &kafka.MessageProducerMock{}, config.KafkaConfiguration{})
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
 
-	products.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
+	repository.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
 		return true, nil
 	}
 
@@ -35,9 +40,12 @@ func TestCreateProductThatAlreadyExistError(t *testing.T) {
 
 // TestCreateProductWithInternalServerErrorToFound for test CreateProduct
 func TestCreateProductWithInternalServerErrorToFound(t *testing.T) {
-	service := NewProductService(log, &products.ProductRepositoryMock{}, &cache.RedisCacheMock{}, &kafka.MessageProducerMock{}, config.KafkaConfiguration{})
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
 
-	products.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
+	repository.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
 		return false, errors.New("internal query error")
 	}
 
@@ -47,13 +55,16 @@ func TestCreateProductWithInternalServerErrorToFound(t *testing.T) {
 
 // TestCreateProductWithInternalServerErrorToSave for test CreateProduct
 func TestCreateProductWithInternalServerErrorToSave(t *testing.T) {
-	service := NewProductService(log, &products.ProductRepositoryMock{}, &cache.RedisCacheMock{}, &kafka.MessageProducerMock{}, config.KafkaConfiguration{})
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
 
-	products.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
+	repository.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
 		return false, nil
 	}
 
-	products.CreateFunc = func(ctx context.Context, model *domain.ProductModel) (*domain.ProductModel, error) {
+	repository.CreateFunc = func(ctx context.Context, model *domain.ProductModel, audit *domain.ProductAuditModel) (*domain.ProductModel, error) {
 		return nil, errors.New("internal error to save")
 	}
 
@@ -63,23 +74,26 @@ func TestCreateProductWithInternalServerErrorToSave(t *testing.T) {
 
 // TestCreateProductWithSuccessButFailToCache for test CreateProduct
 func TestCreateProductWithSuccessButFailToCache(t *testing.T) {
-	service := NewProductService(log, &products.ProductRepositoryMock{}, &cache.RedisCacheMock{}, &kafka.MessageProducerMock{}, config.KafkaConfiguration{})
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
 
-	products.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
+	repository.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
 		return false, nil
 	}
 
-	products.CreateFunc = func(ctx context.Context, model *domain.ProductModel) (*domain.ProductModel, error) {
+	repository.CreateFunc = func(ctx context.Context, model *domain.ProductModel, audit *domain.ProductAuditModel) (*domain.ProductModel, error) {
 		return nil, nil
 	}
 
 	var redisResponse = &redis.StatusCmd{}
 	redisResponse.SetErr(errors.New("internal error"))
-	cache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
 		return redisResponse
 	}
 
-	kafka.ProduceMessageFunc = func(topicName, value, eventName, traceID string) {}
+	producer.ProduceMessageFunc = func(topicName, value, eventName, tenantID, traceID string) {}
 
 	productResponse, _ := service.CreateProduct(defaultContext, product, username, traceID)
 	assert.NotEmpty(t, productResponse.ID)
@@ -87,23 +101,279 @@ func TestCreateProductWithSuccessButFailToCache(t *testing.T) {
 
 // TestCreateProductWithSuccess for test CreateProduct
 func TestCreateProductWithSuccess(t *testing.T) {
-	service := NewProductService(log, &products.ProductRepositoryMock{}, &cache.RedisCacheMock{}, &kafka.MessageProducerMock{}, config.KafkaConfiguration{})
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
 
-	products.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
+	repository.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
 		return false, nil
 	}
 
-	products.CreateFunc = func(ctx context.Context, model *domain.ProductModel) (*domain.ProductModel, error) {
+	repository.CreateFunc = func(ctx context.Context, model *domain.ProductModel, audit *domain.ProductAuditModel) (*domain.ProductModel, error) {
 		return nil, nil
 	}
 
 	var redisResponse = &redis.StatusCmd{}
-	cache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
 		return redisResponse
 	}
 
-	kafka.ProduceMessageFunc = func(topicName, value, eventName, traceID string) {}
+	producer.ProduceMessageFunc = func(topicName, value, eventName, tenantID, traceID string) {}
 
 	productResponse, _ := service.CreateProduct(defaultContext, product, username, traceID)
 	assert.NotEmpty(t, productResponse.ID)
 }
+
+// TestProductsAreIsolatedByTenant for test CreateProduct and GetProduct
+func TestProductsAreIsolatedByTenant(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
+	store := map[string]*domain.ProductModel{}
+	cacheStore := map[string]interface{}{}
+
+	repository.ProductAlreadyExistFunc = func(ctx context.Context, name, unitType, unit, brand, color, style string) (bool, error) {
+		return false, nil
+	}
+	repository.CreateFunc = func(ctx context.Context, model *domain.ProductModel, audit *domain.ProductAuditModel) (*domain.ProductModel, error) {
+		store[model.ID] = model
+		return model, nil
+	}
+	repository.GetProductByIdFunc = func(ctx context.Context, productID string) (*domain.ProductModel, error) {
+		if model, ok := store[productID]; ok && model.TenantID == domain.TenantFromContext(ctx) {
+			return model, nil
+		}
+		return nil, nil
+	}
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+		cacheStore[key] = value
+		return &redis.StatusCmd{}
+	}
+	redisCache.GetFunc = func(ctx context.Context, key string) *redis.StringCmd {
+		cmd := &redis.StringCmd{}
+		cmd.SetErr(redis.Nil)
+		return cmd
+	}
+	var eventTenant string
+	producer.ProduceMessageFunc = func(topicName, value, eventName, tenantID, traceID string) {
+		eventTenant = tenantID
+	}
+
+	productResponse, err := service.CreateProduct(domain.WithTenant(defaultContext, "tenant-a"), product, username, traceID)
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant-a", eventTenant)
+	assert.Contains(t, cacheStore, cache.ProductKey("tenant-a", productResponse.ID))
+
+	response, err := service.GetProduct(domain.WithTenant(defaultContext, "tenant-a"), productResponse.ID, traceID)
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant-a", response.TenantID)
+
+	_, err = service.GetProduct(domain.WithTenant(defaultContext, "tenant-b"), productResponse.ID, traceID)
+	assert.Equal(t, "not found", err.Error())
+}
+
+// TestUpdateProductWithModifiedVersion for test UpdateProduct
+func TestUpdateProductWithModifiedVersion(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
+	loaded := &domain.ProductResponse{ID: "1", Name: "product_test", Status: domain.ProductPending, Version: 3}
+
+	var expectedVersion int64
+	repository.UpdateFunc = func(ctx context.Context, model *domain.ProductModel, version int64, audit *domain.ProductAuditModel) (bool, error) {
+		expectedVersion = version
+		return false, nil
+	}
+	var deleted []string
+	redisCache.DelFunc = func(ctx context.Context, keys ...string) *redis.IntCmd {
+		deleted = keys
+		return &redis.IntCmd{}
+	}
+
+	_, err := service.UpdateProduct(defaultContext, loaded, product, username, traceID)
+	assert.Equal(t, "the product was modified", err.Error())
+	assert.Equal(t, int64(3), expectedVersion)
+	assert.Equal(t, []string{cache.ProductKey(domain.DefaultTenantID, "1")}, deleted)
+}
+
+// TestUpdateProductWithSuccess for test UpdateProduct
+func TestUpdateProductWithSuccess(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
+	loaded := &domain.ProductResponse{ID: "1", Name: "product_test", Status: domain.ProductPending, AuditUser: "owner", Version: 3}
+
+	repository.UpdateFunc = func(ctx context.Context, model *domain.ProductModel, version int64, audit *domain.ProductAuditModel) (bool, error) {
+		return true, nil
+	}
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+		return &redis.StatusCmd{}
+	}
+	var eventName string
+	producer.ProduceMessageFunc = func(topicName, value, name, tenantID, traceID string) {
+		eventName = name
+	}
+
+	response, err := service.UpdateProduct(defaultContext, loaded, product, username, traceID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), response.Version)
+	assert.Equal(t, "owner", response.AuditUser)
+	assert.Equal(t, domain.ProductUpdatedEventName, eventName)
+}
+
+// TestUpdateProductWritesTheAuditDiff for test UpdateProduct
+func TestUpdateProductWritesTheAuditDiff(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
+	loaded := &domain.ProductResponse{ID: "1", Name: "product_test", Description: "old", Status: domain.ProductPending, AuditUser: "owner", Version: 3}
+
+	var productAudit *domain.ProductAuditModel
+	repository.UpdateFunc = func(ctx context.Context, model *domain.ProductModel, version int64, audit *domain.ProductAuditModel) (bool, error) {
+		productAudit = audit
+		return true, nil
+	}
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+		return &redis.StatusCmd{}
+	}
+	producer.ProduceMessageFunc = func(topicName, value, name, tenantID, traceID string) {}
+
+	_, err := service.UpdateProduct(defaultContext, loaded, &domain.Product{Name: "product_test", Description: "new", Status: domain.ProductPending}, username, traceID)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.ProductUpdatedAudit, productAudit.Operation)
+	assert.Equal(t, username, productAudit.AuditUser)
+	assert.Equal(t, traceID, productAudit.TraceID)
+	assert.Equal(t, map[string]domain.ProductChange{
+		"description": {Before: "old", After: "new"},
+		"version":     {Before: float64(3), After: float64(4)},
+	}, productAudit.Changes)
+}
+
+// TestGetProductHistoryWithNextPage for test GetProductHistory
+func TestGetProductHistoryWithNextPage(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
+
+	repository.GetProductHistoryFunc = func(ctx context.Context, productID string, beforeID int64, limit int) ([]*domain.ProductAuditModel, error) {
+		assert.Equal(t, int64(10), beforeID)
+		assert.Equal(t, 3, limit)
+		return []*domain.ProductAuditModel{{ID: 9}, {ID: 8}, {ID: 7}}, nil
+	}
+
+	response, err := service.GetProductHistory(defaultContext, "1", 10, 2, traceID)
+	assert.Nil(t, err)
+	assert.Len(t, response.Items, 2)
+	assert.Equal(t, "8", response.NextCursor)
+}
+
+// TestCreateProductWithInactiveStatus for test CreateProduct
+func TestCreateProductWithInactiveStatus(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
+
+	_, err := service.CreateProduct(defaultContext, &domain.Product{Name: "product_test", Status: domain.ProductInactive}, username, traceID)
+	var transitionError *custom_error.TransitionError
+	assert.ErrorAs(t, err, &transitionError)
+	assert.Equal(t, []string{domain.ProductPending, domain.ProductAvailable}, transitionError.Allowed)
+}
+
+// TestChangeProductStatus for test ChangeProductStatus
+func TestChangeProductStatus(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
+
+	var productAudit *domain.ProductAuditModel
+	repository.UpdateFunc = func(ctx context.Context, model *domain.ProductModel, version int64, audit *domain.ProductAuditModel) (bool, error) {
+		productAudit = audit
+		return true, nil
+	}
+	redisCache.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+		return &redis.StatusCmd{}
+	}
+	var eventName string
+	producer.ProduceMessageFunc = func(topicName, value, name, tenantID, traceID string) {
+		eventName = name
+	}
+
+	tests := []struct {
+		from      string
+		to        string
+		eventName string
+		allowed   []string
+	}{
+		{domain.ProductPending, domain.ProductAvailable, "publish.product.event", nil},
+		{domain.ProductPending, domain.ProductInactive, "reject.product.event", nil},
+		{domain.ProductAvailable, domain.ProductInactive, "deactivate.product.event", nil},
+		{domain.ProductInactive, domain.ProductPending, "reactivate.product.event", nil},
+		{domain.ProductAvailable, domain.ProductPending, "", []string{domain.ProductInactive}},
+		{domain.ProductInactive, domain.ProductAvailable, "", []string{domain.ProductPending}},
+		{domain.ProductPending, domain.ProductPending, "", []string{domain.ProductAvailable, domain.ProductInactive}},
+	}
+	for _, test := range tests {
+		t.Run(test.from+"_to_"+test.to, func(t *testing.T) {
+			eventName = ""
+			loaded := &domain.ProductResponse{ID: "1", Name: "product_test", Status: test.from, Version: 3}
+			request := &domain.ProductStatusChange{Status: test.to, Reason: "reviewed"}
+
+			response, err := service.ChangeProductStatus(defaultContext, loaded, request, username, traceID)
+			if test.allowed != nil {
+				var transitionError *custom_error.TransitionError
+				assert.ErrorAs(t, err, &transitionError)
+				assert.Equal(t, test.allowed, transitionError.Allowed)
+				assert.Equal(t, http.StatusConflict, transitionError.ErrorCode())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.to, response.Status)
+			assert.Equal(t, test.eventName, eventName)
+			assert.Equal(t, "reviewed", productAudit.Reason)
+			assert.Equal(t, domain.ProductChange{Before: test.from, After: test.to}, productAudit.Changes["status"])
+		})
+	}
+}
+
+// TestGetProductsWithCacheHitsAndMisses for test GetProducts
+func TestGetProductsWithCacheHitsAndMisses(t *testing.T) {
+	repository := &products.ProductRepositoryMock{}
+	redisCache := &cache.RedisCacheMock{}
+	producer := &kafka.MessageProducerMock{}
+	service := NewProductService(log, repository, redisCache, producer, config.KafkaConfiguration{})
+
+	redisCache.MGetFunc = func(ctx context.Context, keys ...string) *redis.SliceCmd {
+		assert.Equal(t, []string{"default:product:3", "default:product:1", "default:product:2"}, keys)
+		cmd := redis.NewSliceCmd(ctx)
+		cmd.SetVal([]interface{}{nil, `{"id":"1","version":1}`, nil})
+		return cmd
+	}
+	repository.GetProductsByIdsFunc = func(ctx context.Context, productIDs []string) ([]*domain.ProductModel, error) {
+		assert.Equal(t, []string{"3", "2"}, productIDs)
+		return []*domain.ProductModel{{ID: "2", TenantID: "default", Version: 1}}, nil
+	}
+	backfilled := map[string]string{}
+	redisCache.PipelinedFunc = func(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+		return nil, fn(&fakePipeline{store: backfilled})
+	}
+
+	response, err := service.GetProducts(defaultContext, []string{"3", "1", "2", "1"}, traceID)
+
+	assert.NoError(t, err)
+	assert.Contains(t, backfilled, "default:product:2")
+	assert.Len(t, backfilled, 1)
+	assert.Equal(t, []*domain.ProductBatchItem{
+		{ID: "3", Error: "not found"},
+		{ID: "1", Found: true, Product: &domain.ProductResponse{ID: "1", Version: 1}},
+		{ID: "2", Found: true, Product: &domain.ProductResponse{ID: "2", TenantID: "default", Version: 1}},
+		{ID: "1", Found: true, Product: &domain.ProductResponse{ID: "1", Version: 1}},
+	}, response.Items)
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/custom_error"
+	"golang-api-hexagonal/core/domain"
+	"golang-api-hexagonal/core/ports"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+)
+
+// UserService user service
+type UserService struct {
+	log            *zap.SugaredLogger
+	userRepository ports.IUserRepository
+}
+
+// NewUserService create new user service
+func NewUserService(log *zap.SugaredLogger, userRepository ports.IUserRepository) *UserService {
+	return &UserService{
+		log:            log,
+		userRepository: userRepository,
+	}
+}
+
+// CreateUser create the user with the password stored as a bcrypt hash
+func (us *UserService) CreateUser(ctx context.Context, request *domain.User, traceID string) (*domain.UserModel, error) {
+	user, err := us.userRepository.GetUserByUsername(ctx, request.Username)
+	if err != nil {
+		us.log.With("traceId", traceID).Errorf("Internal server error: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	} else if user != nil {
+		us.log.With("traceId", traceID).Errorf("User already exist")
+		return nil, custom_error.New(http.StatusConflict, "already exist")
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	if err != nil {
+		us.log.With("traceId", traceID).Errorf("Internal error to hash the password: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	userModel, err := us.userRepository.Create(ctx, domain.FromUserToUserModel(request, string(passwordHash)))
+	if err != nil {
+		us.log.With("traceId", traceID).Errorf("Internal server error: %v", err)
+		return nil, custom_error.New(http.StatusInternalServerError, "internal server error")
+	}
+
+	us.log.With("traceId", traceID).Infof("The user %s was created with success", userModel.Username)
+	return userModel, nil
+}
+
+// AssignRoles replace the roles of the user
+func (us *UserService) AssignRoles(ctx context.Context, request *domain.UserRoles, traceID string) error {
+	updated, err := us.userRepository.UpdateRoles(ctx, request.Username, request.Roles)
+	if err != nil {
+		us.log.With("traceId", traceID).Errorf("Internal server error: %v", err)
+		return custom_error.New(http.StatusInternalServerError, "internal server error")
+	} else if !updated {
+		us.log.With("traceId", traceID).Errorf("User not found")
+		return custom_error.New(http.StatusNotFound, "not found")
+	}
+
+	us.log.With("traceId", traceID).Infof("The user %s has the roles %v", request.Username, request.Roles)
+	return nil
+}
@@ -3,37 +3,119 @@ package golangapitemplate.authz
 # By default, deny requests.
 default allow = false
 
-# Admins can do everything
+# Allow the request when at least one rule grants it
 allow {
+	count(grants) > 0
+}
+
+# Admins can do everything
+grants["admin"] {
 	input.Token.Roles[_] == "admin"
 }
 
 # Business are allowed to update product
-allow {
+grants["business_update_product"] {
     input.EntityData.Type == "updateProduct"
 	input.Token.Roles[_] == "business"
 }
 
 # Business are allowed to view product
-allow {
+grants["business_view_product"] {
     input.EntityData.Type == "viewProduct"
 	input.Token.Roles[_] == "business"
 }
 
 # User are allowed to view product
-allow {
+grants["user_view_product"] {
     input.EntityData.Type == "viewProduct"
 	input.Token.Roles[_] == "user"
 }
 
+# Users and business are allowed to search the products
+grants["search_products"] {
+    input.EntityData.Type == "searchProducts"
+	search_roles := {"user", "business"}
+	search_roles[input.Token.Roles[_]]
+}
+
+# Business are allowed to change the product status, except the reactivation of the inactive products
+grants["business_change_product_status"] {
+    input.EntityData.Type == "changeProductStatus"
+	input.Token.Roles[_] == "business"
+	input.EntityData.Status != "inactive"
+}
+
+# Business and auditors are allowed to view the product history
+grants["business_view_product_history"] {
+    input.EntityData.Type == "viewProductHistory"
+	input.Token.Roles[_] == "business"
+}
+
+grants["auditor_view_product_history"] {
+    input.EntityData.Type == "viewProductHistory"
+	input.Token.Roles[_] == "auditor"
+}
+
 # This business Username is allowed to create product
-allow {
+grants["business_main_create_product"] {
     input.EntityData.Type == "createProduct"
 	input.Token.Roles[_] == "business"
 	input.Token.Username == "business_main_id"
 }
 
-# Only owners can edit objects
-allow {
+# This business Username is allowed to import products, the owner of an import can view its status
+grants["business_main_import_products"] {
+    input.EntityData.Type == "importProducts"
+	input.Token.Roles[_] == "business"
+	input.Token.Username == "business_main_id"
+}
+
+# Business and auditors are allowed to export the products, the owner of an export can download it
+grants["business_export_products"] {
+    input.EntityData.Type == "exportProducts"
+	input.Token.Roles[_] == "business"
+}
+
+grants["auditor_export_products"] {
+    input.EntityData.Type == "exportProducts"
+	input.Token.Roles[_] == "auditor"
+}
+
+# Only owners can edit objects, the status changes are granted by the roles so the owners can not bypass the lifecycle
+grants["owner"] {
+    owner_operations[input.EntityData.Type]
     input.Token.Username == input.EntityData.Owner
 }
+
+owner_operations := {"viewProduct", "updateProduct", "viewProductHistory", "importProducts", "exportProducts"}
+
+# Reasons of the decision, these codes are returned to the clients so they must not leak policy data
+reasons = grants {
+	allow
+}
+
+reasons = {"no_roles"} {
+	not allow
+	not has_roles
+}
+
+reasons = {"operation_not_allowed"} {
+	not allow
+	has_roles
+}
+
+has_roles {
+	input.Token.Roles[_]
+}
+
+# Obligations the API must fulfil with the decision
+obligations["audit_api_key_access"] {
+	allow
+	startswith(input.Token.Subject, "apikey:")
+}
+
+decision = {
+	"allow": allow,
+	"reasons": reasons,
+	"obligations": obligations,
+}
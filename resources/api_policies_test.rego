@@ -0,0 +1,139 @@
+package golangapitemplate.authz_test
+
+import data.golangapitemplate.authz
+
+# Admins can do everything
+test_admin_can_do_everything {
+	authz.allow with input as {"Token": {"Username": "root", "Roles": ["admin"]}, "EntityData": {"Type": "createProduct"}}
+	authz.allow with input as {"Token": {"Username": "root", "Roles": ["admin"]}, "EntityData": {"Type": "manageApiKeys"}}
+	authz.grants["admin"] with input as {"Token": {"Username": "root", "Roles": ["admin"]}, "EntityData": {"Type": "viewProduct"}}
+}
+
+# Business are allowed to update product
+test_business_can_update_product {
+	authz.allow with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "updateProduct", "Owner": "alice"}}
+	authz.reasons == {"business_update_product"} with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "updateProduct", "Owner": "alice"}}
+}
+
+# Business are allowed to view product
+test_business_can_view_product {
+	authz.allow with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "viewProduct", "Owner": "alice"}}
+}
+
+test_business_can_not_create_product {
+	not authz.allow with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "createProduct"}}
+}
+
+test_business_can_not_manage_api_keys {
+	not authz.allow with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "manageApiKeys"}}
+}
+
+# User are allowed to view product
+test_user_can_view_product {
+	authz.allow with input as {"Token": {"Username": "john", "Roles": ["user"]}, "EntityData": {"Type": "viewProduct", "Owner": "alice"}}
+	authz.reasons == {"user_view_product"} with input as {"Token": {"Username": "john", "Roles": ["user"]}, "EntityData": {"Type": "viewProduct", "Owner": "alice"}}
+}
+
+test_user_can_not_update_product {
+	not authz.allow with input as {"Token": {"Username": "john", "Roles": ["user"]}, "EntityData": {"Type": "updateProduct", "Owner": "alice"}}
+	authz.reasons == {"operation_not_allowed"} with input as {"Token": {"Username": "john", "Roles": ["user"]}, "EntityData": {"Type": "updateProduct", "Owner": "alice"}}
+}
+
+test_user_can_not_create_product {
+	not authz.allow with input as {"Token": {"Username": "john", "Roles": ["user"]}, "EntityData": {"Type": "createProduct"}}
+}
+
+# Users and business are allowed to search the products
+test_users_and_business_can_search_products {
+	authz.reasons == {"search_products"} with input as {"Token": {"Username": "john", "Roles": ["user"]}, "EntityData": {"Type": "searchProducts"}}
+	authz.reasons == {"search_products"} with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "searchProducts"}}
+	not authz.allow with input as {"Token": {"Username": "carol", "Roles": ["auditor"]}, "EntityData": {"Type": "searchProducts"}}
+}
+
+# Business are allowed to change the product status, except the reactivation of the inactive products
+test_business_can_change_product_status {
+	authz.reasons == {"business_change_product_status"} with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "changeProductStatus", "Owner": "alice", "Status": "pending", "TargetStatus": "available"}}
+}
+
+test_business_can_not_reactivate_product {
+	not authz.allow with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "changeProductStatus", "Owner": "alice", "Status": "inactive", "TargetStatus": "pending"}}
+	authz.allow with input as {"Token": {"Username": "root", "Roles": ["admin"]}, "EntityData": {"Type": "changeProductStatus", "Owner": "alice", "Status": "inactive", "TargetStatus": "pending"}}
+}
+
+test_user_can_not_change_product_status {
+	not authz.allow with input as {"Token": {"Username": "john", "Roles": ["user"]}, "EntityData": {"Type": "changeProductStatus", "Owner": "alice", "Status": "pending", "TargetStatus": "available"}}
+}
+
+# Business and auditors are allowed to view the product history
+test_business_can_view_product_history {
+	authz.reasons == {"business_view_product_history"} with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "viewProductHistory", "Owner": "alice"}}
+}
+
+test_auditor_can_view_product_history {
+	authz.reasons == {"auditor_view_product_history"} with input as {"Token": {"Username": "carol", "Roles": ["auditor"]}, "EntityData": {"Type": "viewProductHistory", "Owner": "alice"}}
+	not authz.allow with input as {"Token": {"Username": "carol", "Roles": ["auditor"]}, "EntityData": {"Type": "viewProduct", "Owner": "alice"}}
+}
+
+test_user_can_not_view_product_history {
+	not authz.allow with input as {"Token": {"Username": "john", "Roles": ["user"]}, "EntityData": {"Type": "viewProductHistory", "Owner": "alice"}}
+}
+
+# This business Username is allowed to create product
+test_business_main_id_can_create_product {
+	authz.allow with input as {"Token": {"Username": "business_main_id", "Roles": ["business"]}, "EntityData": {"Type": "createProduct"}}
+	authz.reasons == {"business_main_create_product"} with input as {"Token": {"Username": "business_main_id", "Roles": ["business"]}, "EntityData": {"Type": "createProduct"}}
+}
+
+test_business_main_id_needs_the_business_role {
+	not authz.allow with input as {"Token": {"Username": "business_main_id", "Roles": ["user"]}, "EntityData": {"Type": "createProduct"}}
+}
+
+# This business Username is allowed to import products, the owner of an import can view its status
+test_business_main_id_can_import_products {
+	authz.reasons == {"business_main_import_products"} with input as {"Token": {"Username": "business_main_id", "Roles": ["business"]}, "EntityData": {"Type": "importProducts"}}
+	not authz.allow with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "importProducts"}}
+	authz.reasons == {"owner"} with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "importProducts", "Owner": "john"}}
+}
+
+# Business and auditors are allowed to export the products, the owner of an export can download it
+test_business_and_auditors_can_export_products {
+	authz.reasons == {"business_export_products"} with input as {"Token": {"Username": "john", "Roles": ["business"]}, "EntityData": {"Type": "exportProducts"}}
+	authz.reasons == {"auditor_export_products"} with input as {"Token": {"Username": "carol", "Roles": ["auditor"]}, "EntityData": {"Type": "exportProducts"}}
+	not authz.allow with input as {"Token": {"Username": "bob", "Roles": ["user"]}, "EntityData": {"Type": "exportProducts", "Owner": "carol"}}
+}
+
+# Only owners can edit objects
+test_owner_can_update_product {
+	authz.allow with input as {"Token": {"Username": "alice", "Roles": ["user"]}, "EntityData": {"Type": "updateProduct", "Owner": "alice"}}
+	authz.reasons == {"owner"} with input as {"Token": {"Username": "alice", "Roles": ["user"]}, "EntityData": {"Type": "updateProduct", "Owner": "alice"}}
+}
+
+test_owner_without_roles_can_update_product {
+	authz.allow with input as {"Token": {"Username": "alice", "Roles": []}, "EntityData": {"Type": "updateProduct", "Owner": "alice"}}
+}
+
+test_owner_can_not_change_product_status {
+	not authz.allow with input as {"Token": {"Username": "alice", "Roles": []}, "EntityData": {"Type": "changeProductStatus", "Owner": "alice", "Status": "inactive", "TargetStatus": "available"}}
+	authz.reasons == {"business_change_product_status"} with input as {"Token": {"Username": "alice", "Roles": ["business"]}, "EntityData": {"Type": "changeProductStatus", "Owner": "alice", "Status": "pending", "TargetStatus": "available"}}
+	not authz.allow with input as {"Token": {"Username": "alice", "Roles": ["business"]}, "EntityData": {"Type": "changeProductStatus", "Owner": "alice", "Status": "inactive", "TargetStatus": "available"}}
+}
+
+test_other_user_is_not_owner {
+	not authz.allow with input as {"Token": {"Username": "bob", "Roles": []}, "EntityData": {"Type": "updateProduct", "Owner": "alice"}}
+}
+
+# Reasons and obligations of the decision
+test_no_roles_reason {
+	authz.reasons == {"no_roles"} with input as {"Token": {"Username": "bob", "Roles": []}, "EntityData": {"Type": "viewProduct", "Owner": "alice"}}
+	authz.reasons == {"no_roles"} with input as {"Token": {"Username": "bob"}, "EntityData": {"Type": "viewProduct", "Owner": "alice"}}
+}
+
+test_api_key_access_is_audited {
+	authz.obligations == {"audit_api_key_access"} with input as {"Token": {"Subject": "apikey:f3a2", "Username": "batch", "Roles": ["user"]}, "EntityData": {"Type": "viewProduct"}}
+	count(authz.obligations) == 0 with input as {"Token": {"Subject": "f3a2", "Username": "john", "Roles": ["user"]}, "EntityData": {"Type": "viewProduct"}}
+}
+
+test_decision {
+	authz.decision == {"allow": true, "reasons": {"admin", "business_view_product"}, "obligations": set()} with input as {"Token": {"Username": "root", "Roles": ["admin", "business"]}, "EntityData": {"Type": "viewProduct"}}
+	authz.decision == {"allow": false, "reasons": {"operation_not_allowed"}, "obligations": set()} with input as {"Token": {"Username": "john", "Roles": ["user"]}, "EntityData": {"Type": "reloadPolicies"}}
+}
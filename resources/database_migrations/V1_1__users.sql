@@ -0,0 +1,9 @@
+create table if not exists users
+(
+    id              UUID PRIMARY KEY,
+    username        varchar (256) NOT NULL UNIQUE,
+    password_hash   varchar (256) NOT NULL,
+    roles           varchar (50)[] NOT NULL DEFAULT '{}',
+    creation_date   timestamp NOT NULL DEFAULT now(),
+    update_date     timestamp NOT NULL DEFAULT now()
+);
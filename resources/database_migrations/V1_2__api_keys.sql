@@ -0,0 +1,12 @@
+create table if not exists api_keys
+(
+    id              UUID PRIMARY KEY,
+    name            varchar (256) NOT NULL,
+    prefix          varchar (16) NOT NULL UNIQUE,
+    key_hash        varchar (128) NOT NULL,
+    roles           varchar (50)[] NOT NULL DEFAULT '{}',
+    audit_user      varchar (256) NOT NULL,
+    expires_at      timestamp NULL,
+    revoked_at      timestamp NULL,
+    creation_date   timestamp NOT NULL DEFAULT now()
+);
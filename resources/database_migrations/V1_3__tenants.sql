@@ -0,0 +1,7 @@
+alter table products add column if not exists tenant_id varchar (64) NOT NULL DEFAULT 'default';
+create index if not exists products_tenant_id_idx on products (tenant_id, id);
+
+alter table users add column if not exists tenant_id varchar (64) NOT NULL DEFAULT 'default';
+
+alter table api_keys add column if not exists tenant_id varchar (64) NOT NULL DEFAULT 'default';
+create index if not exists api_keys_tenant_id_idx on api_keys (tenant_id);
@@ -0,0 +1 @@
+alter table products add column if not exists version bigint NOT NULL DEFAULT 1;
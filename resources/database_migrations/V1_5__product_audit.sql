@@ -0,0 +1,13 @@
+create table if not exists product_audit
+(
+    id              bigserial PRIMARY KEY,
+    tenant_id       varchar (64) NOT NULL,
+    product_id      UUID NOT NULL,
+    operation       varchar (32) NOT NULL,
+    audit_user      varchar (50) NOT NULL,
+    trace_id        varchar (128) NULL,
+    changes         jsonb NOT NULL,
+    creation_date   timestamp NOT NULL DEFAULT now()
+);
+
+create index if not exists product_audit_product_id_idx on product_audit (tenant_id, product_id, id DESC);
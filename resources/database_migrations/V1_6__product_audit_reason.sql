@@ -0,0 +1 @@
+alter table product_audit add column if not exists reason varchar (256) NULL;
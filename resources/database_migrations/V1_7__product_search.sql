@@ -0,0 +1,9 @@
+alter table products add column if not exists search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED;
+
+create index if not exists products_search_vector_idx on products using gin (search_vector);
+create index if not exists products_tenant_id_creation_date_idx on products (tenant_id, creation_date, id);
+create index if not exists products_tenant_id_name_idx on products (tenant_id, name, id);
@@ -0,0 +1,3 @@
+drop table if exists products;
+drop type if exists product_status;
+drop type if exists unit_type;
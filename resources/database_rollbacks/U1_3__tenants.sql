@@ -0,0 +1,7 @@
+drop index if exists api_keys_tenant_id_idx;
+alter table api_keys drop column if exists tenant_id;
+
+alter table users drop column if exists tenant_id;
+
+drop index if exists products_tenant_id_idx;
+alter table products drop column if exists tenant_id;
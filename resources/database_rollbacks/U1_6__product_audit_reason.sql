@@ -0,0 +1 @@
+alter table product_audit drop column if exists reason;
@@ -0,0 +1,4 @@
+drop index if exists products_tenant_id_name_idx;
+drop index if exists products_tenant_id_creation_date_idx;
+drop index if exists products_search_vector_idx;
+alter table products drop column if exists search_vector;
@@ -0,0 +1,14 @@
+package resources
+
+import "embed"
+
+// DatabaseMigrations the Flyway versioned migrations, V<version>__<description>.sql
+//
+//go:embed database_migrations/*.sql
+var DatabaseMigrations embed.FS
+
+// DatabaseRollbacks the undo migrations of the versioned migrations, U<version>__<description>.sql.
+// They are not in the Flyway migrations folder, so the Flyway community edition ignores them
+//
+//go:embed database_rollbacks/*.sql
+var DatabaseRollbacks embed.FS
@@ -0,0 +1,45 @@
+//go:build integration
+
+package integration
+
+import "sync"
+
+// Message kafka message produced by the services
+type Message struct {
+	Topic     string
+	Value     string
+	EventName string
+	TenantID  string
+	TraceID   string
+}
+
+// MessageRecorder in memory kafka producer, the produced messages are kept in order
+type MessageRecorder struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+// NewMessageRecorder create new in memory kafka producer
+func NewMessageRecorder() *MessageRecorder {
+	return &MessageRecorder{}
+}
+
+// ProduceMessage record the message
+func (mr *MessageRecorder) ProduceMessage(topicName, value, eventName, tenantID, traceID string) {
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+	mr.messages = append(mr.messages, Message{Topic: topicName, Value: value, EventName: eventName, TenantID: tenantID, TraceID: traceID})
+}
+
+// Messages the messages produced in the topic
+func (mr *MessageRecorder) Messages(topic string) []Message {
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+	var messages []Message
+	for _, message := range mr.messages {
+		if message.Topic == topic {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
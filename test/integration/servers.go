@@ -0,0 +1,221 @@
+//go:build integration
+
+// Package integration helpers of the integration tests: throwaway Postgres databases with the migrations applied,
+// Redis databases and an in memory Kafka producer. The server of TEST_DATABASE_DSN is used when it is set, otherwise the
+// postgres binaries of the PATH are started for the tests. Each test package starts its own redis-server, so the parallel
+// packages do not share the Redis databases
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"golang-api-hexagonal/adapters/cache"
+	"golang-api-hexagonal/adapters/repository/migrations"
+	"golang-api-hexagonal/resources"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// redisDatabases databases of the redis server of the package, each test uses its own database
+const redisDatabases = 1024
+
+// server a local server started for the tests
+type server struct {
+	once    sync.Once
+	address string
+	err     error
+	skip    string
+	stop    func()
+}
+
+var (
+	postgresServer server
+	redisServer    server
+	databaseCount  atomic.Int64
+	redisCount     atomic.Int64
+)
+
+// Run run the tests of the package and stop the servers started for them, the TestMain of the integration tests
+func Run(m *testing.M) int {
+	code := m.Run()
+	for _, started := range []*server{&postgresServer, &redisServer} {
+		if started.stop != nil {
+			started.stop()
+		}
+	}
+	return code
+}
+
+// Database new database with the migrations applied, the database is dropped at the end of the test
+func Database(t *testing.T) *bun.DB {
+	t.Helper()
+	dsn := serverAddress(t, &postgresServer, "TEST_DATABASE_DSN", startPostgres)
+	ctx := context.Background()
+
+	admin := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn))), pgdialect.New())
+	name := fmt.Sprintf("integration_%d_%d", os.Getpid(), databaseCount.Add(1))
+	if _, err := admin.ExecContext(ctx, "CREATE DATABASE "+name); err != nil {
+		_ = admin.Close()
+		t.Fatalf("Fail to create the database %s: %v", name, err)
+	}
+
+	databaseURL, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("Invalid database dsn: %v", err)
+	}
+	databaseURL.Path = "/" + name
+	db := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(databaseURL.String()))), pgdialect.New())
+	t.Cleanup(func() {
+		_ = db.Close()
+		_, _ = admin.ExecContext(ctx, "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)")
+		_ = admin.Close()
+	})
+
+	migrator, err := migrations.NewMigrator(zap.NewNop().Sugar(), db, resources.DatabaseMigrations, resources.DatabaseRollbacks)
+	if err != nil {
+		t.Fatalf("Invalid migrations: %v", err)
+	}
+	if _, err = migrator.Up(ctx); err != nil {
+		t.Fatalf("Fail to migrate the database %s: %v", name, err)
+	}
+	return db
+}
+
+// Redis redis cache on a new database of the redis-server of the package, the databases are not reused by the tests
+func Redis(t *testing.T) *cache.RedisCache {
+	t.Helper()
+	address := serverAddress(t, &redisServer, "", startRedis)
+	database := redisCount.Add(1) - 1
+	if database >= redisDatabases {
+		t.Fatalf("The %d redis databases of the package are used, raise redisDatabases", redisDatabases)
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: address, DB: int(database)})
+	if err := client.FlushDB(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		t.Fatalf("Fail to flush the redis database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return &cache.RedisCache{Client: client}
+}
+
+// serverAddress address of the environment variable or of the server started once by the tests, the server is always
+// started without variable
+func serverAddress(t *testing.T, started *server, variable string, start func(dir string) (string, func(), error)) string {
+	t.Helper()
+	if address := os.Getenv(variable); address != "" {
+		return address
+	}
+	started.once.Do(func() {
+		dir, err := os.MkdirTemp("", "integration")
+		if err != nil {
+			started.err = err
+			return
+		}
+		started.address, started.stop, started.err = start(dir)
+		if started.err != nil {
+			_ = os.RemoveAll(dir)
+			if _, skip := started.err.(*exec.Error); skip && variable != "" {
+				started.skip = fmt.Sprintf("%s is not set and %v", variable, started.err)
+			} else if skip {
+				started.skip = started.err.Error()
+			}
+		}
+	})
+	if started.skip != "" {
+		t.Skip(started.skip)
+	}
+	if started.err != nil {
+		t.Fatalf("Fail to start the test server: %v", started.err)
+	}
+	return started.address
+}
+
+// startPostgres start a postgres server in the directory, it can not be started by root
+func startPostgres(dir string) (string, func(), error) {
+	data := filepath.Join(dir, "data")
+	if err := run("initdb", "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync"); err != nil {
+		return "", nil, err
+	}
+	port, err := freePort()
+	if err != nil {
+		return "", nil, err
+	}
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, dir)
+	if err = run("pg_ctl", "-D", data, "-o", options, "-l", filepath.Join(dir, "postgres.log"), "-w", "start"); err != nil {
+		return "", nil, err
+	}
+	stop := func() {
+		_ = run("pg_ctl", "-D", data, "-m", "immediate", "stop")
+		_ = os.RemoveAll(dir)
+	}
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port), stop, nil
+}
+
+// startRedis start a redis server without persistence in the directory
+func startRedis(dir string) (string, func(), error) {
+	port, err := freePort()
+	if err != nil {
+		return "", nil, err
+	}
+	command := exec.Command("redis-server", "--port", fmt.Sprint(port), "--bind", "127.0.0.1",
+		"--dir", dir, "--save", "", "--appendonly", "no", "--databases", fmt.Sprint(redisDatabases))
+	if err = command.Start(); err != nil {
+		return "", nil, err
+	}
+	stop := func() {
+		_ = command.Process.Kill()
+		_ = command.Wait()
+		_ = os.RemoveAll(dir)
+	}
+
+	address := fmt.Sprintf("127.0.0.1:%d", port)
+	client := redis.NewClient(&redis.Options{Addr: address})
+	defer client.Close()
+	for attempt := 0; attempt < 50; attempt++ {
+		if err = client.Ping(context.Background()).Err(); err == nil {
+			return address, stop, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	stop()
+	return "", nil, fmt.Errorf("redis-server is not ready: %w", err)
+}
+
+// run run the command, the output is the error of the failed command
+func run(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		if _, notFound := err.(*exec.Error); notFound {
+			return err
+		}
+		return fmt.Errorf("%s failed: %w: %s", name, err, output)
+	}
+	return nil
+}
+
+// freePort a free local tcp port
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}